@@ -0,0 +1,238 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// accessCmd represents the access command
+var accessCmd = &cobra.Command{
+	Use:   "access",
+	Short: "Review who can access what.",
+	Long:  `The subcommand of access review, which combines users, project members and projects.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Use \"harborctl access --help\" for more information about this command.")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(accessCmd)
+
+	initAccessReport()
+}
+
+// accessReportCmd represents the report command
+var accessReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report the roles of users and groups across projects.",
+	Long: `This command enumerates all projects with their members and roles, and the administrators of Harbor, producing a user (or group) x project x role matrix.
+
+The type of an entry is one of:
+- user:     a user member of the project.
+- group:    a group member of the project.
+- sysadmin: an administrator of Harbor, who can access all projects ('*').
+- public:   anyone ('*') can pull from the public project.
+
+NOTE: the members of groups are managed by LDAP and not known by Harbor API, so filtering by '--user' does not include the roles granted through groups, which are warned about to stderr instead.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := reportAccess(); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var accessReport struct {
+	user         string
+	format       string
+	public       bool
+	groupWarning bool
+}
+
+func initAccessReport() {
+	accessCmd.AddCommand(accessReportCmd)
+
+	accessReportCmd.Flags().StringVarP(&accessReport.user,
+		"user",
+		"u", "",
+		"Report the access of the user only.")
+
+	accessReportCmd.Flags().StringVarP(&accessReport.format,
+		"format",
+		"f", "table",
+		"The output format, valid values are 'table', 'csv' and 'json'.")
+
+	accessReportCmd.Flags().BoolVarP(&accessReport.public,
+		"public",
+		"", true,
+		"Report the public projects which anyone can pull from.")
+
+	accessReportCmd.Flags().BoolVarP(&accessReport.groupWarning,
+		"group_warning",
+		"", true,
+		"Warn about the group members of projects when filtering by '--user'.")
+}
+
+// accessEntry is a role of a subject (user or group) in a project.
+type accessEntry struct {
+	Subject string `json:"subject"`
+	Type    string `json:"type"`
+	Project string `json:"project"`
+	Role    string `json:"role"`
+}
+
+func reportAccess() error {
+	switch accessReport.format {
+	case "table", "csv", "json":
+	default:
+		return fmt.Errorf("format must be one of [table|csv|json], got %q", accessReport.format)
+	}
+
+	entries, groups, err := collectAccess()
+	if err != nil {
+		return err
+	}
+
+	if accessReport.user != "" {
+		filtered := entries[:0]
+		for _, e := range entries {
+			if (e.Type != "group" && e.Subject == accessReport.user) || e.Type == "public" {
+				filtered = append(filtered, e)
+			}
+		}
+		entries = filtered
+
+		if accessReport.groupWarning && len(groups) > 0 {
+			fmt.Fprintf(os.Stderr, "WARNING: %s may also access through the groups below, whose members are not known by Harbor:\n", accessReport.user)
+			for _, g := range groups {
+				fmt.Fprintf(os.Stderr, "  %s in %s as %s\n", g.Subject, g.Project, g.Role)
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Subject != entries[j].Subject {
+			return entries[i].Subject < entries[j].Subject
+		}
+		return entries[i].Project < entries[j].Project
+	})
+
+	switch accessReport.format {
+	case "json":
+		if entries == nil {
+			entries = []accessEntry{}
+		}
+		b, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"subject", "type", "project", "role"})
+		for _, e := range entries {
+			w.Write([]string{e.Subject, e.Type, e.Project, e.Role})
+		}
+		w.Flush()
+		return w.Error()
+
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SUBJECT\tTYPE\tPROJECT\tROLE")
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Subject, e.Type, e.Project, e.Role)
+		}
+		w.Flush()
+	}
+	return nil
+}
+
+// collectAccess returns all entries of access, with the entries of groups.
+func collectAccess() ([]accessEntry, []accessEntry, error) {
+	var entries, groups []accessEntry
+
+	users, err := listUsers()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// NOTE: the list of users may not include admin user, which is always
+	// user 1, so it is added unless listed.
+	listed := false
+	for _, u := range users {
+		listed = listed || u.UserID == 1
+	}
+	if !listed {
+		var admin userBrief
+		if err := utils.GetStruct(utils.URLGen("/api/users/1"), &admin); err == nil {
+			users = append(users, admin)
+		} else {
+			fmt.Fprintln(os.Stderr, "WARNING: get admin user:", err)
+		}
+	}
+
+	for _, u := range users {
+		if u.HasAdminRole {
+			entries = append(entries, accessEntry{Subject: u.Username, Type: "sysadmin", Project: "*", Role: "sysadmin"})
+		}
+	}
+
+	projects, err := listProjects()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, p := range projects {
+		if accessReport.public && p.Metadata.Public == "true" {
+			entries = append(entries, accessEntry{Subject: "*", Type: "public", Project: p.Name, Role: "guest"})
+		}
+
+		members, err := listProjectMembers(p.ProjectID, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, m := range members {
+			role, ok := projectRoles[m.RoleID]
+			if !ok {
+				role = m.RoleName
+			}
+
+			e := accessEntry{Subject: m.EntityName, Type: "user", Project: p.Name, Role: role}
+			if m.EntityType == "g" {
+				e.Type = "group"
+				groups = append(groups, e)
+			}
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, groups, nil
+}
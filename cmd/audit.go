@@ -0,0 +1,230 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Export and stream access logs.",
+	Long: `The subcommand of access logs ('/logs' and '/projects/{project_id}/logs'), which fetches all pages of logs in a time range, rather than one page as 'log' and 'project log' do.
+
+NOTE: the time of '--since' and '--until' can be a duration ago like '30d', '12h' or '2w', 'today', 'yesterday', a date like '2018-10-01' or '20181001', a time like '2018-10-01 08:00' or RFC3339, or a unix timestamp.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Use \"harborctl audit --help\" for more information about this command.")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+}
+
+// accessLog is an entry of access logs.
+type accessLog struct {
+	LogID     int64  `json:"log_id"`
+	Username  string `json:"username"`
+	ProjectID int64  `json:"project_id"`
+	RepoName  string `json:"repo_name"`
+	RepoTag   string `json:"repo_tag"`
+	GUID      string `json:"guid,omitempty"`
+	Operation string `json:"operation"`
+	OpTime    string `json:"op_time"`
+}
+
+// auditFilter filters access logs on the server side.
+type auditFilter struct {
+	projectID  int64
+	username   string
+	repository string
+	tag        string
+	operation  string
+	pageSize   int
+}
+
+func addAuditFilterFlags(cmd *cobra.Command, f *auditFilter) {
+	cmd.Flags().VarP(newIDValue(&f.projectID, "project"),
+		"project_id",
+		"j",
+		"The project ID or name to get access logs of. (all projects which the user is a member of if not set)")
+
+	cmd.Flags().StringVarP(&f.username,
+		"username",
+		"n", "",
+		"Username of the operator.")
+
+	cmd.Flags().StringVarP(&f.repository,
+		"repository",
+		"r", "",
+		"The name of repository.")
+
+	cmd.Flags().StringVarP(&f.tag,
+		"tag",
+		"t", "",
+		"The name of tag.")
+
+	cmd.Flags().StringVarP(&f.operation,
+		"operation",
+		"o", "",
+		"The operation, valid values are 'create', 'delete', 'push' and 'pull'.")
+
+	cmd.Flags().IntVarP(&f.pageSize,
+		"page_size",
+		"s", 100,
+		"The size of pages to fetch logs by, maximum is 100.")
+}
+
+func (f *auditFilter) validate() error {
+	switch f.operation {
+	case "", "create", "delete", "push", "pull":
+	default:
+		return errors.New("operation must be one of [create|delete|push|pull]")
+	}
+	if f.pageSize <= 0 || f.pageSize > 100 {
+		return errors.New("page_size must be in range [1, 100]")
+	}
+	return nil
+}
+
+// url returns the URL of access logs between the unix timestamps begin and
+// end, which are ignored if zero.
+func (f *auditFilter) url(begin, end int64) string {
+	targetURL := utils.URLGen("/api/logs")
+	if f.projectID != 0 {
+		targetURL = utils.URLGen("/api/projects/" + strconv.FormatInt(f.projectID, 10) + "/logs")
+	}
+
+	q := url.Values{}
+	if f.username != "" {
+		q.Set("username", f.username)
+	}
+	if f.repository != "" {
+		q.Set("repository", f.repository)
+	}
+	if f.tag != "" {
+		q.Set("tag", f.tag)
+	}
+	if f.operation != "" {
+		q.Set("operation", f.operation)
+	}
+	if begin != 0 {
+		q.Set("begin_timestamp", strconv.FormatInt(begin, 10))
+	}
+	if end != 0 {
+		q.Set("end_timestamp", strconv.FormatInt(end, 10))
+	}
+
+	if len(q) == 0 {
+		return targetURL
+	}
+	return targetURL + "?" + q.Encode()
+}
+
+// fetchAuditLogs fetches the access logs between begin and end with an ID
+// greater than afterID, ordered from the oldest.
+//
+// NOTE: Harbor returns logs from the newest, so the pages shift when new logs
+// come during fetching, the duplicated entries are dropped.
+func fetchAuditLogs(f *auditFilter, begin, end, afterID int64) ([]accessLog, error) {
+	var logs []accessLog
+	seen := make(map[int64]bool)
+
+	err := utils.GetPages(f.url(begin, end), f.pageSize, func(pageURL string) (int, error) {
+		var page []accessLog
+		if err := utils.GetStruct(pageURL, &page); err != nil {
+			return 0, err
+		}
+
+		for _, l := range page {
+			if l.LogID <= afterID {
+				// the rest are fetched already.
+				return 0, nil
+			}
+			if !seen[l.LogID] {
+				seen[l.LogID] = true
+				logs = append(logs, l)
+			}
+		}
+		return len(page), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].LogID < logs[j].LogID
+	})
+	return logs, nil
+}
+
+var auditCSVHeader = []string{"log_id", "op_time", "username", "operation", "project_id", "repository", "tag"}
+
+// writeAuditLogs writes logs in format 'csv', 'jsonl' or 'text'. The header of
+// CSV is written only if header is true.
+func writeAuditLogs(w io.Writer, format string, logs []accessLog, header bool) error {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		if header {
+			cw.Write(auditCSVHeader)
+		}
+		for _, l := range logs {
+			cw.Write([]string{
+				strconv.FormatInt(l.LogID, 10), l.OpTime, l.Username, l.Operation,
+				strconv.FormatInt(l.ProjectID, 10), l.RepoName, l.RepoTag,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		for _, l := range logs {
+			if err := enc.Encode(l); err != nil {
+				return err
+			}
+		}
+
+	default:
+		for _, l := range logs {
+			image := l.RepoName
+			if l.RepoTag != "" && l.RepoTag != "N/A" {
+				image += ":" + l.RepoTag
+			}
+			if _, err := fmt.Fprintf(w, "%s  %-8s %-7s %s\n", l.OpTime, l.Username, l.Operation, image); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
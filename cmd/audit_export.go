@@ -0,0 +1,129 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// auditExportCmd represents the export command
+var auditExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export access logs in a time range as CSV or JSON lines.",
+	Long: `This command fetches all pages of access logs between '--since' and '--until', and writes them ordered from the oldest as CSV or JSON lines.
+
+e.g. harborctl audit export --since 2018-10-01 --until 2018-11-01 --format jsonl -f 2018-10.jsonl`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := exportAudit(); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var auditExport struct {
+	auditFilter
+	since  string
+	until  string
+	format string
+	file   string
+}
+
+func init() {
+	auditCmd.AddCommand(auditExportCmd)
+
+	addAuditFilterFlags(auditExportCmd, &auditExport.auditFilter)
+
+	auditExportCmd.Flags().StringVarP(&auditExport.since,
+		"since",
+		"", "30d",
+		"Export logs since the time.")
+
+	auditExportCmd.Flags().StringVarP(&auditExport.until,
+		"until",
+		"", "",
+		"Export logs until the time. (now if not set)")
+
+	auditExportCmd.Flags().StringVarP(&auditExport.format,
+		"format",
+		"", "csv",
+		"The output format, valid values are 'csv' and 'jsonl'.")
+
+	auditExportCmd.Flags().StringVarP(&auditExport.file,
+		"file",
+		"f", "",
+		"The file to write into. (stdout if not set)")
+}
+
+func exportAudit() error {
+	if err := auditExport.validate(); err != nil {
+		return err
+	}
+	if auditExport.format != "csv" && auditExport.format != "jsonl" {
+		return errors.New("format must be one of [csv|jsonl]")
+	}
+
+	now := time.Now()
+	since, err := utils.ParseTimeExpr(auditExport.since, now)
+	if err != nil {
+		return err
+	}
+	var end int64
+	if auditExport.until != "" {
+		until, err := utils.ParseTimeExpr(auditExport.until, now)
+		if err != nil {
+			return err
+		}
+		if !until.After(since) {
+			return errors.New("until must be later than since")
+		}
+		end = until.Unix()
+	}
+
+	logs, err := fetchAuditLogs(&auditExport.auditFilter, since.Unix(), end, 0)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if auditExport.file != "" {
+		f, err := os.Create(auditExport.file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := writeAuditLogs(w, auditExport.format, logs, true); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "<== Exported %d logs since %s\n", len(logs), since.Format(time.RFC3339))
+	return nil
+}
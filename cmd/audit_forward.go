@@ -0,0 +1,390 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// auditForwardCmd represents the forward command
+var auditForwardCmd = &cobra.Command{
+	Use:   "forward",
+	Short: "Forward new access logs to syslog, a webhook or files continuously.",
+	Long: `This command polls new access logs, and ships them to the sinks in batches:
+
+- syslog:  RFC5424 messages over UDP or TCP (octet counting framed).
+- webhook: JSON arrays of logs posted to an HTTP endpoint.
+- file:    JSON lines appended to a local file, which is rotated by size.
+
+The ID of the last log shipped to each sink is saved in the checkpoint file after each batch, so a restarted forwarder continues from where it stopped. When there is no checkpoint yet, logs since '--since' are forwarded first, and the time is saved in the checkpoint before the first poll, so logs written while the forwarder is down are not skipped.
+
+NOTE:
+- Multiple sinks can be given separated by comma, each sink keeps its own position, so a failing sink is retried on the next poll without resending logs to the others.
+- Delivery is at least once: a log may be shipped twice if the forwarder stops between shipping a batch and saving the checkpoint, or a sink fails after receiving a log without acknowledging it (e.g. a webhook timing out).
+- With '--once', the command forwards the pending logs and exits, which suits a cron job.
+
+e.g. harborctl audit forward --sink syslog,file --syslog_address siem.example.com:514 --file_path /var/log/harbor/audit.log`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := forwardAudit(); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var auditForward struct {
+	auditFilter
+	since          string
+	checkpoint     string
+	interval       time.Duration
+	batchSize      int
+	once           bool
+	sinks          []string
+	syslogNetwork  string
+	syslogAddress  string
+	syslogFacility string
+	syslogSDID     string
+	webhookURL     string
+	filePath       string
+	fileMaxSize    int64
+	fileMaxBackups int
+}
+
+func init() {
+	auditCmd.AddCommand(auditForwardCmd)
+
+	addAuditFilterFlags(auditForwardCmd, &auditForward.auditFilter)
+
+	auditForwardCmd.Flags().StringVarP(&auditForward.since,
+		"since",
+		"", "now",
+		"Forward logs since the time when there is no checkpoint.")
+
+	auditForwardCmd.Flags().StringVarP(&auditForward.checkpoint,
+		"checkpoint",
+		"c", "",
+		"The checkpoint file to save the last forwarded log in, default is .audit_checkpoint.json in the directory of the config file.")
+
+	auditForwardCmd.Flags().DurationVarP(&auditForward.interval,
+		"interval",
+		"i", 10*time.Second,
+		"The interval of polling new logs.")
+
+	auditForwardCmd.Flags().IntVarP(&auditForward.batchSize,
+		"batch_size",
+		"b", 100,
+		"The maximum number of logs shipped in a batch.")
+
+	auditForwardCmd.Flags().BoolVarP(&auditForward.once,
+		"once",
+		"", false,
+		"Forward the pending logs and exit.")
+
+	auditForwardCmd.Flags().StringSliceVarP(&auditForward.sinks,
+		"sink",
+		"", nil,
+		"(REQUIRED) The sinks to forward logs to, valid values are 'syslog', 'webhook' and 'file'.")
+	auditForwardCmd.MarkFlagRequired("sink")
+
+	auditForwardCmd.Flags().StringVarP(&auditForward.syslogNetwork,
+		"syslog_network",
+		"", "udp",
+		"The network of syslog, valid values are 'udp' and 'tcp'.")
+
+	auditForwardCmd.Flags().StringVarP(&auditForward.syslogAddress,
+		"syslog_address",
+		"", "",
+		"The address of syslog server, e.g. 'siem.example.com:514'.")
+
+	auditForwardCmd.Flags().StringVarP(&auditForward.syslogFacility,
+		"syslog_facility",
+		"", "local0",
+		"The facility of syslog messages.")
+
+	auditForwardCmd.Flags().StringVarP(&auditForward.syslogSDID,
+		"syslog_sd_id",
+		"", "harbor@32473",
+		"The SD-ID of the structured data of syslog messages, of format 'name@<private enterprise number>'. The default is a placeholder of the example number of RFC5424, which should be replaced with your own.")
+
+	auditForwardCmd.Flags().StringVarP(&auditForward.webhookURL,
+		"webhook_url",
+		"", "",
+		"The URL of webhook to post logs to.")
+
+	auditForwardCmd.Flags().StringVarP(&auditForward.filePath,
+		"file_path",
+		"", "",
+		"The file to append logs to.")
+
+	auditForwardCmd.Flags().Int64VarP(&auditForward.fileMaxSize,
+		"file_max_size",
+		"", 100,
+		"The size in MiB to rotate the file at, 0 for never.")
+
+	auditForwardCmd.Flags().IntVarP(&auditForward.fileMaxBackups,
+		"file_max_backups",
+		"", 5,
+		"The number of rotated files to keep.")
+}
+
+// defaultAuditCheckpoint returns the checkpoint file beside the config file,
+// or in conf/ if no config file is used.
+func defaultAuditCheckpoint() string {
+	if f := viper.ConfigFileUsed(); f != "" {
+		return filepath.Join(filepath.Dir(f), ".audit_checkpoint.json")
+	}
+	return filepath.Join("conf", ".audit_checkpoint.json")
+}
+
+// auditCheckpoint records the last log forwarded to each sink.
+type auditCheckpoint struct {
+	// LastLogID is the position of the sinks not in Sinks, which is the
+	// only one kept by the checkpoints of earlier versions.
+	LastLogID int64            `json:"last_log_id"`
+	Sinks     map[string]int64 `json:"sinks,omitempty"`
+	// Since is the Unix time logs are forwarded since.
+	Since   int64  `json:"since,omitempty"`
+	Updated string `json:"updated"`
+}
+
+func loadAuditCheckpoint(path string) (*auditCheckpoint, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cp auditCheckpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, fmt.Errorf("malformed checkpoint %s: %v", path, err)
+	}
+	return &cp, nil
+}
+
+// saveAuditCheckpoint replaces the checkpoint file atomically.
+func saveAuditCheckpoint(path string, cp *auditCheckpoint) error {
+	cp.Updated = time.Now().Format(time.RFC3339)
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// position returns the ID of the last log forwarded to the sink.
+func (cp *auditCheckpoint) position(sink string) int64 {
+	if id, ok := cp.Sinks[sink]; ok {
+		return id
+	}
+	return cp.LastLogID
+}
+
+// minPosition returns the ID of the last log forwarded to all sinks.
+func (cp *auditCheckpoint) minPosition() int64 {
+	min := int64(-1)
+	for _, name := range auditForward.sinks {
+		if id := cp.position(name); min < 0 || id < min {
+			min = id
+		}
+	}
+	if min < 0 {
+		return 0
+	}
+	return min
+}
+
+func newAuditSinks() ([]auditSink, error) {
+	var sinks []auditSink
+	seen := make(map[string]bool)
+	for i, name := range auditForward.sinks {
+		var (
+			s   auditSink
+			err error
+		)
+		name = strings.TrimSpace(name)
+		auditForward.sinks[i] = name
+		switch {
+		case seen[name]:
+			err = fmt.Errorf("duplicate sink %q", name)
+		case name == "syslog":
+			s, err = newSyslogSink(auditForward.syslogNetwork, auditForward.syslogAddress, auditForward.syslogFacility, auditForward.syslogSDID)
+		case name == "webhook":
+			s, err = newWebhookSink(auditForward.webhookURL)
+		case name == "file":
+			s, err = newFileSink(auditForward.filePath, auditForward.fileMaxSize<<20, auditForward.fileMaxBackups)
+		default:
+			err = fmt.Errorf("sink must be one of [syslog|webhook|file], got %q", name)
+		}
+		seen[name] = true
+		if err != nil {
+			for _, s := range sinks {
+				s.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+func forwardAudit() error {
+	if err := auditForward.validate(); err != nil {
+		return err
+	}
+	if auditForward.batchSize <= 0 {
+		return errors.New("batch_size must be positive")
+	}
+	if auditForward.interval < time.Second {
+		return errors.New("interval must be at least 1s")
+	}
+	if auditForward.checkpoint == "" {
+		auditForward.checkpoint = defaultAuditCheckpoint()
+	}
+
+	sinks, err := newAuditSinks()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, s := range sinks {
+			s.Close()
+		}
+	}()
+
+	cp, err := loadAuditCheckpoint(auditForward.checkpoint)
+	if err != nil {
+		return err
+	}
+
+	if cp != nil {
+		fmt.Fprintln(os.Stderr, "==> Continue from checkpoint, last log ID:", cp.minPosition())
+	} else {
+		since, err := utils.ParseTimeExpr(auditForward.since, time.Now())
+		if err != nil {
+			return err
+		}
+		// save the starting position before the first poll, so the logs
+		// written before a restart are not skipped by re-evaluating '--since'.
+		cp = &auditCheckpoint{Since: since.Unix()}
+		if err := saveAuditCheckpoint(auditForward.checkpoint, cp); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "==> No checkpoint, forward logs since", since.Format(time.RFC3339))
+	}
+	if cp.Sinks == nil {
+		cp.Sinks = make(map[string]int64)
+	}
+
+	for {
+		logs, err := fetchAuditLogs(&auditForward.auditFilter, cp.Since, 0, cp.minPosition())
+		if err != nil {
+			if auditForward.once {
+				return err
+			}
+			fmt.Fprintln(os.Stderr, "error:", err)
+		}
+
+		// the sinks failed in this poll, which are retried on the next one.
+		failed := make([]bool, len(sinks))
+		for len(logs) > 0 {
+			n := auditForward.batchSize
+			if n > len(logs) {
+				n = len(logs)
+			}
+			batch := logs[:n]
+
+			if sendAuditBatch(sinks, cp, batch, failed) == 0 {
+				break
+			}
+			if err := saveAuditCheckpoint(auditForward.checkpoint, cp); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "<== Forwarded %d logs, last log ID: %d\n", n, batch[n-1].LogID)
+			logs = logs[n:]
+		}
+
+		if auditForward.once {
+			for _, f := range failed {
+				if f {
+					return errors.New("some logs are not forwarded")
+				}
+			}
+			return nil
+		}
+		time.Sleep(auditForward.interval)
+	}
+}
+
+// sendAuditBatch ships the logs of the batch not shipped yet to each sink
+// not failed, and advances the positions of sinks in the checkpoint. It
+// returns the number of sinks still working.
+func sendAuditBatch(sinks []auditSink, cp *auditCheckpoint, batch []accessLog, failed []bool) int {
+	working := 0
+	for i, s := range sinks {
+		if failed[i] {
+			continue
+		}
+
+		name := auditForward.sinks[i]
+		pos := cp.position(name)
+		logs := batch
+		for len(logs) > 0 && logs[0].LogID <= pos {
+			logs = logs[1:]
+		}
+		if len(logs) == 0 {
+			working++
+			continue
+		}
+
+		n, err := s.Send(logs)
+		if n > 0 {
+			cp.Sinks[name] = logs[n-1].LogID
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: sink %s: %v\n", name, err)
+			failed[i] = true
+			continue
+		}
+		working++
+	}
+	return working
+}
@@ -0,0 +1,275 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// auditSink is where access logs are forwarded to.
+type auditSink interface {
+	// Send ships the logs in order, and returns the number of logs shipped
+	// before an error, which are not sent again.
+	Send(logs []accessLog) (int, error)
+	Close() error
+}
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSink sends logs as RFC5424 messages over UDP or TCP. Messages over
+// TCP are framed by octet counting of RFC6587.
+type syslogSink struct {
+	network  string
+	address  string
+	hostname string
+	facility int
+	sdID     string
+	conn     net.Conn
+}
+
+func newSyslogSink(network, address, facility, sdID string) (*syslogSink, error) {
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("syslog network must be one of [udp|tcp], got %q", network)
+	}
+	if address == "" {
+		return nil, fmt.Errorf("syslog address is required")
+	}
+	f, ok := syslogFacilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", facility)
+	}
+	// NOTE: an SD-ID of RFC5424 is at most 32 printable characters except
+	// '=', ' ', ']' and '"', and must have '@' unless registered by IANA.
+	if i := strings.Index(sdID, "@"); i <= 0 || i == len(sdID)-1 || len(sdID) > 32 ||
+		strings.IndexFunc(sdID, func(r rune) bool { return r <= ' ' || r > '~' || strings.ContainsRune(`=]"`, r) }) >= 0 {
+		return nil, fmt.Errorf("invalid syslog SD-ID %q, must be of format 'name@<private enterprise number>'", sdID)
+	}
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	return &syslogSink{network: network, address: address, hostname: hostname, facility: f, sdID: sdID}, nil
+}
+
+// sdEscape escapes a PARAM-VALUE of structured data.
+var sdEscape = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+func (s *syslogSink) format(l accessLog) string {
+	// NOTE: the severity is 'informational'.
+	pri := s.facility*8 + 6
+
+	ts := l.OpTime
+	if ts == "" {
+		ts = "-"
+	}
+
+	sd := fmt.Sprintf(`[%s log_id="%d" username="%s" operation="%s" project_id="%d" repository="%s" tag="%s"]`,
+		s.sdID, l.LogID, sdEscape.Replace(l.Username), sdEscape.Replace(l.Operation), l.ProjectID,
+		sdEscape.Replace(l.RepoName), sdEscape.Replace(l.RepoTag))
+
+	msg := fmt.Sprintf("%s %s %s:%s", l.Username, l.Operation, l.RepoName, l.RepoTag)
+
+	return fmt.Sprintf("<%d>1 %s %s harbor - %s %s %s", pri, ts, s.hostname, l.Operation, sd, msg)
+}
+
+func (s *syslogSink) Send(logs []accessLog) (int, error) {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, 10*time.Second)
+		if err != nil {
+			return 0, err
+		}
+		s.conn = conn
+	}
+
+	for i, l := range logs {
+		msg := s.format(l)
+		if s.network == "tcp" {
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		}
+
+		s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if _, err := s.conn.Write([]byte(msg)); err != nil {
+			// reconnect on the next try.
+			s.conn.Close()
+			s.conn = nil
+			return i, err
+		}
+	}
+	return len(logs), nil
+}
+
+func (s *syslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// webhookSink posts logs as JSON arrays to an HTTP endpoint.
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhookSink(url string) (*webhookSink, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+	return &webhookSink{url: url, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (s *webhookSink) Send(logs []accessLog) (int, error) {
+	b, err := json.Marshal(logs)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return 0, fmt.Errorf("POST %s: %s %s", s.url, resp.Status, bytes.TrimSpace(body))
+	}
+	return len(logs), nil
+}
+
+func (s *webhookSink) Close() error {
+	return nil
+}
+
+// fileSink appends logs as JSON lines to a file, which is rotated when it
+// exceeds maxSize, keeping at most maxBackups of the rotated ones as
+// path.1, path.2 and so on.
+type fileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	f          *os.File
+	size       int64
+}
+
+func newFileSink(path string, maxSize int64, maxBackups int) (*fileSink, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file path is required")
+	}
+	s := &fileSink{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.f, s.size = f, fi.Size()
+	return nil
+}
+
+func (s *fileSink) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return s.reopen(err)
+	}
+
+	if s.maxBackups > 0 {
+		os.Remove(s.path + "." + strconv.Itoa(s.maxBackups))
+		for i := s.maxBackups - 1; i >= 1; i-- {
+			os.Rename(s.path+"."+strconv.Itoa(i), s.path+"."+strconv.Itoa(i+1))
+		}
+		if err := os.Rename(s.path, s.path+".1"); err != nil {
+			return s.reopen(err)
+		}
+	} else if err := os.Remove(s.path); err != nil {
+		return s.reopen(err)
+	}
+
+	return s.open()
+}
+
+// reopen reopens the file not rotated for err, so that it is rotated again on
+// the next send, rather than written after closed.
+func (s *fileSink) reopen(err error) error {
+	if oerr := s.open(); oerr != nil {
+		return fmt.Errorf("rotate %s: %v, and reopen: %v", s.path, err, oerr)
+	}
+	return fmt.Errorf("rotate %s: %v", s.path, err)
+}
+
+func (s *fileSink) Send(logs []accessLog) (int, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// ends[i] is the offset in buf where the line of logs[i] ends.
+	ends := make([]int, len(logs))
+	for i, l := range logs {
+		if err := enc.Encode(l); err != nil {
+			return 0, err
+		}
+		ends[i] = buf.Len()
+	}
+
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(buf.Len()) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := s.f.Write(buf.Bytes())
+	s.size += int64(n)
+	if err == nil {
+		err = s.f.Sync()
+	}
+	if err != nil {
+		// the lines written completely are shipped.
+		return sort.SearchInts(ends, n+1), err
+	}
+	return len(logs), nil
+}
+
+func (s *fileSink) Close() error {
+	return s.f.Close()
+}
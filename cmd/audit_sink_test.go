@@ -0,0 +1,85 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSinkRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	s, err := newFileSink(path, 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	logs := []accessLog{{LogID: 1, Username: "alice", Operation: "push", RepoName: "library/nginx", RepoTag: "1.15"}}
+	for i := 0; i < 2; i++ {
+		if n, err := s.Send(logs); n != 1 || err != nil {
+			t.Fatalf("Send = %d, %v", n, err)
+		}
+	}
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Fatalf("not rotated: %v", err)
+	}
+
+	// a backup which can be neither removed nor replaced fails the rotation.
+	os.Remove(path + ".1")
+	if err := os.MkdirAll(filepath.Join(path+".1", "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Send(logs); n != 0 || err == nil || !strings.Contains(err.Error(), "rotate") {
+		t.Fatalf("Send = %d, %v, want a rotation error", n, err)
+	}
+
+	// the file is reopened, and rotated on the next send.
+	os.RemoveAll(path + ".1")
+	if n, err := s.Send(logs); n != 1 || err != nil {
+		t.Fatalf("Send after the rotation failed = %d, %v", n, err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil || strings.Count(string(b), "\n") != 1 {
+		t.Errorf("%s = %q, %v", path, b, err)
+	}
+}
+
+func TestSyslogSinkSDID(t *testing.T) {
+	for _, id := range []string{"harbor@32473", "audit@12345.1"} {
+		s, err := newSyslogSink("udp", "127.0.0.1:514", "local0", id)
+		if err != nil {
+			t.Errorf("newSyslogSink(%q): %v", id, err)
+			continue
+		}
+		if msg := s.format(accessLog{LogID: 1}); !strings.Contains(msg, "["+id+" log_id=\"1\"") {
+			t.Errorf("format with SD-ID %q = %s", id, msg)
+		}
+	}
+	for _, id := range []string{"", "harbor", "@32473", "harbor@", "har bor@1", "harbor@1]", "a=b@1", strings.Repeat("a", 30) + "@123"} {
+		if _, err := newSyslogSink("udp", "127.0.0.1:514", "local0", id); err == nil {
+			t.Errorf("newSyslogSink(%q) succeeded, want error", id)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// auditTailCmd represents the tail command
+var auditTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Show the latest access logs, and optionally stream new ones.",
+	Long:  `This command shows the latest access logs since '--since', and with '--follow' keeps polling and streams the new logs as they come.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := tailAudit(); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var auditTail struct {
+	auditFilter
+	since    string
+	lines    int
+	follow   bool
+	interval time.Duration
+	format   string
+}
+
+func init() {
+	auditCmd.AddCommand(auditTailCmd)
+
+	addAuditFilterFlags(auditTailCmd, &auditTail.auditFilter)
+
+	auditTailCmd.Flags().StringVarP(&auditTail.since,
+		"since",
+		"", "1d",
+		"Show logs since the time.")
+
+	auditTailCmd.Flags().IntVarP(&auditTail.lines,
+		"lines",
+		"l", 10,
+		"Show the latest N logs at first, 0 for all logs since the time.")
+
+	auditTailCmd.Flags().BoolVarP(&auditTail.follow,
+		"follow",
+		"f", false,
+		"Keep polling and stream new logs.")
+
+	auditTailCmd.Flags().DurationVarP(&auditTail.interval,
+		"interval",
+		"i", 5*time.Second,
+		"The interval of polling with '--follow'.")
+
+	auditTailCmd.Flags().StringVarP(&auditTail.format,
+		"format",
+		"", "text",
+		"The output format, valid values are 'text' and 'jsonl'.")
+}
+
+func tailAudit() error {
+	if err := auditTail.validate(); err != nil {
+		return err
+	}
+	if auditTail.format != "text" && auditTail.format != "jsonl" {
+		return errors.New("format must be one of [text|jsonl]")
+	}
+	if auditTail.interval < time.Second {
+		return errors.New("interval must be at least 1s")
+	}
+
+	since, err := utils.ParseTimeExpr(auditTail.since, time.Now())
+	if err != nil {
+		return err
+	}
+
+	logs, err := fetchAuditLogs(&auditTail.auditFilter, since.Unix(), 0, 0)
+	if err != nil {
+		return err
+	}
+
+	var lastID int64
+	if len(logs) > 0 {
+		lastID = logs[len(logs)-1].LogID
+	}
+	if auditTail.lines > 0 && len(logs) > auditTail.lines {
+		logs = logs[len(logs)-auditTail.lines:]
+	}
+	if err := writeAuditLogs(os.Stdout, auditTail.format, logs, false); err != nil {
+		return err
+	}
+
+	for auditTail.follow {
+		time.Sleep(auditTail.interval)
+
+		// NOTE: only the logs newer than the last one shown are fetched.
+		logs, err := fetchAuditLogs(&auditTail.auditFilter, since.Unix(), 0, lastID)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			continue
+		}
+		if len(logs) == 0 {
+			continue
+		}
+
+		lastID = logs[len(logs)-1].LogID
+		if err := writeAuditLogs(os.Stdout, auditTail.format, logs, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/moooofly/harborctl/utils/chart"
+	"github.com/spf13/cobra"
+)
+
+// helmChartCmd represents the chart command
+var helmChartCmd = &cobra.Command{
+	Use:   "chart",
+	Short: "Manage helm charts in the chart repositories of projects.",
+	Long: `The subcommand of managing helm charts without helm, by the chart repositories of projects.
+
+NOTE: for the raw '/chartrepo' API, see 'harborctl chartrepo'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Use \"harborctl chart --help\" for more information about this command.")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(helmChartCmd)
+}
+
+// parseChartRef parses a chart reference of format 'PROJECT/NAME'.
+func parseChartRef(s string) (string, string, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid chart %q, must be of format 'PROJECT/NAME'", s)
+	}
+	return parts[0], parts[1], nil
+}
+
+// fetchChartIndex fetches the index.yaml of the chart repository of the project.
+func fetchChartIndex(project string) (*chart.IndexFile, error) {
+	_, body, err := utils.Send("GET", utils.URLGen("/chartrepo/"+project+"/index.yaml"), nil)
+	if err != nil {
+		return nil, err
+	}
+	return chart.ParseIndex(body)
+}
+
+// chartURL returns the absolute URL of a chart archive in the index, whose
+// URLs are relative to the repository usually.
+func chartURL(project, u string) (string, error) {
+	ref, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+	if ref.IsAbs() {
+		return u, nil
+	}
+
+	base, err := url.Parse(utils.URLGen("/chartrepo/" + project + "/"))
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// downloadChart downloads the archive of the chart version, and verifies its digest.
+func downloadChart(project string, cv *chart.ChartVersion) ([]byte, error) {
+	if len(cv.URLs) == 0 {
+		return nil, fmt.Errorf("no URL of chart %s-%s in index", cv.Name, cv.Version)
+	}
+	u, err := chartURL(project, cv.URLs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	_, body, err := utils.Send("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cv.Digest != "" && chart.Digest(body) != cv.Digest {
+		return nil, errors.New("digest mismatch of chart " + cv.Name + "-" + cv.Version + ", expect " + cv.Digest + ", got " + chart.Digest(body))
+	}
+	return body, nil
+}
@@ -0,0 +1,225 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/moooofly/harborctl/utils/chart"
+	"github.com/spf13/cobra"
+)
+
+// chartDeleteVersionCmd represents the delete command
+var chartDeleteVersionCmd = &cobra.Command{
+	Use:   "delete PROJECT/NAME",
+	Short: "Delete a version of a chart.",
+	Long:  `This command deletes the version of the chart from the chart repository of the project, while the other versions are kept.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := deleteChartVersion(args[0]); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var chartDeleteVersion struct {
+	version string
+}
+
+// chartPruneCmd represents the prune command
+var chartPruneCmd = &cobra.Command{
+	Use:   "prune PROJECT[/NAME]",
+	Short: "Delete the old versions of charts.",
+	Long: `This command deletes the old versions of the chart, or of all the charts in the project, except the latest '--keep' versions and the ones matching '--keep_range'. With '--prerelease', only the pre-release versions (e.g. '1.2.0-ci.42') are pruned and counted.
+
+The range is of constraints separated by spaces or commas for 'and', and by '||' for 'or', e.g. '>=1.2.0 <2.0.0', '^1.2', '~1.2.3', '1.x' or '>=2 || 1.4.x'.
+
+The versions to delete are only listed unless '--yes' is set.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := pruneCharts(args[0]); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var chartPrune struct {
+	keep       int
+	keepRange  string
+	prerelease bool
+	yes        bool
+}
+
+func init() {
+	helmChartCmd.AddCommand(chartDeleteVersionCmd)
+
+	chartDeleteVersionCmd.Flags().StringVarP(&chartDeleteVersion.version,
+		"version",
+		"v", "",
+		"(REQUIRED) The chart version to delete.")
+	chartDeleteVersionCmd.MarkFlagRequired("version")
+
+	helmChartCmd.AddCommand(chartPruneCmd)
+
+	chartPruneCmd.Flags().IntVarP(&chartPrune.keep,
+		"keep",
+		"k", 0,
+		"Keep the latest N versions of each chart.")
+
+	chartPruneCmd.Flags().StringVarP(&chartPrune.keepRange,
+		"keep_range",
+		"r", "",
+		"Keep the versions matching the semantic version range.")
+
+	chartPruneCmd.Flags().BoolVarP(&chartPrune.prerelease,
+		"prerelease",
+		"", false,
+		"Prune the pre-release versions only.")
+
+	chartPruneCmd.Flags().BoolVarP(&chartPrune.yes,
+		"yes",
+		"y", false,
+		"Delete the versions, otherwise they are only listed.")
+}
+
+func deleteChart(project, name, version string) error {
+	_, _, err := utils.Send("DELETE", utils.URLGen("/api/chartrepo/"+project+"/charts/"+name+"/"+version), nil)
+	return err
+}
+
+func deleteChartVersion(ref string) error {
+	project, name, err := parseChartRef(ref)
+	if err != nil {
+		return err
+	}
+
+	if err := deleteChart(project, name, chartDeleteVersion.version); err != nil {
+		if utils.IsStatus(err, 404) {
+			return fmt.Errorf("no version %s of chart %s in project %s", chartDeleteVersion.version, name, project)
+		}
+		return err
+	}
+	fmt.Printf("Deleted %s-%s from project %s.\n", name, chartDeleteVersion.version, project)
+	return nil
+}
+
+// pruneVersions returns the versions to delete of a chart, whose versions are
+// sorted from the latest.
+func pruneVersions(versions []*chart.ChartVersion, keep int, keepRange *chart.Range, prerelease bool) []*chart.ChartVersion {
+	var prune []*chart.ChartVersion
+	var kept int
+	for _, cv := range versions {
+		v, err := chart.ParseVersion(cv.Version)
+		if prerelease && (err != nil || v.Prerelease == "") {
+			continue
+		}
+
+		if kept < keep {
+			kept++
+			continue
+		}
+		if keepRange != nil && err == nil && keepRange.Match(v) {
+			continue
+		}
+		prune = append(prune, cv)
+	}
+	return prune
+}
+
+func pruneCharts(ref string) error {
+	project, name := ref, ""
+	if strings.Contains(ref, "/") {
+		var err error
+		if project, name, err = parseChartRef(ref); err != nil {
+			return err
+		}
+	}
+
+	if chartPrune.keep <= 0 && chartPrune.keepRange == "" {
+		return errors.New("at least one of --keep and --keep_range is required")
+	}
+	var keepRange *chart.Range
+	if chartPrune.keepRange != "" {
+		var err error
+		if keepRange, err = chart.ParseRange(chartPrune.keepRange); err != nil {
+			return err
+		}
+	}
+
+	index, err := fetchChartIndex(project)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	if name != "" {
+		if _, ok := index.Entries[name]; !ok {
+			return fmt.Errorf("no chart named %q in project %s", name, project)
+		}
+		names = append(names, name)
+	} else {
+		for n := range index.Entries {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+	}
+
+	var prune []*chart.ChartVersion
+	for _, n := range names {
+		prune = append(prune, pruneVersions(index.Entries[n], chartPrune.keep, keepRange, chartPrune.prerelease)...)
+	}
+	if len(prune) == 0 {
+		fmt.Println("<== Nothing to prune.")
+		return nil
+	}
+
+	var failed int
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tCREATED\tRESULT")
+	for _, cv := range prune {
+		result := "to delete"
+		if chartPrune.yes {
+			result = "deleted"
+			if err := deleteChart(project, cv.Name, cv.Version); err != nil {
+				result = "failed: " + err.Error()
+				failed++
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", cv.Name, cv.Version, cv.Created, result)
+	}
+	w.Flush()
+
+	if !chartPrune.yes {
+		fmt.Println("<== Nothing is changed, set '--yes' to delete them.")
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d versions are not deleted", failed, len(prune))
+	}
+	return nil
+}
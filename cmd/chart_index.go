@@ -0,0 +1,108 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils/chart"
+	"github.com/spf13/cobra"
+)
+
+// chartIndexCmd represents the index command
+var chartIndexCmd = &cobra.Command{
+	Use:   "index PROJECT",
+	Short: "Show the charts in the index of a project.",
+	Long:  `This command reads the index.yaml of the chart repository of the project, and shows the versions of the charts with their app versions, digests and creation time, from the latest version of each chart.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := showChartIndex(args[0]); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var chartIndex struct {
+	name    string
+	version string
+}
+
+func init() {
+	helmChartCmd.AddCommand(chartIndexCmd)
+
+	chartIndexCmd.Flags().StringVarP(&chartIndex.name,
+		"name",
+		"n", "",
+		"Show the chart of the name only.")
+
+	chartIndexCmd.Flags().StringVarP(&chartIndex.version,
+		"version",
+		"v", "",
+		"Show the versions matching the semantic version range only, e.g. '>=1.2 <2'.")
+}
+
+func showChartIndex(project string) error {
+	var r *chart.Range
+	if chartIndex.version != "" {
+		var err error
+		if r, err = chart.ParseRange(chartIndex.version); err != nil {
+			return err
+		}
+	}
+
+	index, err := fetchChartIndex(project)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for n := range index.Entries {
+		if chartIndex.name == "" || n == chartIndex.name {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tAPP VERSION\tDIGEST\tCREATED\tDESCRIPTION")
+	for _, n := range names {
+		for _, cv := range index.Entries[n] {
+			if r != nil {
+				v, err := chart.ParseVersion(cv.Version)
+				if err != nil || !r.Match(v) {
+					continue
+				}
+			}
+
+			digest := cv.Digest
+			if len(digest) > 12 {
+				digest = digest[:12]
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", cv.Name, cv.Version, cv.AppVersion, digest, cv.Created, truncate(cv.Description, 50))
+		}
+	}
+	w.Flush()
+	return nil
+}
@@ -0,0 +1,265 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/moooofly/harborctl/utils/chart"
+	"github.com/spf13/cobra"
+)
+
+// chartInspectCmd represents the inspect command
+var chartInspectCmd = &cobra.Command{
+	Use:   "inspect PROJECT/NAME",
+	Short: "Show the metadata, dependencies, values and templates of a chart.",
+	Long: `This command downloads the archive of the chart version and shows its Chart.yaml, its dependencies (in Chart.yaml or requirements.yaml), the default values.yaml, the templates and the provenance status, without helm.
+
+The provenance status is the one recorded by Harbor. If the chart is signed, the digest in the provenance file is checked against the archive, and with '--verify' the signature is verified by 'gpg --verify' too.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := inspectChart(args[0]); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var chartInspect struct {
+	version string
+	verify  bool
+	keyring string
+}
+
+func init() {
+	helmChartCmd.AddCommand(chartInspectCmd)
+
+	chartInspectCmd.Flags().StringVarP(&chartInspect.version,
+		"version",
+		"v", "",
+		"The chart version, default is the latest stable one.")
+
+	chartInspectCmd.Flags().BoolVarP(&chartInspect.verify,
+		"verify",
+		"", false,
+		"Verify the signature of the provenance file by gpg.")
+
+	chartInspectCmd.Flags().StringVarP(&chartInspect.keyring,
+		"keyring",
+		"", "",
+		"The keyring of gpg to verify with, default is the one of gpg.")
+}
+
+// chartVersionDetail is the part of the chart version detail used.
+type chartVersionDetail struct {
+	Security struct {
+		Signature struct {
+			Signed   bool   `json:"signed"`
+			ProvFile string `json:"prov_file"`
+		} `json:"signature"`
+	} `json:"security"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+}
+
+func inspectChart(ref string) error {
+	project, name, err := parseChartRef(ref)
+	if err != nil {
+		return err
+	}
+
+	index, err := fetchChartIndex(project)
+	if err != nil {
+		return err
+	}
+	cv, err := index.Get(name, chartInspect.version)
+	if err != nil {
+		return err
+	}
+
+	var detail chartVersionDetail
+	if err := utils.GetStruct(utils.URLGen("/api/chartrepo/"+project+"/charts/"+name+"/"+cv.Version), &detail); err != nil {
+		return err
+	}
+
+	b, err := downloadChart(project, cv)
+	if err != nil {
+		return err
+	}
+	a, err := chart.LoadArchive(b)
+	if err != nil {
+		return err
+	}
+
+	printChartMetadata(a.Metadata, cv, &detail)
+
+	deps, err := a.Dependencies()
+	if err != nil {
+		return err
+	}
+	fmt.Println("\nDependencies:")
+	if len(deps) == 0 {
+		fmt.Println("  (none)")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  NAME\tVERSION\tREPOSITORY\tCONDITION\tVENDORED")
+		for i := range deps {
+			d := &deps[i]
+			name := d.Name
+			if d.Alias != "" {
+				name += " (as " + d.Alias + ")"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%t\n", name, d.Version, d.Repository, d.Condition, a.Vendored(d))
+		}
+		w.Flush()
+	}
+
+	fmt.Println("\nTemplates:")
+	templates := a.FileNames("templates/")
+	if len(templates) == 0 {
+		fmt.Println("  (none)")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, t := range templates {
+		fmt.Fprintf(w, "  %s\t%s\n", strings.TrimPrefix(t, "templates/"), humanSize(int64(len(a.Files[t]))))
+	}
+	w.Flush()
+
+	fmt.Println("\nValues (values.yaml):")
+	if values := bytes.TrimSpace(a.Files["values.yaml"]); len(values) == 0 {
+		fmt.Println("  (none)")
+	} else {
+		for _, l := range strings.Split(string(values), "\n") {
+			fmt.Println("  " + l)
+		}
+	}
+
+	fmt.Println("\nProvenance:")
+	return printChartProvenance(project, cv, b, &detail)
+}
+
+func printChartMetadata(m *chart.Metadata, cv *chart.ChartVersion, detail *chartVersionDetail) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", m.Name)
+	fmt.Fprintf(w, "Version:\t%s\n", m.Version)
+	fmt.Fprintf(w, "App Version:\t%s\n", m.AppVersion)
+	fmt.Fprintf(w, "API Version:\t%s\n", m.APIVersion)
+	if m.Type != "" {
+		fmt.Fprintf(w, "Type:\t%s\n", m.Type)
+	}
+	fmt.Fprintf(w, "Description:\t%s\n", m.Description)
+	if m.KubeVersion != "" {
+		fmt.Fprintf(w, "Kube Version:\t%s\n", m.KubeVersion)
+	}
+	if m.Home != "" {
+		fmt.Fprintf(w, "Home:\t%s\n", m.Home)
+	}
+	if len(m.Sources) > 0 {
+		fmt.Fprintf(w, "Sources:\t%s\n", strings.Join(m.Sources, ", "))
+	}
+	if len(m.Keywords) > 0 {
+		fmt.Fprintf(w, "Keywords:\t%s\n", strings.Join(m.Keywords, ", "))
+	}
+	for _, mt := range m.Maintainers {
+		s := mt.Name
+		if mt.Email != "" {
+			s += " <" + mt.Email + ">"
+		}
+		fmt.Fprintf(w, "Maintainer:\t%s\n", s)
+	}
+	if m.Deprecated {
+		fmt.Fprintf(w, "Deprecated:\ttrue\n")
+	}
+	fmt.Fprintf(w, "Created:\t%s\n", cv.Created)
+	fmt.Fprintf(w, "Digest:\tsha256:%s\n", cv.Digest)
+
+	var labels []string
+	for _, l := range detail.Labels {
+		labels = append(labels, l.Name)
+	}
+	if len(labels) > 0 {
+		fmt.Fprintf(w, "Labels:\t%s\n", strings.Join(labels, ", "))
+	}
+	w.Flush()
+}
+
+func printChartProvenance(project string, cv *chart.ChartVersion, archive []byte, detail *chartVersionDetail) error {
+	if !detail.Security.Signature.Signed {
+		fmt.Println("  Signed: false")
+		return nil
+	}
+	fmt.Println("  Signed: true")
+
+	u, err := chartURL(project, cv.URLs[0]+".prov")
+	if err != nil {
+		return err
+	}
+	_, prov, err := utils.Send("GET", u, nil)
+	if err != nil {
+		return fmt.Errorf("provenance: %v", err)
+	}
+
+	digests, err := chart.ProvenanceDigests(prov)
+	if err != nil {
+		return err
+	}
+	name := cv.Name + "-" + cv.Version + ".tgz"
+	switch d := digests[name]; {
+	case d == "":
+		fmt.Printf("  Digest: no digest of %s in provenance file\n", name)
+	case d == "sha256:"+chart.Digest(archive):
+		fmt.Println("  Digest: matched")
+	default:
+		fmt.Printf("  Digest: MISMATCHED, %s in provenance file\n", d)
+	}
+
+	if !chartInspect.verify {
+		return nil
+	}
+
+	args := []string{"--batch"}
+	if chartInspect.keyring != "" {
+		args = append(args, "--no-default-keyring", "--keyring", chartInspect.keyring)
+	}
+	args = append(args, "--verify")
+
+	var out bytes.Buffer
+	c := exec.Command("gpg", args...)
+	c.Stdin = bytes.NewReader(prov)
+	c.Stdout = &out
+	c.Stderr = &out
+	result := "verified"
+	if err := c.Run(); err != nil {
+		result = "FAILED (" + err.Error() + ")"
+	}
+	fmt.Println("  Signature: " + result)
+	for _, l := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		fmt.Println("    " + l)
+	}
+	return nil
+}
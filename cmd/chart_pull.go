@@ -0,0 +1,116 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// chartPullCmd represents the pull command
+var chartPullCmd = &cobra.Command{
+	Use:   "pull PROJECT/NAME",
+	Short: "Download a chart archive from a project.",
+	Long: `This command downloads the archive of the chart version from the chart repository of the project, as listed by its index.yaml, and verifies its digest.
+
+The latest stable version is downloaded if '--version' is not given.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := pullChart(args[0]); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var chartPull struct {
+	version     string
+	destination string
+	prov        bool
+}
+
+func init() {
+	helmChartCmd.AddCommand(chartPullCmd)
+
+	chartPullCmd.Flags().StringVarP(&chartPull.version,
+		"version",
+		"v", "",
+		"The chart version, default is the latest stable one.")
+
+	chartPullCmd.Flags().StringVarP(&chartPull.destination,
+		"destination",
+		"d", ".",
+		"The directory to save the chart archive.")
+
+	chartPullCmd.Flags().BoolVarP(&chartPull.prov,
+		"prov",
+		"", false,
+		"Download the provenance file too.")
+}
+
+func pullChart(ref string) error {
+	project, name, err := parseChartRef(ref)
+	if err != nil {
+		return err
+	}
+
+	index, err := fetchChartIndex(project)
+	if err != nil {
+		return err
+	}
+	cv, err := index.Get(name, chartPull.version)
+	if err != nil {
+		return err
+	}
+
+	archive, err := downloadChart(project, cv)
+	if err != nil {
+		return err
+	}
+
+	file := filepath.Join(chartPull.destination, path.Base(cv.URLs[0]))
+	if err := ioutil.WriteFile(file, archive, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Pulled %s-%s to %s.\n", cv.Name, cv.Version, file)
+
+	if chartPull.prov {
+		u, err := chartURL(project, cv.URLs[0]+".prov")
+		if err != nil {
+			return err
+		}
+		_, prov, err := utils.Send("GET", u, nil)
+		if err != nil {
+			return fmt.Errorf("provenance: %v", err)
+		}
+		if err := ioutil.WriteFile(file+".prov", prov, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Pulled the provenance file to %s.prov.\n", file)
+	}
+	return nil
+}
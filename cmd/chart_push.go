@@ -0,0 +1,186 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/moooofly/harborctl/utils/chart"
+	"github.com/spf13/cobra"
+)
+
+// chartPushCmd represents the push command
+var chartPushCmd = &cobra.Command{
+	Use:   "push CHART",
+	Short: "Package a chart directory and push it to a project.",
+	Long: `This command pushes CHART, a chart directory or a packaged .tgz archive, to the chart repository of the project. A directory is packaged like 'helm package', with the files matching .helmignore skipped.
+
+The provenance file is pushed together if given by '--prov', or generated with '--sign', which signs the chart by 'gpg --clearsign' with the key of '--key' (the default key of gpg if empty) like 'helm package --sign'.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := pushChart(args[0]); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var chartPush struct {
+	project string
+	prov    string
+	sign    bool
+	key     string
+	save    string
+}
+
+func init() {
+	helmChartCmd.AddCommand(chartPushCmd)
+
+	chartPushCmd.Flags().StringVarP(&chartPush.project,
+		"project",
+		"p", "",
+		"(REQUIRED) The name of the project to push to.")
+	chartPushCmd.MarkFlagRequired("project")
+
+	chartPushCmd.Flags().StringVarP(&chartPush.prov,
+		"prov",
+		"", "",
+		"The provenance file of the chart.")
+
+	chartPushCmd.Flags().BoolVarP(&chartPush.sign,
+		"sign",
+		"", false,
+		"Sign the chart by gpg and push the provenance file.")
+
+	chartPushCmd.Flags().StringVarP(&chartPush.key,
+		"key",
+		"k", "",
+		"The name of the gpg key to sign with.")
+
+	chartPushCmd.Flags().StringVarP(&chartPush.save,
+		"save",
+		"", "",
+		"Save the packaged archive (and the provenance file) to the directory too.")
+}
+
+// signChart signs the chart archive by gpg, and returns the provenance file.
+func signChart(m *chart.Metadata, archive []byte, key string) ([]byte, error) {
+	body, err := chart.ProvenanceBody(m, archive)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"--batch", "--yes", "--armor", "--digest-algo", "SHA512", "--clearsign"}
+	if key != "" {
+		args = append(args, "--local-user", key)
+	}
+
+	var stdout, stderr bytes.Buffer
+	c := exec.Command("gpg", args...)
+	c.Stdin = bytes.NewReader(body)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("gpg: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func pushChart(src string) error {
+	if chartPush.sign && chartPush.prov != "" {
+		return errors.New("--sign and --prov are mutually exclusive")
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	var m *chart.Metadata
+	var archive []byte
+	if info.IsDir() {
+		var b bytes.Buffer
+		if m, err = chart.Package(src, &b); err != nil {
+			return err
+		}
+		archive = b.Bytes()
+	} else {
+		if archive, err = ioutil.ReadFile(src); err != nil {
+			return err
+		}
+		a, err := chart.LoadArchive(archive)
+		if err != nil {
+			return err
+		}
+		m = a.Metadata
+	}
+
+	var prov []byte
+	switch {
+	case chartPush.sign:
+		if prov, err = signChart(m, archive, chartPush.key); err != nil {
+			return err
+		}
+	case chartPush.prov != "":
+		if prov, err = ioutil.ReadFile(chartPush.prov); err != nil {
+			return err
+		}
+	}
+
+	if chartPush.save != "" {
+		file := filepath.Join(chartPush.save, m.ArchiveName())
+		if err := ioutil.WriteFile(file, archive, 0644); err != nil {
+			return err
+		}
+		if prov != nil {
+			if err := ioutil.WriteFile(file+".prov", prov, 0644); err != nil {
+				return err
+			}
+		}
+	}
+
+	files := []utils.FormFile{{Field: "chart", Path: m.ArchiveName(), Data: archive}}
+	if prov != nil {
+		files = append(files, utils.FormFile{Field: "prov", Path: m.ArchiveName() + ".prov", Data: prov})
+	}
+
+	targetURL := utils.URLGen("/api/chartrepo/" + chartPush.project + "/charts")
+	if _, _, err := utils.SendMultipart(targetURL, files...); err != nil {
+		if utils.IsStatus(err, 409) {
+			return fmt.Errorf("chart %s-%s already exists in project %s", m.Name, m.Version, chartPush.project)
+		}
+		return err
+	}
+
+	fmt.Printf("Pushed %s (digest: %s) to project %s", m.ArchiveName(), chart.Digest(archive), chartPush.project)
+	if prov != nil {
+		fmt.Print(" with provenance")
+	}
+	fmt.Println(".")
+	return nil
+}
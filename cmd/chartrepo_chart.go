@@ -87,12 +87,12 @@ func initChartGet() {
 func getChartsInfo() {
 	var targetURL string
 	if chartGet.chartVersion != "" {
-		targetURL = chartrepoURL + "/" + chartGet.chartName +
-			"/charts/" + chartGet.projectName +
+		targetURL = chartrepoURL + "/" + chartGet.projectName +
+			"/charts/" + chartGet.chartName +
 			"/" + chartGet.chartVersion
 	} else {
-		targetURL = chartrepoURL + "/" + chartGet.chartName +
-			"/charts/" + chartGet.projectName
+		targetURL = chartrepoURL + "/" + chartGet.projectName +
+			"/charts/" + chartGet.chartName
 	}
 	utils.Get(targetURL)
 }
@@ -187,7 +187,11 @@ func initChartUpload() {
 
 func uploadChart() {
 	targetURL := chartrepoURL + "/" + chartUpload.projectName + "/charts"
-	utils.Multipart(targetURL, chartUpload.chartFile, chartUpload.provFile)
+	files := []utils.FormFile{{Field: "chart", Path: chartUpload.chartFile}}
+	if chartUpload.provFile != "" {
+		files = append(files, utils.FormFile{Field: "prov", Path: chartUpload.provFile})
+	}
+	utils.Multipart(targetURL, files...)
 }
 
 // listCmd represents the list command
@@ -89,8 +89,8 @@ func initChartLabelGet() {
 
 func getChartLabel() {
 	var targetURL string
-	targetURL = chartrepoURL + "/" + chartLabelGet.chartName +
-		"/charts/" + chartLabelGet.projectName +
+	targetURL = chartrepoURL + "/" + chartLabelGet.projectName +
+		"/charts/" + chartLabelGet.chartName +
 		"/" + chartLabelGet.chartVersion + "/labels"
 	utils.Get(targetURL)
 }
@@ -135,10 +135,10 @@ func initChartLabelDelete() {
 		"(REQUIRED) The chart version")
 	chartLabelDeleteCmd.MarkFlagRequired("chart_version")
 
-	chartLabelDeleteCmd.Flags().Int64VarP(&chartLabelDelete.ID,
+	chartLabelDeleteCmd.Flags().VarP(newIDValue(&chartLabelDelete.ID, "label"),
 		"label_id",
-		"i", 0,
-		"(REQUIRED) The label ID")
+		"i",
+		"(REQUIRED) The label ID or name")
 	chartLabelDeleteCmd.MarkFlagRequired("label_id")
 }
 
@@ -200,10 +200,10 @@ func initChartLabelAttach() {
 		"(REQUIRED) The chart version")
 	chartLabelAttachCmd.MarkFlagRequired("chart_version")
 
-	chartLabelAttachCmd.Flags().Int64VarP(&chartLabelAttach.Label.ID,
+	chartLabelAttachCmd.Flags().VarP(newIDValue(&chartLabelAttach.Label.ID, "label"),
 		"label_id",
-		"i", 0,
-		"(REQUIRED) The label id")
+		"i",
+		"(REQUIRED) The label ID or name")
 	chartLabelAttachCmd.MarkFlagRequired("label_id")
 }
 
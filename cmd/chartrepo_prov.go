@@ -77,5 +77,5 @@ func initProvUpload() {
 
 func uploadProv() {
 	targetURL := chartrepoURL + "/" + provUpload.projectName + "/prov"
-	utils.Multipart(targetURL, provUpload.provFile)
+	utils.Multipart(targetURL, utils.FormFile{Field: "prov", Path: provUpload.provFile})
 }
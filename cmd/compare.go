@@ -0,0 +1,301 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compare the tags of a project between two Harbor instances.",
+	Long: `This command lists the repositories and tags of the project on both the source and the destination instances, compares their manifest digests, and reports the tags which are missing on the destination, extra on the destination, or divergent.
+
+The instances are the contexts in the configuration file, e.g.
+
+contexts:
+  dev:
+    scheme: https
+    address: harbor-dev.example.com
+    username: admin
+    password_file: ~/.harbor/dev.password   # or password_env, prompted for if neither is given
+  prod:
+    address: harbor.example.com
+    username: admin
+    password_env: HARBOR_PROD_PASSWORD
+
+With '--replicate', the replication policies of the project on the source whose target is the destination are triggered if any tag is missing or divergent.
+
+It exits with status 1 if the project differs.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		same, err := compareInstances()
+		if err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+		if !same {
+			os.Exit(1)
+		}
+	},
+}
+
+var compareOpts struct {
+	source    string
+	dest      string
+	project   string
+	replicate bool
+	all       bool
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().StringVarP(&compareOpts.source,
+		"source",
+		"s", "",
+		"(REQUIRED) The context of the source instance.")
+	compareCmd.MarkFlagRequired("source")
+
+	compareCmd.Flags().StringVarP(&compareOpts.dest,
+		"dest",
+		"d", "",
+		"(REQUIRED) The context of the destination instance.")
+	compareCmd.MarkFlagRequired("dest")
+
+	compareCmd.Flags().StringVarP(&compareOpts.project,
+		"project",
+		"p", "",
+		"(REQUIRED) The name of the project to compare.")
+	compareCmd.MarkFlagRequired("project")
+
+	compareCmd.Flags().BoolVarP(&compareOpts.replicate,
+		"replicate",
+		"", false,
+		"Trigger the replication from the source to the destination for the missing or divergent tags.")
+
+	compareCmd.Flags().BoolVarP(&compareOpts.all,
+		"all",
+		"a", false,
+		"Show the identical tags too.")
+}
+
+// contextProjectID finds the ID of the project by its exact name in the context.
+func contextProjectID(ctx *utils.Context, name string) (int64, error) {
+	var projects []projectBrief
+	if err := ctx.GetStruct("/api/projects?name="+url.QueryEscape(name), &projects); err != nil {
+		return 0, err
+	}
+	for _, p := range projects {
+		if p.Name == name {
+			return p.ProjectID, nil
+		}
+	}
+	return 0, fmt.Errorf("no project named %q in context %s", name, ctx.Name)
+}
+
+// contextTagDigests returns the manifest digests of all the tags in the project,
+// by repository and tag.
+func contextTagDigests(ctx *utils.Context, projectID int64) (map[string]map[string]string, error) {
+	var repos []string
+	uri := "/api/repositories?project_id=" + strconv.FormatInt(projectID, 10)
+	err := utils.GetPages(uri, 100, func(pageURI string) (int, error) {
+		var page []struct {
+			Name string `json:"name"`
+		}
+		if err := ctx.GetStruct(pageURI, &page); err != nil {
+			return 0, err
+		}
+		for _, r := range page {
+			repos = append(repos, r.Name)
+		}
+		return len(page), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	digests := make(map[string]map[string]string)
+	for _, repo := range repos {
+		var tags []struct {
+			Name   string `json:"name"`
+			Digest string `json:"digest"`
+		}
+		if err := ctx.GetStruct("/api/repositories/"+repo+"/tags", &tags); err != nil {
+			return nil, err
+		}
+
+		digests[repo] = make(map[string]string)
+		for _, t := range tags {
+			digests[repo][t.Name] = t.Digest
+		}
+	}
+	return digests, nil
+}
+
+// tagDiff is a tag differing between the instances.
+type tagDiff struct {
+	repository string
+	tag        string
+	status     string
+	source     string
+	dest       string
+}
+
+func diffTagDigests(src, dst map[string]map[string]string) []tagDiff {
+	var diffs []tagDiff
+	for repo, tags := range src {
+		for tag, d := range tags {
+			switch dd, ok := dst[repo][tag]; {
+			case !ok:
+				diffs = append(diffs, tagDiff{repo, tag, "missing", d, ""})
+			case dd != d:
+				diffs = append(diffs, tagDiff{repo, tag, "divergent", d, dd})
+			default:
+				diffs = append(diffs, tagDiff{repo, tag, "identical", d, dd})
+			}
+		}
+	}
+	for repo, tags := range dst {
+		for tag, dd := range tags {
+			if _, ok := src[repo][tag]; !ok {
+				diffs = append(diffs, tagDiff{repo, tag, "extra", "", dd})
+			}
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].repository != diffs[j].repository {
+			return diffs[i].repository < diffs[j].repository
+		}
+		return diffs[i].tag < diffs[j].tag
+	})
+	return diffs
+}
+
+func compareInstances() (bool, error) {
+	if compareOpts.source == compareOpts.dest {
+		return false, errors.New("the source and the destination are the same context")
+	}
+
+	src, err := utils.LoadContext(compareOpts.source)
+	if err != nil {
+		return false, err
+	}
+	dst, err := utils.LoadContext(compareOpts.dest)
+	if err != nil {
+		return false, err
+	}
+
+	srcProjectID, err := contextProjectID(src, compareOpts.project)
+	if err != nil {
+		return false, err
+	}
+	dstProjectID, err := contextProjectID(dst, compareOpts.project)
+	if err != nil {
+		return false, err
+	}
+
+	srcDigests, err := contextTagDigests(src, srcProjectID)
+	if err != nil {
+		return false, err
+	}
+	dstDigests, err := contextTagDigests(dst, dstProjectID)
+	if err != nil {
+		return false, err
+	}
+
+	counts := make(map[string]int)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "REPOSITORY\tTAG\tSTATUS\tSOURCE (%s)\tDEST (%s)\n", src.Name, dst.Name)
+	for _, d := range diffTagDigests(srcDigests, dstDigests) {
+		counts[d.status]++
+		if d.status == "identical" && !compareOpts.all {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.repository, d.tag, d.status, shortDigest(d.source), shortDigest(d.dest))
+	}
+	w.Flush()
+
+	fmt.Printf("\nidentical: %d, missing: %d, extra: %d, divergent: %d\n",
+		counts["identical"], counts["missing"], counts["extra"], counts["divergent"])
+
+	if counts["missing"]+counts["divergent"] > 0 && compareOpts.replicate {
+		if err := replicateToContext(src, srcProjectID, dst); err != nil {
+			return false, err
+		}
+	}
+
+	return counts["missing"]+counts["extra"]+counts["divergent"] == 0, nil
+}
+
+// replicateToContext triggers the replication policies of the project in src
+// whose target is the instance of dst.
+func replicateToContext(src *utils.Context, projectID int64, dst *utils.Context) error {
+	var policies []policyRequest
+	if err := src.GetStruct("/api/policies/replication?project_id="+strconv.FormatInt(projectID, 10), &policies); err != nil {
+		return err
+	}
+
+	var triggered int
+	for _, p := range policies {
+		for _, t := range p.Targets {
+			var target targetBrief
+			if err := src.GetStruct("/api/targets/"+strconv.FormatInt(t.ID, 10), &target); err != nil {
+				return err
+			}
+			if !dst.HasEndpoint(target.Endpoint) {
+				continue
+			}
+
+			body := struct {
+				PolicyID int64 `json:"policy_id"`
+			}{p.ID}
+			if _, _, err := src.Send("POST", "/api/replications", &body); err != nil {
+				return err
+			}
+			fmt.Printf("Triggered replication policy %s (id: %d) to %s.\n", p.Name, p.ID, target.Endpoint)
+			triggered++
+		}
+	}
+
+	if triggered == 0 {
+		return fmt.Errorf("no replication policy of project %s in context %s replicates to %s", compareOpts.project, src.Name, dst.Address)
+	}
+	return nil
+}
+
+// shortDigest returns the first 12 hex digits of digest like 'docker images'.
+func shortDigest(digest string) string {
+	if len(digest) > 19 {
+		return digest[:19]
+	}
+	return digest
+}
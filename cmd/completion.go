@@ -21,29 +21,234 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // completionCmd represents the completion command
 var completionCmd = &cobra.Command{
-	Use:   "completion",
-	Short: "Generates bash completion scripts",
-	Long: `1. To load auto-completion, run
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generates shell completion scripts",
+	Long: `Generates the completion script of the shell, bash by default.
 
+Besides the commands and flags, the values of the flags and arguments are completed from the server, e.g. the project names and IDs, repositories, tags, labels, replication policies and targets, with the responses cached for a minute in conf/.cache.json.
+
+1. To load auto-completion, run
+
+    # bash
     . <(harborctl completion)
 
-2. To configure your bash shell to load auto-ompletions for each session, add to your bashrc
+    # fish
+    harborctl completion fish | source
+
+    # PowerShell
+    harborctl completion powershell | Out-String | Invoke-Expression
+
+2. To configure your shell to load auto-completions for each session, add to its profile
 
     # ~/.bashrc or ~/.profile
     . <(harborctl completion)
+
+    # ~/.config/fish/config.fish
+    harborctl completion fish | source
+
+    # $PROFILE
+    harborctl completion powershell | Out-String | Invoke-Expression
+
+   For zsh, save the script as '_harborctl' in a directory of $fpath, e.g.
+
+    harborctl completion zsh > "${fpath[1]}/_harborctl"
 `,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
 	Run: func(cmd *cobra.Command, args []string) {
-		rootCmd.GenBashCompletion(os.Stdout)
+		shell := "bash"
+		if len(args) > 0 {
+			shell = args[0]
+		}
+
+		var err error
+		switch shell {
+		case "bash":
+			markValueFlags(rootCmd)
+			rootCmd.BashCompletionFunction = nameScript(bashCompletionFunction)
+			err = rootCmd.GenBashCompletion(os.Stdout)
+		case "zsh":
+			err = genZshCompletion(os.Stdout)
+		case "fish", "powershell":
+			_, err = fmt.Print(nameScript(completionScripts[shell]))
+		default:
+			err = errors.New("shell must be one of [bash|zsh|fish|powershell]")
+		}
+		if err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(completionCmd)
 }
+
+// nameScript replaces the name in a completion script with the name of the
+// root command.
+func nameScript(script string) string {
+	return strings.Replace(script, "harborctl", rootCmd.Name(), -1)
+}
+
+// markValueFlags marks the flags completed by completers, so that the script
+// of GenBashCompletion completes them by __harborctl_values.
+func markValueFlags(cmd *cobra.Command) {
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if flagResource(cmd, f.Name) != "" {
+			cmd.Flags().SetAnnotation(f.Name, cobra.BashCompCustom, []string{nameScript("__harborctl_values")})
+		}
+	})
+	for _, c := range cmd.Commands() {
+		markValueFlags(c)
+	}
+}
+
+// bashCompletionFunction is the custom functions of the script of
+// GenBashCompletion, which completes the values of the flags marked by
+// markValueFlags, and the arguments by __custom_func, by the candidates of
+// 'harborctl __complete', see completeArgs.
+const bashCompletionFunction = `__harborctl_values()
+{
+    local -a args=("${words[@]:1:$((cword-1))}")
+    # NOTE: the value of '--flag=value' is completed as cur, after the flag.
+    if [[ "${words[cword]}" == -*"=$cur" ]]; then
+        args+=("${words[cword]%%=*}")
+    fi
+
+    local out
+    out=$(harborctl __complete "${args[@]}" "$cur" 2>/dev/null) || return
+    local directive="${out##*:}"
+    out="${out%:*}"
+
+    local c
+    COMPREPLY=()
+    while IFS= read -r c; do
+        c="${c%%$'\t'*}"
+        [[ -n "$c" && "$c" == "$cur"* ]] && COMPREPLY+=("$c")
+    done <<< "$out"
+
+    if (( directive & 2 )) && [[ $(type -t compopt) = "builtin" ]]; then
+        compopt -o nospace
+    fi
+    if declare -F __ltrim_colon_completions >/dev/null; then
+        __ltrim_colon_completions "$cur"
+    fi
+}
+
+__custom_func()
+{
+    __harborctl_values
+}
+`
+
+// genZshCompletion writes the script of GenZshCompletion, whose files
+// completed are completed by __harborctl_values instead, which completes the
+// flags and the values of the flags and arguments by 'harborctl __complete'.
+func genZshCompletion(w io.Writer) error {
+	var buf bytes.Buffer
+	if err := rootCmd.GenZshCompletion(&buf); err != nil {
+		return err
+	}
+
+	script := strings.Replace(buf.String(), " :_files'", " :"+nameScript("__harborctl_values")+"'", -1)
+	i := strings.Index(script, "\n") + 1
+	_, err := fmt.Fprint(w, script[:i]+"\n"+nameScript(zshCompletionFunction)+script[i:])
+	return err
+}
+
+// zshCompletionFunction is the function completing the words other than the
+// commands in the script of GenZshCompletion.
+const zshCompletionFunction = `__harborctl_values() {
+    local -a lines comps
+    local out directive line
+    out=$(harborctl __complete "${words[@]:1:$((CURRENT-1))}" 2>/dev/null) || return
+    lines=("${(@f)out}")
+    directive="${lines[-1]#:}"
+    for line in "${(@)lines[1,-2]}"; do
+        [[ -z "$line" ]] && continue
+        # NOTE: ':' separates the value from the description for _describe.
+        comps+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+    done
+
+    if (( ${#comps} == 0 )); then
+        (( directive & 4 )) || _files
+        return
+    fi
+    if (( directive & 2 )); then
+        _describe -t values 'values' comps -S ''
+    else
+        _describe -t values 'values' comps
+    fi
+}
+`
+
+// completionScripts are the completion scripts of the shells not supported by
+// cobra, which complete by the candidates of 'harborctl __complete', see
+// completeArgs.
+var completionScripts = map[string]string{
+	"fish": `# fish completion for harborctl
+function __harborctl_complete
+    set -l args (commandline -opc) (commandline -ct)
+    set -e args[1]
+    set -l out (harborctl __complete $args 2>/dev/null)
+    or return
+    set -l directive (string replace -r '^:' '' -- $out[-1])
+    set -e out[-1]
+
+    if test (count $out) -eq 0
+        if test (math "floor($directive / 4) % 2") -eq 0
+            __fish_complete_path (commandline -ct)
+        end
+        return
+    end
+    printf '%s\n' $out
+end
+complete -c harborctl -f -a '(__harborctl_complete)'
+`,
+	"powershell": `# PowerShell completion for harborctl
+Register-ArgumentCompleter -Native -CommandName 'harborctl' -ScriptBlock {
+    param($WordToComplete, $CommandAst, $CursorPosition)
+
+    $Command = $CommandAst.Extent.ToString()
+    if ($Command.Length -gt $CursorPosition) {
+        $Command = $Command.Substring(0, $CursorPosition)
+    }
+    $Program, $Arguments = $Command.Split(" ", 2)
+    $RequestComp = "$Program __complete $Arguments"
+    if ($WordToComplete -eq "") {
+        # NOTE: an empty argument is dropped unless quoted like this.
+        $RequestComp = "$RequestComp" + ' ` + "`\"`\"" + `'
+    }
+
+    $Out = @(Invoke-Expression -Command "$RequestComp" 2>$null)
+    if ($Out.Count -eq 0) { return }
+    $Directive = [int]($Out[-1].TrimStart(':'))
+    $Out = @($Out | Select-Object -SkipLast 1)
+
+    if ($Out.Count -eq 0) {
+        # NOTE: nothing returned falls back to completing the file names.
+        if ($Directive -band 4) { "" }
+        return
+    }
+    $Out | Where-Object { $_ -like "$WordToComplete*" } | ForEach-Object {
+        $Value, $Description = $_.Split("` + "`t" + `", 2)
+        if (-not $Description) { $Description = $Value }
+        [System.Management.Automation.CompletionResult]::new($Value, $Value, 'ParameterValue', $Description)
+    }
+}
+`,
+}
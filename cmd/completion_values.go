@@ -0,0 +1,528 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// The directives of the completion, printed as ':N' after the candidates.
+const (
+	compDirectiveError = 1 << iota
+	compDirectiveNoSpace
+	compDirectiveNoFile
+)
+
+// completionCacheTTL is how long the responses for the completion are cached.
+const completionCacheTTL = time.Minute
+
+// completeCmd represents the __complete command, which is called by the
+// completion scripts.
+var completeCmd = &cobra.Command{
+	Use:                "__complete [ARG...] WORD",
+	Short:              "Print the candidates completing the last argument, for the completion scripts.",
+	Hidden:             true,
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		candidates, directive := completeArgs(args)
+		for _, c := range candidates {
+			fmt.Println(c)
+		}
+		fmt.Printf(":%d\n", directive)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(completeCmd)
+}
+
+// flagResources are the resources completing the values of the flags by name,
+// see completers.
+var flagResources = map[string]string{
+	"project_id":          "project_id",
+	"project_name":        "project",
+	"project":             "project",
+	"source_project_name": "project",
+	"from_project":        "project",
+	"to_project":          "project",
+	"repo_name":           "repository",
+	"repository":          "repository",
+	"tag":                 "tag",
+	"label_id":            "label_id",
+	"label":               "label",
+	"from":                "label",
+	"to":                  "label",
+	"policy_id":           "policy_id",
+	"policy":              "policy_id",
+	"target_id":           "target_id",
+	"robot_id":            "robot_id",
+	"webhook_id":          "webhook_id",
+	"events":              "webhook_event",
+	"source":              "context",
+	"dest":                "context",
+	"from_context":        "context",
+	"to_context":          "context",
+}
+
+// idResources are the resources of the flag 'id' by the command path prefix,
+// the first match wins.
+var idResources = []struct {
+	path     string
+	resource string
+}{
+	{"harborctl repository label", "label_id"},
+	{"harborctl repository tag label", "label_id"},
+	{"harborctl label", "label_id"},
+	{"harborctl registry", "target_id"},
+	{"harborctl replication policy", "policy_id"},
+}
+
+// argResources are the resources completing the arguments by the names in
+// the usage line, e.g. 'IMAGE' of 'verify IMAGE...'.
+var argResources = map[string]string{
+	"IMAGE":          "image",
+	"PROJECT":        "project",
+	"PROJECT/NAME":   "chart",
+	"PROJECT[/NAME]": "chart",
+}
+
+// completer returns the candidates of the resource completing toComplete,
+// each of format 'VALUE' or 'VALUE\tDESCRIPTION', with the directive.
+type completer func(cmd *cobra.Command, toComplete string) ([]string, int)
+
+var completers = map[string]completer{
+	"project":       completeProjects,
+	"project_id":    completeProjectIDs,
+	"repository":    completeRepositories,
+	"tag":           completeTags,
+	"image":         completeImages,
+	"chart":         completeCharts,
+	"label":         completeLabels,
+	"label_id":      completeLabelIDs,
+	"policy_id":     completePolicyIDs,
+	"target_id":     completeTargetIDs,
+	"robot_id":      completeRobotIDs,
+	"webhook_id":    completeWebhookIDs,
+	"webhook_event": completeWebhookEvents,
+	"context":       completeContexts,
+}
+
+// completeArgs returns the candidates completing the last of args, which are
+// the arguments after 'harborctl' on the command line, with the directive.
+func completeArgs(args []string) ([]string, int) {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	toComplete := args[len(args)-1]
+	args = args[:len(args)-1]
+
+	cmd, rest, _ := rootCmd.Find(args)
+	var flag *pflag.Flag
+	if n := len(rest); n > 0 && strings.HasPrefix(rest[n-1], "-") && !strings.Contains(rest[n-1], "=") {
+		if f := lookupFlag(cmd, rest[n-1]); f != nil && f.NoOptDefVal == "" {
+			flag, rest = f, rest[:n-1]
+		}
+	}
+
+	// NOTE: the flags given so far narrow the candidates, e.g. the tags of '--repo_name'.
+	cmd.FParseErrWhitelist.UnknownFlags = true
+	cmd.ParseFlags(rest)
+
+	switch {
+	case flag != nil:
+		return completeResource(cmd, flagResource(cmd, flag.Name), toComplete)
+	case strings.HasPrefix(toComplete, "-") && strings.Contains(toComplete, "="):
+		i := strings.Index(toComplete, "=")
+		f := lookupFlag(cmd, toComplete[:i])
+		if f == nil {
+			return nil, compDirectiveNoFile
+		}
+		candidates, directive := completeResource(cmd, flagResource(cmd, f.Name), toComplete[i+1:])
+		for j := range candidates {
+			candidates[j] = toComplete[:i+1] + candidates[j]
+		}
+		return candidates, directive
+	case strings.HasPrefix(toComplete, "-"):
+		return completeFlags(cmd, toComplete), compDirectiveNoFile
+	}
+
+	var candidates []string
+	for _, c := range cmd.Commands() {
+		if c.IsAvailableCommand() && strings.HasPrefix(c.Name(), toComplete) {
+			candidates = append(candidates, c.Name()+"\t"+c.Short)
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates, compDirectiveNoFile
+	}
+
+	for _, arg := range cmd.ValidArgs {
+		if strings.HasPrefix(arg, toComplete) {
+			candidates = append(candidates, arg)
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates, compDirectiveNoFile
+	}
+	return completeResource(cmd, argResource(cmd), toComplete)
+}
+
+// lookupFlag looks up the flag of cmd by '--name' or '-shorthand'.
+func lookupFlag(cmd *cobra.Command, s string) *pflag.Flag {
+	if strings.HasPrefix(s, "--") {
+		return cmd.Flags().Lookup(s[2:])
+	}
+	if len(s) == 2 {
+		return cmd.Flags().ShorthandLookup(s[1:])
+	}
+	return nil
+}
+
+func flagResource(cmd *cobra.Command, name string) string {
+	if name != "id" {
+		return flagResources[name]
+	}
+
+	path := strings.Replace(cmd.CommandPath(), rootCmd.Name(), "harborctl", 1)
+	for _, r := range idResources {
+		if strings.HasPrefix(path, r.path) {
+			return r.resource
+		}
+	}
+	return ""
+}
+
+func argResource(cmd *cobra.Command) string {
+	fields := strings.Fields(cmd.Use)
+	if len(fields) < 2 {
+		return ""
+	}
+	arg := strings.TrimSuffix(strings.Trim(fields[1], "[]"), "...")
+	if fields[1] == "PROJECT[/NAME]" {
+		arg = fields[1]
+	}
+
+	// NOTE: an argument not repeatable is completed only once.
+	if !strings.HasSuffix(fields[1], "...") && len(cmd.Flags().Args()) > 0 {
+		return "-"
+	}
+	return argResources[arg]
+}
+
+func completeFlags(cmd *cobra.Command, toComplete string) []string {
+	var candidates []string
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if !f.Hidden && strings.HasPrefix("--"+f.Name, toComplete) {
+			candidates = append(candidates, "--"+f.Name+"\t"+f.Usage)
+		}
+	})
+	return candidates
+}
+
+// completeResource completes by the completer of the resource, or by the file
+// names if no completer.
+func completeResource(cmd *cobra.Command, resource, toComplete string) ([]string, int) {
+	if resource == "-" {
+		return nil, compDirectiveNoFile
+	}
+	c, ok := completers[resource]
+	if !ok {
+		return nil, 0
+	}
+
+	candidates, directive := c(cmd, toComplete)
+	sort.Strings(candidates)
+	return candidates, directive | compDirectiveNoFile
+}
+
+// flagValue returns the value of the flag of cmd given on the command line,
+// or empty.
+func flagValue(cmd *cobra.Command, names ...string) string {
+	for _, name := range names {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+			return f.Value.String()
+		}
+	}
+	return ""
+}
+
+func getCached(uri string, st interface{}) error {
+	return utils.GetStructCached(utils.URLGen(uri), completionCacheTTL, st)
+}
+
+func cachedProjects() []projectBrief {
+	var projects []projectBrief
+	getCached("/api/projects?page_size=100", &projects)
+	return projects
+}
+
+func completeProjects(cmd *cobra.Command, toComplete string) ([]string, int) {
+	var candidates []string
+	for _, p := range cachedProjects() {
+		candidates = append(candidates, p.Name+"\tproject "+strconv.FormatInt(p.ProjectID, 10))
+	}
+	return candidates, 0
+}
+
+func completeProjectIDs(cmd *cobra.Command, toComplete string) ([]string, int) {
+	var candidates []string
+	for _, p := range cachedProjects() {
+		candidates = append(candidates, strconv.FormatInt(p.ProjectID, 10)+"\t"+p.Name)
+	}
+	return candidates, 0
+}
+
+// completeRepositories completes the projects followed by '/', and then the
+// repositories of the project.
+func completeRepositories(cmd *cobra.Command, toComplete string) ([]string, int) {
+	projects := cachedProjects()
+
+	i := strings.Index(toComplete, "/")
+	if i < 0 {
+		var candidates []string
+		for _, p := range projects {
+			candidates = append(candidates, p.Name+"/")
+		}
+		return candidates, compDirectiveNoSpace
+	}
+
+	var candidates []string
+	for _, p := range projects {
+		if p.Name != toComplete[:i] {
+			continue
+		}
+
+		var repos []struct {
+			Name      string `json:"name"`
+			TagsCount int64  `json:"tags_count"`
+		}
+		getCached("/api/repositories?page_size=100&project_id="+strconv.FormatInt(p.ProjectID, 10), &repos)
+		for _, r := range repos {
+			candidates = append(candidates, r.Name+"\t"+strconv.FormatInt(r.TagsCount, 10)+" tags")
+		}
+	}
+	return candidates, 0
+}
+
+func repoTags(repo string) []string {
+	var tags []tagDetail
+	getCached("/api/repositories/"+repo+"/tags", &tags)
+
+	var candidates []string
+	for _, t := range tags {
+		candidates = append(candidates, t.Name+"\t"+shortDigest(t.Digest))
+	}
+	return candidates
+}
+
+func completeTags(cmd *cobra.Command, toComplete string) ([]string, int) {
+	repo := flagValue(cmd, "repo_name", "repository")
+	if repo == "" {
+		return nil, 0
+	}
+	return repoTags(repo), 0
+}
+
+// completeImages completes the repositories, and then the tags after ':'.
+func completeImages(cmd *cobra.Command, toComplete string) ([]string, int) {
+	i := strings.LastIndex(toComplete, ":")
+	if i < 0 {
+		candidates, directive := completeRepositories(cmd, toComplete)
+		if directive&compDirectiveNoSpace == 0 {
+			// NOTE: the tag is optional, so the repositories are completed with and without ':'.
+			for _, c := range candidates {
+				candidates = append(candidates, strings.SplitN(c, "\t", 2)[0]+":")
+			}
+		}
+		return candidates, directive | compDirectiveNoSpace
+	}
+
+	var candidates []string
+	for _, t := range repoTags(toComplete[:i]) {
+		candidates = append(candidates, toComplete[:i+1]+t)
+	}
+	return candidates, 0
+}
+
+// completeCharts completes the projects followed by '/', and then the charts
+// of the project.
+func completeCharts(cmd *cobra.Command, toComplete string) ([]string, int) {
+	i := strings.Index(toComplete, "/")
+	if i < 0 {
+		var candidates []string
+		for _, p := range cachedProjects() {
+			candidates = append(candidates, p.Name+"/")
+		}
+		return candidates, compDirectiveNoSpace
+	}
+
+	var charts []struct {
+		Name          string `json:"name"`
+		LatestVersion string `json:"latest_version"`
+	}
+	getCached("/api/chartrepo/"+toComplete[:i]+"/charts", &charts)
+
+	var candidates []string
+	for _, c := range charts {
+		candidates = append(candidates, toComplete[:i+1]+c.Name+"\t"+c.LatestVersion)
+	}
+	return candidates, 0
+}
+
+// flagProjectID returns the ID of the project given on the command line, by
+// ID or name, or "" if none.
+func flagProjectID(cmd *cobra.Command) string {
+	// NOTE: '--project_id' may be given a name too, see idValue.
+	id := flagValue(cmd, "project_id")
+	name := flagValue(cmd, "project", "project_name")
+	if strings.HasPrefix(id, idNamePrefix) {
+		id, name = "", strings.TrimPrefix(id, idNamePrefix)
+	} else if _, err := strconv.ParseInt(id, 10, 64); id != "" && err != nil {
+		id, name = "", id
+	}
+	if name != "" {
+		for _, p := range cachedProjects() {
+			if p.Name == name {
+				id = strconv.FormatInt(p.ProjectID, 10)
+			}
+		}
+	}
+	if id == "0" {
+		return ""
+	}
+	return id
+}
+
+// cachedLabels lists the global labels, and the labels of the project of
+// '--project_id' or '--project' if given.
+func cachedLabels(cmd *cobra.Command) []labelBrief {
+	var labels, projectLabels []labelBrief
+	getCached("/api/labels?scope=g&page_size=100", &labels)
+
+	if id := flagProjectID(cmd); id != "" {
+		getCached("/api/labels?scope=p&page_size=100&project_id="+id, &projectLabels)
+	}
+	return append(labels, projectLabels...)
+}
+
+func labelScopeName(l *labelBrief) string {
+	if l.Scope == "p" {
+		return "project label"
+	}
+	return "global label"
+}
+
+func completeLabels(cmd *cobra.Command, toComplete string) ([]string, int) {
+	var candidates []string
+	for _, l := range cachedLabels(cmd) {
+		candidates = append(candidates, l.Name+"\t"+labelScopeName(&l))
+	}
+	return candidates, 0
+}
+
+func completeLabelIDs(cmd *cobra.Command, toComplete string) ([]string, int) {
+	var candidates []string
+	for _, l := range cachedLabels(cmd) {
+		candidates = append(candidates, strconv.FormatInt(l.ID, 10)+"\t"+l.Name+" ("+labelScopeName(&l)+")")
+	}
+	return candidates, 0
+}
+
+func completePolicyIDs(cmd *cobra.Command, toComplete string) ([]string, int) {
+	var policies []struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	getCached("/api/policies/replication", &policies)
+
+	var candidates []string
+	for _, p := range policies {
+		candidates = append(candidates, strconv.FormatInt(p.ID, 10)+"\t"+p.Name)
+	}
+	return candidates, 0
+}
+
+func completeTargetIDs(cmd *cobra.Command, toComplete string) ([]string, int) {
+	var targets []targetBrief
+	getCached("/api/targets", &targets)
+
+	var candidates []string
+	for _, t := range targets {
+		candidates = append(candidates, strconv.FormatInt(t.ID, 10)+"\t"+t.Name+" ("+t.Endpoint+")")
+	}
+	return candidates, 0
+}
+
+func completeRobotIDs(cmd *cobra.Command, toComplete string) ([]string, int) {
+	id := flagProjectID(cmd)
+	if id == "" {
+		return nil, 0
+	}
+
+	var robots []robotBrief
+	getCached("/api/projects/"+id+"/robots?page_size=100", &robots)
+
+	var candidates []string
+	for _, r := range robots {
+		candidates = append(candidates, strconv.FormatInt(r.ID, 10)+"\t"+r.Name)
+	}
+	return candidates, 0
+}
+
+func completeWebhookIDs(cmd *cobra.Command, toComplete string) ([]string, int) {
+	id := flagProjectID(cmd)
+	if id == "" {
+		return nil, 0
+	}
+
+	var policies []webhookPolicy
+	getCached("/api/projects/"+id+"/webhook/policies?page_size=100", &policies)
+
+	var candidates []string
+	for _, p := range policies {
+		candidates = append(candidates, strconv.FormatInt(p.ID, 10)+"\t"+p.Name)
+	}
+	return candidates, 0
+}
+
+// completeWebhookEvents completes the last of the event types separated by
+// commas.
+func completeWebhookEvents(cmd *cobra.Command, toComplete string) ([]string, int) {
+	prefix := toComplete[:strings.LastIndex(toComplete, ",")+1]
+
+	var candidates []string
+	for _, name := range strings.Split(webhookEventNames(), "|") {
+		candidates = append(candidates, prefix+name)
+	}
+	return candidates, compDirectiveNoSpace
+}
+
+func completeContexts(cmd *cobra.Command, toComplete string) ([]string, int) {
+	return utils.ContextNames(), 0
+}
@@ -0,0 +1,95 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/moooofly/harborctl/utils/registry"
+	"github.com/spf13/cobra"
+)
+
+// imageCmd represents the image command
+var imageCmd = &cobra.Command{
+	Use:   "image",
+	Short: "Registry '/v2' API.",
+	Long: `The subcommand of registry '/v2' hierarchy, which pulls and pushes images through the token service of Harbor without Docker daemon.
+
+NOTE: IMAGE is of format '[host/]project/repo[:tag][@digest]', and the host, if given, must match the current address.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Use \"harborctl image --help\" for more information about this command.")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(imageCmd)
+}
+
+// registryClient returns a client of the registry behind current Harbor. The
+// password is read from terminal if username is given without one.
+func registryClient(username, password string) (*registry.Client, error) {
+	if username != "" && password == "" {
+		passwd, err := utils.ReadPasswordFromTerm()
+		if err != nil {
+			return nil, err
+		}
+		if passwd == "" {
+			return nil, errors.New("Password Required")
+		}
+		password = passwd
+	}
+
+	return registry.NewClient(utils.URLGen(""), username, password), nil
+}
+
+// imageFormat returns the format of image storage, 'docker' for a
+// tarball of 'docker save' and 'oci' for an OCI image layout directory.
+func imageFormat(format string, isDir bool) (string, error) {
+	switch format {
+	case "oci", "docker":
+		return format, nil
+	case "":
+		if isDir {
+			return "oci", nil
+		}
+		return "docker", nil
+	default:
+		return "", fmt.Errorf("format must be one of [oci|docker], got %q", format)
+	}
+}
+
+// humanSize formats size in bytes by binary units, e.g. '1.5 MiB'.
+func humanSize(size int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+
+	f := float64(size)
+	i := 0
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.2f %s", f, units[i])
+}
@@ -0,0 +1,330 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/moooofly/harborctl/utils/registry"
+	"github.com/spf13/cobra"
+)
+
+// imageInspectCmd represents the inspect command
+var imageInspectCmd = &cobra.Command{
+	Use:   "inspect IMAGE",
+	Short: "Inspect the manifest and configuration of an image.",
+	Long: `This command decodes the manifest (docker schema1, schema2, OCI manifest or manifest list) of an image, and shows its layers with sizes and digests, the total compressed size, the platform and the configuration, including env, entrypoint, labels and history.
+
+NOTE:
+- For a manifest list (multi-arch image), the manifests in it are listed, and the one of '--platform' is inspected.
+- With '--diff', the layers of IMAGE and the given image are compared instead, e.g. to find out how much a new tag adds on top of an old one.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := inspectImage(args[0]); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var imageInspect struct {
+	platform string
+	diff     string
+	raw      bool
+	username string
+	password string
+}
+
+func init() {
+	imageCmd.AddCommand(imageInspectCmd)
+
+	imageInspectCmd.Flags().StringVarP(&imageInspect.platform,
+		"platform",
+		"", "linux/amd64",
+		"The platform to inspect for a manifest list, of format 'os/arch[/variant]'.")
+
+	imageInspectCmd.Flags().StringVarP(&imageInspect.diff,
+		"diff",
+		"d", "",
+		"Another image to compare layers with.")
+
+	imageInspectCmd.Flags().BoolVarP(&imageInspect.raw,
+		"raw",
+		"", false,
+		"Print the raw manifest only.")
+
+	imageInspectCmd.Flags().StringVarP(&imageInspect.username,
+		"username",
+		"u", "",
+		"The username to access the registry. (anonymous if not set)")
+
+	imageInspectCmd.Flags().StringVarP(&imageInspect.password,
+		"password",
+		"p", "",
+		"The password to access the registry. (read from terminal if not set)")
+}
+
+func inspectImage(image string) error {
+	platform, err := registry.ParsePlatform(imageInspect.platform)
+	if err != nil {
+		return err
+	}
+
+	c, err := registryClient(imageInspect.username, imageInspect.password)
+	if err != nil {
+		return err
+	}
+
+	if imageInspect.diff != "" {
+		a, err := loadImage(c, image, platform)
+		if err != nil {
+			return err
+		}
+		b, err := loadImage(c, imageInspect.diff, platform)
+		if err != nil {
+			return err
+		}
+		printLayerDiff(image, a, imageInspect.diff, b)
+		return nil
+	}
+
+	if imageInspect.raw {
+		ref, err := parseImage(image)
+		if err != nil {
+			return err
+		}
+		body, _, _, err := c.GetManifest(ref.Repository, imageReference(ref))
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(body))
+		return nil
+	}
+
+	img, err := loadImage(c, image, platform)
+	if err != nil {
+		return err
+	}
+	printImage(image, img)
+	return nil
+}
+
+// loadImage fetches and decodes the image. For a manifest list, the manifests
+// in it are listed, and the one matching platform is loaded.
+func loadImage(c *registry.Client, image string, platform *registry.Platform) (*registry.Image, error) {
+	ref, err := parseImage(image)
+	if err != nil {
+		return nil, err
+	}
+
+	reference := imageReference(ref)
+	fmt.Println("==> GET manifest", ref.Repository+":"+reference)
+	body, mediaType, digest, err := c.GetManifest(ref.Repository, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	if registry.IsIndex(mediaType) {
+		var idx registry.Index
+		if err := json.Unmarshal(body, &idx); err != nil {
+			return nil, err
+		}
+
+		fmt.Println("Manifest List:", digest)
+		fmt.Println("Media Type:   ", mediaType)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  PLATFORM\tDIGEST\tSIZE")
+		for _, m := range idx.Manifests {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", m.Platform, m.Digest, humanSize(m.Size))
+		}
+		w.Flush()
+		fmt.Println()
+
+		var found bool
+		for _, m := range idx.Manifests {
+			if platform.Match(m) {
+				fmt.Println("==> GET manifest", ref.Repository+"@"+m.Digest, "for", platform)
+				body, mediaType, digest, err = c.GetManifest(ref.Repository, m.Digest)
+				if err != nil {
+					return nil, err
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no manifest for platform %s in %s", platform, image)
+		}
+	}
+
+	return c.LoadImage(ref.Repository, body, mediaType, digest)
+}
+
+func printImage(image string, img *registry.Image) {
+	cfg := img.Config
+
+	fmt.Println("Image:      ", image)
+	fmt.Println("Digest:     ", img.Digest)
+	fmt.Println("Media Type: ", img.MediaType)
+	fmt.Println("Platform:   ", img.Platform())
+	fmt.Println("Created:    ", cfg.Created)
+	if cfg.Author != "" {
+		fmt.Println("Author:     ", cfg.Author)
+	}
+	fmt.Println("Entrypoint: ", formatArgs(cfg.Config.Entrypoint))
+	fmt.Println("Cmd:        ", formatArgs(cfg.Config.Cmd))
+	if cfg.Config.WorkingDir != "" {
+		fmt.Println("WorkingDir: ", cfg.Config.WorkingDir)
+	}
+	if cfg.Config.User != "" {
+		fmt.Println("User:       ", cfg.Config.User)
+	}
+	if len(cfg.Config.ExposedPorts) > 0 {
+		fmt.Println("Ports:      ", strings.Join(sortedKeys(cfg.Config.ExposedPorts), ", "))
+	}
+	if len(cfg.Config.Volumes) > 0 {
+		fmt.Println("Volumes:    ", strings.Join(sortedKeys(cfg.Config.Volumes), ", "))
+	}
+
+	if len(cfg.Config.Env) > 0 {
+		fmt.Println("Env:")
+		for _, e := range cfg.Config.Env {
+			fmt.Println("  " + e)
+		}
+	}
+
+	if len(cfg.Config.Labels) > 0 {
+		keys := make([]string, 0, len(cfg.Config.Labels))
+		for k := range cfg.Config.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fmt.Println("Labels:")
+		for _, k := range keys {
+			fmt.Printf("  %s=%s\n", k, cfg.Config.Labels[k])
+		}
+	}
+
+	fmt.Printf("Layers: %d, total compressed size: %s (%d bytes)\n", len(img.Layers), humanSize(img.Size()), img.Size())
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  #\tDIGEST\tSIZE\tCREATED BY")
+	for i, h := range img.LayerHistory() {
+		l := img.Layers[i]
+		fmt.Fprintf(w, "  %d\t%s\t%s\t%s\n", i+1, l.Digest, humanSize(l.Size), truncate(h.CreatedBy, 60))
+	}
+	w.Flush()
+
+	if len(cfg.History) > 0 {
+		fmt.Println("History:")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  CREATED\tLAYER\tCREATED BY")
+		for _, h := range cfg.History {
+			layer := "yes"
+			if h.EmptyLayer {
+				layer = "no"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", h.Created, layer, h.CreatedBy)
+		}
+		w.Flush()
+	}
+}
+
+// printLayerDiff compares the layers of image a and b by digest.
+func printLayerDiff(nameA string, a *registry.Image, nameB string, b *registry.Image) {
+	inA := make(map[string]bool)
+	for _, l := range a.Layers {
+		inA[l.Digest] = true
+	}
+	inB := make(map[string]bool)
+	for _, l := range b.Layers {
+		inB[l.Digest] = true
+	}
+
+	fmt.Println("---", nameA, a.Digest)
+	fmt.Println("+++", nameB, b.Digest)
+
+	var shared, onlyA, onlyB int64
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, l := range a.Layers {
+		if inB[l.Digest] {
+			fmt.Fprintf(w, " \t%s\t%s\n", l.Digest, humanSize(l.Size))
+			shared += l.Size
+		} else {
+			fmt.Fprintf(w, "-\t%s\t%s\n", l.Digest, humanSize(l.Size))
+			onlyA += l.Size
+		}
+	}
+	for _, l := range b.Layers {
+		if !inA[l.Digest] {
+			fmt.Fprintf(w, "+\t%s\t%s\n", l.Digest, humanSize(l.Size))
+			onlyB += l.Size
+		}
+	}
+	w.Flush()
+
+	fmt.Println("Shared:      ", humanSize(shared))
+	fmt.Println("Only in", nameA+":", humanSize(onlyA))
+	fmt.Println("Only in", nameB+":", humanSize(onlyB))
+}
+
+// imageReference returns the digest of ref if any, otherwise its tag, which
+// defaults to 'latest'.
+func imageReference(ref *utils.Reference) string {
+	switch {
+	case ref.Digest != "":
+		return ref.Digest
+	case ref.Tag != "":
+		return ref.Tag
+	default:
+		return "latest"
+	}
+}
+
+func formatArgs(args []string) string {
+	if args == nil {
+		return ""
+	}
+	b, _ := json.Marshal(args)
+	return string(b)
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
@@ -0,0 +1,256 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/moooofly/harborctl/utils/registry"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// imagePullCmd represents the pull command
+var imagePullCmd = &cobra.Command{
+	Use:   "pull IMAGE",
+	Short: "Pull an image into an OCI image layout or a 'docker save' tarball.",
+	Long: `This command pulls an image through the registry v2 API, and saves it into an OCI image layout directory or a tarball which can be loaded by 'docker load'.
+
+NOTE:
+- If '--format' is not set, the output ending with '.tar' is taken as a tarball, otherwise as an OCI image layout directory.
+- Pulling into an existing OCI image layout adds the image to it, and the blobs already in it are not pulled again.
+- For a manifest list (multi-arch image), only the manifest of '--platform' is pulled.
+- Docker schema1 manifests are not supported.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := pullImage(args[0]); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var imagePull struct {
+	output   string
+	format   string
+	platform string
+	username string
+	password string
+}
+
+func init() {
+	imageCmd.AddCommand(imagePullCmd)
+
+	imagePullCmd.Flags().StringVarP(&imagePull.output,
+		"output",
+		"o", "",
+		"(REQUIRED) The OCI image layout directory or the tarball to save the image into.")
+	imagePullCmd.MarkFlagRequired("output")
+
+	imagePullCmd.Flags().StringVarP(&imagePull.format,
+		"format",
+		"f", "",
+		"The format of output, valid values are 'oci' and 'docker'.")
+
+	imagePullCmd.Flags().StringVarP(&imagePull.platform,
+		"platform",
+		"", "linux/amd64",
+		"The platform to pull for a manifest list, of format 'os/arch[/variant]'.")
+
+	imagePullCmd.Flags().StringVarP(&imagePull.username,
+		"username",
+		"u", "",
+		"The username to access the registry. (anonymous if not set)")
+
+	imagePullCmd.Flags().StringVarP(&imagePull.password,
+		"password",
+		"p", "",
+		"The password to access the registry. (read from terminal if not set)")
+}
+
+func pullImage(image string) error {
+	ref, err := parseImage(image)
+	if err != nil {
+		return err
+	}
+
+	format, err := imageFormat(imagePull.format, !strings.HasSuffix(imagePull.output, ".tar"))
+	if err != nil {
+		return err
+	}
+
+	c, err := registryClient(imagePull.username, imagePull.password)
+	if err != nil {
+		return err
+	}
+
+	tag := ref.Tag
+	reference := ref.Digest
+	if reference == "" {
+		if tag == "" {
+			tag = "latest"
+		}
+		reference = tag
+	}
+
+	fmt.Println("==> GET manifest", ref.Repository+":"+reference)
+	body, mediaType, digest, err := c.GetManifest(ref.Repository, reference)
+	if err != nil {
+		return err
+	}
+
+	if registry.IsSchema1(mediaType) {
+		return errors.New("docker schema1 manifests are not supported")
+	}
+
+	if registry.IsIndex(mediaType) {
+		platform, err := registry.ParsePlatform(imagePull.platform)
+		if err != nil {
+			return err
+		}
+
+		var idx registry.Index
+		if err := json.Unmarshal(body, &idx); err != nil {
+			return err
+		}
+
+		var found bool
+		for _, m := range idx.Manifests {
+			if platform.Match(m) {
+				fmt.Println("==> GET manifest", ref.Repository+"@"+m.Digest, "for", platform)
+				body, mediaType, digest, err = c.GetManifest(ref.Repository, m.Digest)
+				if err != nil {
+					return err
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("no manifest for platform %s in %s", platform, image)
+		}
+	}
+
+	var m registry.Manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return err
+	}
+	desc := registry.Descriptor{MediaType: mediaType, Digest: digest, Size: int64(len(body))}
+
+	if format == "oci" {
+		err = pullToLayout(c, ref.Repository, tag, desc, body, &m)
+	} else {
+		err = pullToTarball(c, ref.Repository, tag, &m)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("<== Digest:", digest)
+	return nil
+}
+
+func pullToLayout(c *registry.Client, repo, tag string, desc registry.Descriptor, body []byte, m *registry.Manifest) error {
+	layout := registry.Layout{Path: imagePull.output}
+
+	for _, b := range append([]registry.Descriptor{m.Config}, m.Layers...) {
+		if layout.HasBlob(b.Digest) {
+			fmt.Println("==> Blob exists", b.Digest)
+			continue
+		}
+
+		fmt.Println("==> GET blob", b.Digest, "size:", b.Size)
+		rc, err := c.GetBlob(repo, b.Digest)
+		if err != nil {
+			return err
+		}
+		err = layout.WriteBlob(b.Digest, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := layout.WriteBlob(desc.Digest, bytes.NewReader(body)); err != nil {
+		return err
+	}
+	return layout.AddManifest(desc, tag)
+}
+
+func pullToTarball(c *registry.Client, repo, tag string, m *registry.Manifest) error {
+	f, err := os.Create(imagePull.output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tw := registry.NewTarballWriter(f)
+	tm := registry.TarballManifest{Config: registry.Encoded(m.Config.Digest) + ".json"}
+	if tag != "" {
+		tm.RepoTags = []string{viper.GetString("address") + "/" + repo + ":" + tag}
+	}
+
+	if err := pullTarballFile(c, tw, repo, tm.Config, m.Config); err != nil {
+		return err
+	}
+
+	// NOTE: layers are saved as they are (gzip compressed), which 'docker load' can handle.
+	for _, l := range m.Layers {
+		name := registry.Encoded(l.Digest) + "/layer.tar"
+		if err := pullTarballFile(c, tw, repo, name, l); err != nil {
+			return err
+		}
+		tm.Layers = append(tm.Layers, name)
+	}
+
+	tw.AddManifest(tm)
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func pullTarballFile(c *registry.Client, tw *registry.TarballWriter, repo, name string, b registry.Descriptor) error {
+	fmt.Println("==> GET blob", b.Digest, "size:", b.Size)
+	rc, err := c.GetBlob(repo, b.Digest)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if err := tw.WriteFile(name, b.Size, io.TeeReader(rc, h)); err != nil {
+		return err
+	}
+
+	if got := "sha256:" + hex.EncodeToString(h.Sum(nil)); got != b.Digest {
+		return fmt.Errorf("blob digest mismatch, expect %s, got %s", b.Digest, got)
+	}
+	return nil
+}
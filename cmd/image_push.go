@@ -0,0 +1,282 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/moooofly/harborctl/utils/registry"
+	"github.com/spf13/cobra"
+)
+
+// imagePushCmd represents the push command
+var imagePushCmd = &cobra.Command{
+	Use:   "push IMAGE",
+	Short: "Push an image from an OCI image layout or a 'docker save' tarball.",
+	Long: `This command pushes an image through the registry v2 API, from an OCI image layout directory or a tarball made by 'docker save'.
+
+NOTE:
+- If '--format' is not set, a directory input is taken as an OCI image layout, otherwise as a tarball.
+- If the input holds more than one image, '--ref_name' selects the one to push.
+- Blobs which exist in the repository already are not pushed again.
+- IMAGE must not contain a digest, and the tag defaults to 'latest'.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := pushImage(args[0]); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var imagePush struct {
+	input    string
+	format   string
+	refName  string
+	username string
+	password string
+}
+
+func init() {
+	imageCmd.AddCommand(imagePushCmd)
+
+	imagePushCmd.Flags().StringVarP(&imagePush.input,
+		"input",
+		"i", "",
+		"(REQUIRED) The OCI image layout directory or the tarball to push the image from.")
+	imagePushCmd.MarkFlagRequired("input")
+
+	imagePushCmd.Flags().StringVarP(&imagePush.format,
+		"format",
+		"f", "",
+		"The format of input, valid values are 'oci' and 'docker'.")
+
+	imagePushCmd.Flags().StringVarP(&imagePush.refName,
+		"ref_name",
+		"r", "",
+		"The image to push when the input holds more than one, namely the 'org.opencontainers.image.ref.name' annotation for OCI image layout, or one of 'RepoTags' for tarball.")
+
+	imagePushCmd.Flags().StringVarP(&imagePush.username,
+		"username",
+		"u", "",
+		"(REQUIRED) The username to access the registry.")
+	imagePushCmd.MarkFlagRequired("username")
+
+	imagePushCmd.Flags().StringVarP(&imagePush.password,
+		"password",
+		"p", "",
+		"The password to access the registry. (read from terminal if not set)")
+}
+
+func pushImage(image string) error {
+	ref, err := parseImage(image)
+	if err != nil {
+		return err
+	}
+	if ref.Digest != "" {
+		return errors.New("IMAGE to push must not contain a digest")
+	}
+
+	tag := ref.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+
+	fi, err := os.Stat(imagePush.input)
+	if err != nil {
+		return err
+	}
+
+	format, err := imageFormat(imagePush.format, fi.IsDir())
+	if err != nil {
+		return err
+	}
+
+	c, err := registryClient(imagePush.username, imagePush.password)
+	if err != nil {
+		return err
+	}
+
+	if format == "oci" {
+		layout := registry.Layout{Path: imagePush.input}
+		desc, err := layout.Find(imagePush.refName)
+		if err != nil {
+			return err
+		}
+		return pushLayoutManifest(c, layout, ref.Repository, tag, desc)
+	}
+
+	return pushTarball(c, registry.Tarball{Path: imagePush.input}, ref.Repository, tag)
+}
+
+// pushLayoutManifest pushes the manifest (or index) described by desc along
+// with all the content it references.
+func pushLayoutManifest(c *registry.Client, layout registry.Layout, repo, reference string, desc registry.Descriptor) error {
+	body, err := layout.ReadBlob(desc.Digest)
+	if err != nil {
+		return err
+	}
+
+	mediaType := desc.MediaType
+	if mediaType == "" {
+		mediaType = registry.DetectMediaType(body)
+	}
+
+	if registry.IsIndex(mediaType) {
+		var idx registry.Index
+		if err := json.Unmarshal(body, &idx); err != nil {
+			return err
+		}
+		for _, m := range idx.Manifests {
+			if err := pushLayoutManifest(c, layout, repo, m.Digest, m); err != nil {
+				return err
+			}
+		}
+	} else {
+		var m registry.Manifest
+		if err := json.Unmarshal(body, &m); err != nil {
+			return err
+		}
+		for _, b := range append([]registry.Descriptor{m.Config}, m.Layers...) {
+			err := pushBlob(c, repo, b, func() (io.ReadCloser, error) {
+				return layout.OpenBlob(b.Digest)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Println("==> PUT manifest", repo+":"+reference)
+	if err := c.PutManifest(repo, reference, mediaType, body); err != nil {
+		return err
+	}
+
+	fmt.Println("<== Digest:", registry.Digest(body))
+	return nil
+}
+
+// pushTarball pushes an image of a 'docker save' tarball with a schema2 manifest.
+func pushTarball(c *registry.Client, tb registry.Tarball, repo, tag string) error {
+	tm, err := tb.Find(imagePush.refName)
+	if err != nil {
+		return err
+	}
+
+	rc, _, err := tb.Open(tm.Config)
+	if err != nil {
+		return err
+	}
+	config, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+
+	m := registry.Manifest{
+		SchemaVersion: 2,
+		MediaType:     registry.MediaTypeDockerManifest,
+		Config: registry.Descriptor{
+			MediaType: registry.MediaTypeDockerConfig,
+			Digest:    registry.Digest(config),
+			Size:      int64(len(config)),
+		},
+	}
+
+	err = pushBlob(c, repo, m.Config, func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(config)), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range tm.Layers {
+		desc, err := pushTarballLayer(c, tb, repo, name)
+		if err != nil {
+			return err
+		}
+		m.Layers = append(m.Layers, desc)
+	}
+
+	body, err := json.Marshal(&m)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("==> PUT manifest", repo+":"+tag)
+	if err := c.PutManifest(repo, tag, m.MediaType, body); err != nil {
+		return err
+	}
+
+	fmt.Println("<== Digest:", registry.Digest(body))
+	return nil
+}
+
+// pushTarballLayer compresses a layer of the tarball into a temporary file to
+// get its digest, and pushes it from there.
+func pushTarballLayer(c *registry.Client, tb registry.Tarball, repo, name string) (registry.Descriptor, error) {
+	tmp, err := ioutil.TempFile("", "harborctl-layer-")
+	if err != nil {
+		return registry.Descriptor{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	desc, err := tb.CopyLayer(name, tmp)
+	if err != nil {
+		return registry.Descriptor{}, err
+	}
+
+	err = pushBlob(c, repo, desc, func() (io.ReadCloser, error) {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(tmp), nil
+	})
+	return desc, err
+}
+
+// pushBlob pushes the blob opened by open, unless it exists in repo already.
+func pushBlob(c *registry.Client, repo string, b registry.Descriptor, open func() (io.ReadCloser, error)) error {
+	exists, err := c.BlobExists(repo, b.Digest)
+	if err != nil {
+		return err
+	}
+	if exists {
+		fmt.Println("==> Blob exists", b.Digest)
+		return nil
+	}
+
+	rc, err := open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	fmt.Println("==> PUT blob", b.Digest, "size:", b.Size)
+	return c.PutBlob(repo, b.Digest, b.Size, rc)
+}
@@ -54,6 +54,69 @@ func init() {
 	initLabelGet()
 }
 
+// labelBrief is a label of the global or a project scope.
+type labelBrief struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Color       string `json:"color"`
+	Scope       string `json:"scope"`
+	ProjectID   int64  `json:"project_id"`
+}
+
+// listLabels lists the global labels of the instance if projectID is 0,
+// otherwise the labels of the project.
+func listLabels(ctx *utils.Context, projectID int64) ([]labelBrief, error) {
+	uri := "/api/labels?scope=g"
+	if projectID != 0 {
+		uri = "/api/labels?scope=p&project_id=" + strconv.FormatInt(projectID, 10)
+	}
+
+	var labels []labelBrief
+	err := utils.GetPages(uri, 100, func(pageURI string) (int, error) {
+		var page []labelBrief
+		if err := ctx.GetStruct(pageURI, &page); err != nil {
+			return 0, err
+		}
+		labels = append(labels, page...)
+		return len(page), nil
+	})
+	return labels, err
+}
+
+// availableLabels lists the labels available to the project: the global ones
+// and the ones of the project.
+func availableLabels(ctx *utils.Context, projectID int64) ([]labelBrief, error) {
+	labels, err := listLabels(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	projectLabels, err := listLabels(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return append(labels, projectLabels...), nil
+}
+
+// findLabel finds a label by its name or ID. A project label wins over a
+// global one of the same name.
+func findLabel(labels []labelBrief, s string) (*labelBrief, error) {
+	var found *labelBrief
+	for i := range labels {
+		l := &labels[i]
+		if strconv.FormatInt(l.ID, 10) == s {
+			return l, nil
+		}
+		if l.Name == s && (found == nil || l.Scope == "p") {
+			found = l
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no global or project label %q", s)
+	}
+	return found, nil
+}
+
 // labelListCmd represents the list command
 var labelListCmd = &cobra.Command{
 	Use:   "list",
@@ -86,10 +149,10 @@ func initLabelList() {
 		"(REQUIRED) The label scope. Valid values are 'g' and 'p'. 'g' for global labels and 'p' for project labels.")
 	labelListCmd.MarkFlagRequired("scope")
 
-	labelListCmd.Flags().Int64VarP(&labelList.projectID,
+	labelListCmd.Flags().VarP(newIDValue(&labelList.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"Relevant project ID, required when scope is 'p'.")
+		"j",
+		"Relevant project ID or name, required when scope is 'p'.")
 
 	labelListCmd.Flags().Int64VarP(&labelList.page,
 		"page",
@@ -172,10 +235,10 @@ func initLabelCreate() {
 		"(REQUIRED) The scope of this label. ('p' for project scope, 'g' for global scope)")
 	labelCreateCmd.MarkFlagRequired("scope")
 
-	labelCreateCmd.Flags().Int64VarP(&labelCreate.ProjectID,
+	labelCreateCmd.Flags().VarP(newIDValue(&labelCreate.ProjectID, "project"),
 		"project_id",
-		"j", 0,
-		"The project ID if the label is a project label. ('0' indicates global label, others indicate specific project)")
+		"j",
+		"The project ID or name if the label is a project label. ('0' indicates global label, others indicate specific project)")
 
 	labelCreateCmd.Flags().StringVarP(&labelCreate.CreationTime,
 		"creation_time",
@@ -222,10 +285,10 @@ var labelDelete struct {
 func initLabelDelete() {
 	labelCmd.AddCommand(labelDeleteCmd)
 
-	labelDeleteCmd.Flags().Int64VarP(&labelDelete.ID,
+	labelDeleteCmd.Flags().VarP(newIDValue(&labelDelete.ID, "label"),
 		"id",
-		"i", 0,
-		"(REQUIRED) The ID of the already existing label.")
+		"i",
+		"(REQUIRED) The ID or name of the already existing label.")
 	labelDeleteCmd.MarkFlagRequired("id")
 }
 
@@ -260,10 +323,10 @@ var labelUpdate struct {
 func initLabelUpdate() {
 	labelCmd.AddCommand(labelUpdateCmd)
 
-	labelUpdateCmd.Flags().Int64VarP(&labelUpdate.ID,
+	labelUpdateCmd.Flags().VarP(newIDValue(&labelUpdate.ID, "label").inProject(&labelUpdate.ProjectID),
 		"id",
-		"i", 0,
-		"(REQUIRED) The ID of the label.")
+		"i",
+		"(REQUIRED) The ID or name of the label.")
 	labelUpdateCmd.MarkFlagRequired("id")
 
 	labelUpdateCmd.Flags().StringVarP(&labelUpdate.Name,
@@ -287,10 +350,10 @@ func initLabelUpdate() {
 		"s", "",
 		"The scope of this label. ('p' for project scope, 'g' for global scope)")
 
-	labelUpdateCmd.Flags().Int64VarP(&labelUpdate.ProjectID,
+	labelUpdateCmd.Flags().VarP(newIDValue(&labelUpdate.ProjectID, "project"),
 		"project_id",
-		"j", 0,
-		"The project ID if the label is a project label. ('0' indicates global label, others indicate specific project)")
+		"j",
+		"The project ID or name if the label is a project label. ('0' indicates global label, others indicate specific project)")
 
 	labelUpdateCmd.Flags().StringVarP(&labelUpdate.CreationTime,
 		"creation_time",
@@ -337,10 +400,10 @@ var labelGet struct {
 func initLabelGet() {
 	labelCmd.AddCommand(labelGetCmd)
 
-	labelGetCmd.Flags().Int64VarP(&labelGet.ID,
+	labelGetCmd.Flags().VarP(newIDValue(&labelGet.ID, "label"),
 		"id",
-		"i", 0,
-		"(REQUIRED) The ID of the already existing label.")
+		"i",
+		"(REQUIRED) The ID or name of the already existing label.")
 	labelGetCmd.MarkFlagRequired("id")
 }
 
@@ -0,0 +1,416 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// labelApplyCmd represents the apply command
+var labelApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Attach or detach labels to the images, repositories or charts selected.",
+	Long: `This command attaches the labels to (or with '--detach', detaches them from) all the resources of '--kind' selected by '--selector'.
+
+The selector is of terms separated by commas, which must all match. A term is 'KEY=VALUE' or 'KEY!=VALUE' for the exact value, or 'KEY=~REGEXP' or 'KEY!~REGEXP' for a regular expression, where the keys are:
+
+  project  the project name
+  repo     the repository name without the project, for images and repositories
+  tag      the tag, for images
+  chart    the chart name, for charts
+  version  the chart version, for charts
+
+e.g. 'project=x,repo=~^api/.*,tag=~^v1\.' selects the images tagged 'v1.*' in the repositories 'x/api/*'.
+
+The labels are given by names or IDs, of the global scope or the scope of the project of each resource.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := applyLabels(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var labelApply struct {
+	labels   []string
+	selector string
+	kind     string
+	detach   bool
+	dryRun   bool
+}
+
+func init() {
+	labelCmd.AddCommand(labelApplyCmd)
+
+	labelApplyCmd.Flags().StringSliceVarP(&labelApply.labels,
+		"label",
+		"l", nil,
+		"(REQUIRED) The names or IDs of the labels, separated by commas or by repeating the flag.")
+	labelApplyCmd.MarkFlagRequired("label")
+
+	labelApplyCmd.Flags().StringVarP(&labelApply.selector,
+		"selector",
+		"s", "",
+		"(REQUIRED) The selector of the resources, e.g. 'project=x,repo=~^api/.*,tag=~^v1\\.'.")
+	labelApplyCmd.MarkFlagRequired("selector")
+
+	labelApplyCmd.Flags().StringVarP(&labelApply.kind,
+		"kind",
+		"k", "image",
+		"The kind of the resources, valid values are 'image', 'repository' and 'chart'.")
+
+	labelApplyCmd.Flags().BoolVarP(&labelApply.detach,
+		"detach",
+		"d", false,
+		"Detach the labels instead of attaching.")
+
+	labelApplyCmd.Flags().BoolVarP(&labelApply.dryRun,
+		"dry_run",
+		"", false,
+		"Show the changes only.")
+}
+
+// selectorKeys are the keys of the selector valid for each kind of resources.
+var selectorKeys = map[string][]string{
+	"image":      {"project", "repo", "tag"},
+	"repository": {"project", "repo"},
+	"chart":      {"project", "chart", "version"},
+}
+
+type selectorTerm struct {
+	key   string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+// labelSelector selects resources by their project, repository, tag, chart
+// name and version.
+type labelSelector []selectorTerm
+
+var selectorTermRegexp = regexp.MustCompile(`^\s*([a-z]+)\s*(=~|!~|!=|==|=)(.*)$`)
+
+func parseLabelSelector(s, kind string) (labelSelector, error) {
+	keys, ok := selectorKeys[kind]
+	if !ok {
+		return nil, errors.New("kind must be one of [image|repository|chart]")
+	}
+
+	var sel labelSelector
+	for _, t := range strings.Split(s, ",") {
+		if strings.TrimSpace(t) == "" {
+			continue
+		}
+
+		m := selectorTermRegexp.FindStringSubmatch(t)
+		if m == nil {
+			return nil, fmt.Errorf("invalid selector term %q, must be like 'KEY=VALUE' or 'KEY=~REGEXP'", t)
+		}
+		term := selectorTerm{key: m[1], op: m[2], value: strings.TrimSpace(m[3])}
+		if term.op == "==" {
+			term.op = "="
+		}
+
+		valid := false
+		for _, k := range keys {
+			valid = valid || k == term.key
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid selector key %q of %s, must be one of %v", term.key, kind, keys)
+		}
+
+		if term.op == "=~" || term.op == "!~" {
+			re, err := regexp.Compile(term.value)
+			if err != nil {
+				return nil, fmt.Errorf("selector term %q: %v", t, err)
+			}
+			term.re = re
+		}
+		sel = append(sel, term)
+	}
+	return sel, nil
+}
+
+// match reports whether value matches all the terms of key.
+func (sel labelSelector) match(key, value string) bool {
+	for _, t := range sel {
+		if t.key != key {
+			continue
+		}
+
+		var ok bool
+		switch t.op {
+		case "=":
+			ok = value == t.value
+		case "!=":
+			ok = value != t.value
+		case "=~":
+			ok = t.re.MatchString(value)
+		case "!~":
+			ok = !t.re.MatchString(value)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// labelResource is an image, a repository or a chart version which labels
+// are attached to.
+type labelResource struct {
+	kind      string
+	projectID int64
+	project   string
+	// name is the repository name with the project, or the chart name.
+	name string
+	// ref is the tag or the chart version.
+	ref    string
+	labels map[int64]bool
+}
+
+func (r *labelResource) String() string {
+	switch r.kind {
+	case "image":
+		return r.name + ":" + r.ref
+	case "chart":
+		return r.project + "/" + r.name + ":" + r.ref
+	default:
+		return r.name
+	}
+}
+
+func (r *labelResource) labelsURL() string {
+	switch r.kind {
+	case "image":
+		return utils.URLGen("/api/repositories/" + r.name + "/tags/" + r.ref + "/labels")
+	case "chart":
+		return utils.URLGen("/api/chartrepo/" + r.project + "/charts/" + r.name + "/" + r.ref + "/labels")
+	default:
+		return utils.URLGen("/api/repositories/" + r.name + "/labels")
+	}
+}
+
+func (r *labelResource) attach(labelID int64) error {
+	body := struct {
+		ID int64 `json:"id"`
+	}{labelID}
+	_, _, err := utils.Send("POST", r.labelsURL(), &body)
+	return err
+}
+
+func (r *labelResource) detach(labelID int64) error {
+	_, _, err := utils.Send("DELETE", r.labelsURL()+"/"+strconv.FormatInt(labelID, 10), nil)
+	return err
+}
+
+type labelIDs []struct {
+	ID int64 `json:"id"`
+}
+
+func (ls labelIDs) set() map[int64]bool {
+	m := make(map[int64]bool)
+	for _, l := range ls {
+		m[l.ID] = true
+	}
+	return m
+}
+
+// listLabelResources lists the resources of the kind in the project selected
+// by sel.
+func listLabelResources(kind string, p *projectBrief, sel labelSelector) ([]*labelResource, error) {
+	if kind == "chart" {
+		return listLabelCharts(p, sel)
+	}
+
+	type repository struct {
+		Name   string   `json:"name"`
+		Labels labelIDs `json:"labels"`
+	}
+	var repos []repository
+	targetURL := utils.URLGen("/api/repositories") + "?project_id=" + strconv.FormatInt(p.ProjectID, 10)
+	err := utils.GetPages(targetURL, 100, func(pageURL string) (int, error) {
+		var page []repository
+		if err := utils.GetStruct(pageURL, &page); err != nil {
+			return 0, err
+		}
+		repos = append(repos, page...)
+		return len(page), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []*labelResource
+	for _, repo := range repos {
+		if !sel.match("repo", strings.TrimPrefix(repo.Name, p.Name+"/")) {
+			continue
+		}
+		if kind == "repository" {
+			resources = append(resources, &labelResource{kind, p.ProjectID, p.Name, repo.Name, "", repo.Labels.set()})
+			continue
+		}
+
+		var tags []struct {
+			Name   string   `json:"name"`
+			Labels labelIDs `json:"labels"`
+		}
+		if err := utils.GetStruct(utils.URLGen("/api/repositories/"+repo.Name+"/tags"), &tags); err != nil {
+			return nil, err
+		}
+		for _, t := range tags {
+			if sel.match("tag", t.Name) {
+				resources = append(resources, &labelResource{kind, p.ProjectID, p.Name, repo.Name, t.Name, t.Labels.set()})
+			}
+		}
+	}
+	return resources, nil
+}
+
+func listLabelCharts(p *projectBrief, sel labelSelector) ([]*labelResource, error) {
+	var charts []struct {
+		Name string `json:"name"`
+	}
+	if err := utils.GetStruct(utils.URLGen("/api/chartrepo/"+p.Name+"/charts"), &charts); err != nil {
+		// NOTE: the chart repository of a project is not created until a chart is uploaded.
+		if utils.IsStatus(err, 404) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var resources []*labelResource
+	for _, c := range charts {
+		if !sel.match("chart", c.Name) {
+			continue
+		}
+
+		var versions []struct {
+			Version string   `json:"version"`
+			Labels  labelIDs `json:"labels"`
+		}
+		if err := utils.GetStruct(utils.URLGen("/api/chartrepo/"+p.Name+"/charts/"+c.Name), &versions); err != nil {
+			return nil, err
+		}
+		for _, v := range versions {
+			if sel.match("version", v.Version) {
+				resources = append(resources, &labelResource{"chart", p.ProjectID, p.Name, c.Name, v.Version, v.Labels.set()})
+			}
+		}
+	}
+	return resources, nil
+}
+
+func applyLabels() error {
+	sel, err := parseLabelSelector(labelApply.selector, labelApply.kind)
+	if err != nil {
+		return err
+	}
+	if len(sel) == 0 {
+		return errors.New("selector is empty")
+	}
+
+	projects, err := listProjects()
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[string]int)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tRESOURCE\tLABEL\tRESULT")
+	for i := range projects {
+		p := &projects[i]
+		if !sel.match("project", p.Name) {
+			continue
+		}
+
+		resources, err := listLabelResources(labelApply.kind, p, sel)
+		if err != nil {
+			return err
+		}
+		if len(resources) == 0 {
+			continue
+		}
+
+		available, err := availableLabels(utils.CurrentContext(), p.ProjectID)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range resources {
+			for _, s := range labelApply.labels {
+				// NOTE: a project label may be missing in the other projects selected.
+				var result string
+				if l, err := findLabel(available, s); err != nil {
+					result = "failed: " + err.Error()
+				} else {
+					result = applyLabel(r, l.ID, labelApply.detach, labelApply.dryRun)
+				}
+				counts[strings.SplitN(result, ":", 2)[0]]++
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.kind, r, s, result)
+			}
+		}
+	}
+	w.Flush()
+
+	fmt.Printf("\nchanged: %d, unchanged: %d, failed: %d\n",
+		counts["attached"]+counts["detached"]+counts["to attach"]+counts["to detach"], counts["unchanged"], counts["failed"])
+	if counts["failed"] > 0 {
+		return fmt.Errorf("%d changes failed", counts["failed"])
+	}
+	return nil
+}
+
+// applyLabel attaches or detaches the label to the resource if needed, and
+// returns the result.
+func applyLabel(r *labelResource, labelID int64, detach, dryRun bool) string {
+	switch {
+	case !detach && r.labels[labelID], detach && !r.labels[labelID]:
+		return "unchanged"
+	case dryRun && detach:
+		return "to detach"
+	case dryRun:
+		return "to attach"
+	}
+
+	if detach {
+		if err := r.detach(labelID); err != nil {
+			return "failed: " + err.Error()
+		}
+		delete(r.labels, labelID)
+		return "detached"
+	}
+
+	if err := r.attach(labelID); err != nil {
+		return "failed: " + err.Error()
+	}
+	r.labels[labelID] = true
+	return "attached"
+}
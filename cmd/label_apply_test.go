@@ -0,0 +1,93 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import "testing"
+
+func TestParseLabelSelector(t *testing.T) {
+	type value struct {
+		key, value string
+		want       bool
+	}
+	tests := []struct {
+		sel    string
+		kind   string
+		values []value
+	}{
+		{"", "image", []value{{"repo", "library/nginx", true}}},
+		{"project=library", "image", []value{
+			{"project", "library", true},
+			{"project", "library2", false},
+			// the terms of other keys do not apply.
+			{"repo", "team/app", true},
+		}},
+		{"project==library", "repository", []value{{"project", "library", true}, {"project", "team", false}}},
+		{"tag!=latest", "image", []value{{"tag", "1.15", true}, {"tag", "latest", false}}},
+		{"tag=~^1\\.15", "image", []value{{"tag", "1.15.2", true}, {"tag", "v1.15", false}}},
+		// the regexps are not anchored.
+		{"repo=~nginx", "image", []value{{"repo", "library/nginx-ingress", true}, {"repo", "library/redis", false}}},
+		{"repo!~-rc$", "repository", []value{{"repo", "library/app", true}, {"repo", "library/app-rc", false}}},
+		{" project = library , repo=~^library/a ", "image", []value{
+			{"project", "library", true},
+			{"repo", "library/app", true},
+			{"repo", "library/b", false},
+		}},
+		{"version=~^1\\.,version!=1.2.0", "chart", []value{
+			{"version", "1.3.0", true},
+			{"version", "1.2.0", false},
+			{"version", "2.0.0", false},
+		}},
+		{"chart=nginx,,", "chart", []value{{"chart", "nginx", true}}},
+	}
+	for _, tt := range tests {
+		sel, err := parseLabelSelector(tt.sel, tt.kind)
+		if err != nil {
+			t.Errorf("parseLabelSelector(%q, %q): %v", tt.sel, tt.kind, err)
+			continue
+		}
+		for _, v := range tt.values {
+			if got := sel.match(v.key, v.value); got != v.want {
+				t.Errorf("parseLabelSelector(%q).match(%q, %q) = %v, want %v", tt.sel, v.key, v.value, got, v.want)
+			}
+		}
+	}
+}
+
+func TestParseLabelSelectorInvalid(t *testing.T) {
+	tests := []struct {
+		sel, kind string
+	}{
+		{"project=library", "label"},
+		{"project", "image"},
+		{"=library", "image"},
+		{"Project=library", "image"},
+		{"tag=1.0", "repository"},
+		{"chart=nginx", "image"},
+		{"repo=library/nginx", "chart"},
+		{"repo=~(", "image"},
+		{"project>library", "image"},
+	}
+	for _, tt := range tests {
+		if _, err := parseLabelSelector(tt.sel, tt.kind); err == nil {
+			t.Errorf("parseLabelSelector(%q, %q) succeeded, want error", tt.sel, tt.kind)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// labelConvertCmd represents the convert command
+var labelConvertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert a label between the global and the project scope, keeping the resources attached.",
+	Long: `This command converts the label '--label' to the scope '--scope', by creating (or reusing) the label of the same name, color and description in the new scope, moving the images, repositories and charts to it, and deleting the old label.
+
+To the project scope, a global label is converted in each project using it, or in the project of '--project' only. The global label is kept if any project still uses it, or a replication policy refers to it.
+
+To the global scope, a label of the project '--project' is converted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := convertLabel(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var labelConvert struct {
+	label   string
+	project string
+	scope   string
+	keep    bool
+	dryRun  bool
+}
+
+func init() {
+	labelCmd.AddCommand(labelConvertCmd)
+
+	labelConvertCmd.Flags().StringVarP(&labelConvert.label,
+		"label",
+		"l", "",
+		"(REQUIRED) The name or ID of the label.")
+	labelConvertCmd.MarkFlagRequired("label")
+
+	labelConvertCmd.Flags().StringVarP(&labelConvert.project,
+		"project",
+		"p", "",
+		"The name of the project, required when converting to the global scope.")
+
+	labelConvertCmd.Flags().StringVarP(&labelConvert.scope,
+		"scope",
+		"s", "",
+		"(REQUIRED) The scope to convert to. ('p' for project scope, 'g' for global scope)")
+	labelConvertCmd.MarkFlagRequired("scope")
+
+	labelConvertCmd.Flags().BoolVarP(&labelConvert.keep,
+		"keep",
+		"k", false,
+		"Keep the old label.")
+
+	labelConvertCmd.Flags().BoolVarP(&labelConvert.dryRun,
+		"dry_run",
+		"", false,
+		"Show the changes only.")
+}
+
+func convertLabel() error {
+	if labelConvert.scope != "g" && labelConvert.scope != "p" {
+		return errors.New("scope must be 'g' or 'p'")
+	}
+	if labelConvert.scope == "g" && labelConvert.project == "" {
+		return errors.New("'--project' is required when converting to the global scope")
+	}
+
+	ctx := utils.CurrentContext()
+	projects, err := listProjects()
+	if err != nil {
+		return err
+	}
+
+	var only *projectBrief
+	if labelConvert.project != "" {
+		for i := range projects {
+			if projects[i].Name == labelConvert.project {
+				only = &projects[i]
+			}
+		}
+		if only == nil {
+			return fmt.Errorf("no project named %q", labelConvert.project)
+		}
+		projects = []projectBrief{*only}
+	}
+
+	var labels []labelBrief
+	if only != nil {
+		labels, err = availableLabels(ctx, only.ProjectID)
+	} else {
+		labels, err = listLabels(ctx, 0)
+	}
+	if err != nil {
+		return err
+	}
+	old, err := resolveLabel(labels, labelConvert.label)
+	if err != nil {
+		return err
+	}
+	if old.Scope == labelConvert.scope {
+		return fmt.Errorf("label %q is of the scope %q already", old.Name, old.Scope)
+	}
+	if old.Scope == "p" && old.ProjectID != only.ProjectID {
+		return fmt.Errorf("label %q is not of the project %s", old.Name, only.Name)
+	}
+
+	moved, failed, kept := 0, 0, false
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tRESOURCE\tRESULT")
+	for i := range projects {
+		p := &projects[i]
+		resources, err := labeledResources(p, old.ID)
+		if err != nil {
+			w.Flush()
+			return err
+		}
+		if len(resources) == 0 && only == nil {
+			continue
+		}
+
+		scope := &labelScope{ctx: ctx}
+		if labelConvert.scope == "p" {
+			scope = &labelScope{ctx, p.Name, p.ProjectID}
+		}
+		l, err := convertedLabel(w, scope, old, labelConvert.dryRun)
+		if err != nil {
+			w.Flush()
+			return err
+		}
+
+		m, f := moveResources(w, resources, old.ID, l.ID, labelConvert.dryRun)
+		moved, failed = moved+m, failed+f
+	}
+	w.Flush()
+
+	// NOTE: the global label may be still used by the projects not converted.
+	if old.Scope == "g" && only != nil {
+		all, err := listProjects()
+		if err != nil {
+			return err
+		}
+		for i := range all {
+			if kept || all[i].ProjectID == only.ProjectID {
+				continue
+			}
+			resources, err := labeledResources(&all[i], old.ID)
+			if err != nil {
+				return err
+			}
+			kept = len(resources) > 0
+		}
+	}
+
+	fmt.Printf("\nmoved: %d, failed: %d\n", moved, failed)
+	switch {
+	case failed > 0:
+		return fmt.Errorf("%d resources failed to move, label %q is kept", failed, old.Name)
+	case kept:
+		fmt.Printf("label %q is kept, as the other projects use it\n", old.Name)
+		return nil
+	case labelConvert.keep || labelConvert.dryRun:
+		return nil
+	}
+	return deleteUnusedLabel(old)
+}
+
+// convertedLabel returns the label of the same name as old in the scope,
+// creating it if missing, and writes a row to w for the label created.
+func convertedLabel(w io.Writer, s *labelScope, old *labelBrief, dryRun bool) (*labelBrief, error) {
+	labels, err := listLabels(s.ctx, s.projectID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range labels {
+		if labels[i].Name == old.Name {
+			return &labels[i], nil
+		}
+	}
+
+	l := &labelBrief{Name: old.Name, Color: old.Color, Description: old.Description}
+	if dryRun {
+		fmt.Fprintf(w, "label\t%s %s\t%s\n", s, l.Name, "to be created")
+		return l, nil
+	}
+	if err := saveLabel(s, l); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(w, "label\t%s %s\t%s\n", s, l.Name, "created")
+	return l, nil
+}
@@ -0,0 +1,254 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// labelMoveCmd represents the move command
+var labelMoveCmd = &cobra.Command{
+	Use:   "move",
+	Short: "Move the images, repositories and charts from a label to another.",
+	Long: `This command attaches the label '--to' to all the images, repositories and charts the label '--from' is attached to, and then detaches '--from' from them, e.g. before deleting or renaming a label.
+
+The labels are given by names or IDs. A name is looked up in the global labels, or in the labels available to the project of '--project' if given.
+
+With '--delete', the label '--from' is deleted after all the resources are moved, unless a replication policy still refers to it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := moveLabel(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var labelMove struct {
+	from    string
+	to      string
+	project string
+	delete  bool
+	dryRun  bool
+}
+
+func init() {
+	labelCmd.AddCommand(labelMoveCmd)
+
+	labelMoveCmd.Flags().StringVarP(&labelMove.from,
+		"from",
+		"f", "",
+		"(REQUIRED) The name or ID of the label to move the resources from.")
+	labelMoveCmd.MarkFlagRequired("from")
+
+	labelMoveCmd.Flags().StringVarP(&labelMove.to,
+		"to",
+		"t", "",
+		"(REQUIRED) The name or ID of the label to move the resources to.")
+	labelMoveCmd.MarkFlagRequired("to")
+
+	labelMoveCmd.Flags().StringVarP(&labelMove.project,
+		"project",
+		"p", "",
+		"The name of the project whose labels the names are looked up in.")
+
+	labelMoveCmd.Flags().BoolVarP(&labelMove.delete,
+		"delete",
+		"", false,
+		"Delete the label '--from' after moving.")
+
+	labelMoveCmd.Flags().BoolVarP(&labelMove.dryRun,
+		"dry_run",
+		"", false,
+		"Show the changes only.")
+}
+
+// resolveLabel finds a label by its ID, or by its name in labels.
+func resolveLabel(labels []labelBrief, s string) (*labelBrief, error) {
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		var l labelBrief
+		if err := utils.GetStruct(labelURL+"/"+s, &l); err != nil {
+			return nil, err
+		}
+		return &l, nil
+	}
+	return findLabel(labels, s)
+}
+
+func moveLabel() error {
+	ctx := utils.CurrentContext()
+
+	var projectID int64
+	if labelMove.project != "" {
+		id, err := findProjectID(labelMove.project)
+		if err != nil {
+			return err
+		}
+		projectID = id
+	}
+	labels, err := availableLabels(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	from, err := resolveLabel(labels, labelMove.from)
+	if err != nil {
+		return err
+	}
+	to, err := resolveLabel(labels, labelMove.to)
+	if err != nil {
+		return err
+	}
+	if from.ID == to.ID {
+		return errors.New("the labels to move from and to are the same")
+	}
+
+	// NOTE: a project label can only be attached to the resources of its project.
+	var scope int64
+	switch {
+	case from.Scope == "p" && to.Scope == "p" && from.ProjectID != to.ProjectID:
+		return fmt.Errorf("label %q and %q are of different projects", from.Name, to.Name)
+	case from.Scope == "p":
+		scope = from.ProjectID
+	case to.Scope == "p":
+		return fmt.Errorf("can not move from the global label %q to the project label %q", from.Name, to.Name)
+	}
+
+	projects, err := listProjects()
+	if err != nil {
+		return err
+	}
+
+	moved, failed := 0, 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tRESOURCE\tRESULT")
+	for i := range projects {
+		p := &projects[i]
+		if scope != 0 && p.ProjectID != scope {
+			continue
+		}
+
+		resources, err := labeledResources(p, from.ID)
+		if err != nil {
+			w.Flush()
+			return err
+		}
+		m, f := moveResources(w, resources, from.ID, to.ID, labelMove.dryRun)
+		moved, failed = moved+m, failed+f
+	}
+	w.Flush()
+
+	fmt.Printf("\nmoved: %d, failed: %d\n", moved, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d resources failed to move, label %q is kept", failed, from.Name)
+	}
+	if !labelMove.delete || labelMove.dryRun {
+		return nil
+	}
+	return deleteUnusedLabel(from)
+}
+
+// labeledResources lists the resources of all kinds in the project which the
+// label is attached to.
+func labeledResources(p *projectBrief, labelID int64) ([]*labelResource, error) {
+	var labeled []*labelResource
+	for _, kind := range []string{"repository", "image", "chart"} {
+		resources, err := listLabelResources(kind, p, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resources {
+			if r.labels[labelID] {
+				labeled = append(labeled, r)
+			}
+		}
+	}
+	return labeled, nil
+}
+
+// moveResources moves the resources from a label to another, writing a row of
+// 'KIND RESOURCE RESULT' to w for each, and returns the numbers of the
+// resources moved and failed.
+func moveResources(w io.Writer, resources []*labelResource, from, to int64, dryRun bool) (int, int) {
+	moved, failed := 0, 0
+	for _, r := range resources {
+		result := "to move"
+		if !dryRun {
+			result = moveResource(r, from, to)
+		}
+		if result == "moved" {
+			moved++
+		} else if result != "to move" {
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.kind, r, result)
+	}
+	return moved, failed
+}
+
+// deleteUnusedLabel deletes the label unless a replication policy refers to it.
+func deleteUnusedLabel(l *labelBrief) error {
+	var refs struct {
+		ReplicationPolicies []struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		} `json:"replication_policies"`
+	}
+	if err := utils.GetStruct(labelURL+"/"+strconv.FormatInt(l.ID, 10)+"/resources", &refs); err != nil {
+		return err
+	}
+	if len(refs.ReplicationPolicies) > 0 {
+		var names []string
+		for _, p := range refs.ReplicationPolicies {
+			names = append(names, p.Name)
+		}
+		return fmt.Errorf("label %q is kept, as the replication policies %v refer to it", l.Name, names)
+	}
+
+	if _, _, err := utils.Send("DELETE", labelURL+"/"+strconv.FormatInt(l.ID, 10), nil); err != nil {
+		return err
+	}
+	fmt.Printf("label %q deleted\n", l.Name)
+	return nil
+}
+
+// moveResource attaches label to to r, and then detaches label from.
+func moveResource(r *labelResource, from, to int64) string {
+	if !r.labels[to] {
+		if err := r.attach(to); err != nil {
+			return "failed: " + err.Error()
+		}
+		r.labels[to] = true
+	}
+
+	if err := r.detach(from); err != nil {
+		return "failed: " + err.Error()
+	}
+	delete(r.labels, from)
+	return "moved"
+}
@@ -44,10 +44,10 @@ var labelResourceGet struct {
 func init() {
 	labelCmd.AddCommand(labelResourceCmd)
 
-	labelResourceCmd.Flags().Int64VarP(&labelResourceGet.ID,
+	labelResourceCmd.Flags().VarP(newIDValue(&labelResourceGet.ID, "label"),
 		"id",
-		"i", 0,
-		"(REQUIRED) The ID of the label.")
+		"i",
+		"(REQUIRED) The ID or name of the label.")
 	labelResourceCmd.MarkFlagRequired("id")
 }
 
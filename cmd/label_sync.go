@@ -0,0 +1,262 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// labelSyncCmd represents the sync command
+var labelSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Copy the label definitions from a project to other projects, or between instances.",
+	Long: `This command copies the labels (name, color and description) of '--from_project' to each project of '--to_project', or to all the projects with '--all_projects'. The global labels are copied if a project is not given.
+
+A label missing in the destination is created, and a label of the same name with a different color or description is updated, so running the command again changes nothing.
+
+The source and the destination are of the instance of 'address' by default, or of the contexts configured by '--from_context' and '--to_context', see 'harborctl compare --help'.
+
+e.g. to maintain the labels of 'team-template' in all the projects of the 'prod' instance:
+
+  harborctl label sync --from_project team-template --to_context prod --all_projects`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := syncLabels(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var labelSync struct {
+	fromContext string
+	toContext   string
+	fromProject string
+	toProjects  []string
+	allProjects bool
+	dryRun      bool
+}
+
+func init() {
+	labelCmd.AddCommand(labelSyncCmd)
+
+	labelSyncCmd.Flags().StringVarP(&labelSync.fromContext,
+		"from_context",
+		"", "",
+		"The context of the source instance, default is the instance of 'address'.")
+
+	labelSyncCmd.Flags().StringVarP(&labelSync.toContext,
+		"to_context",
+		"", "",
+		"The context of the destination instance, default is the instance of 'address'.")
+
+	labelSyncCmd.Flags().StringVarP(&labelSync.fromProject,
+		"from_project",
+		"f", "",
+		"The name of the project to copy the labels from, the global labels if not given.")
+
+	labelSyncCmd.Flags().StringSliceVarP(&labelSync.toProjects,
+		"to_project",
+		"t", nil,
+		"The names of the projects to copy the labels to, separated by commas or by repeating the flag, the global labels if not given.")
+
+	labelSyncCmd.Flags().BoolVarP(&labelSync.allProjects,
+		"all_projects",
+		"a", false,
+		"Copy the labels to all the projects of the destination, except '--from_project'.")
+
+	labelSyncCmd.Flags().BoolVarP(&labelSync.dryRun,
+		"dry_run",
+		"", false,
+		"Show the changes only.")
+}
+
+// loadContext loads the context of name, or returns the current one if name
+// is empty.
+func loadContext(name string) (*utils.Context, error) {
+	if name == "" {
+		return utils.CurrentContext(), nil
+	}
+	return utils.LoadContext(name)
+}
+
+// labelScope is the global scope of an instance if projectID is 0, otherwise
+// the scope of the project.
+type labelScope struct {
+	ctx       *utils.Context
+	project   string
+	projectID int64
+}
+
+func (s *labelScope) String() string {
+	name := "(global)"
+	if s.projectID != 0 {
+		name = s.project
+	}
+	if s.ctx.Name != "current" {
+		name = s.ctx.Name + ":" + name
+	}
+	return name
+}
+
+// saveLabel creates the label in the scope if its ID is 0, otherwise updates
+// it. The ID of a label created is set.
+func saveLabel(s *labelScope, l *labelBrief) error {
+	l.Scope, l.ProjectID = "g", s.projectID
+	if s.projectID != 0 {
+		l.Scope = "p"
+	}
+
+	if l.ID != 0 {
+		_, _, err := s.ctx.Send("PUT", "/api/labels/"+strconv.FormatInt(l.ID, 10), l)
+		return err
+	}
+
+	resp, _, err := s.ctx.Send("POST", "/api/labels", l)
+	if err != nil {
+		return err
+	}
+	l.ID, err = strconv.ParseInt(path.Base(resp.Header.Get("Location")), 10, 64)
+	return err
+}
+
+// syncScope copies the labels to the scope, writing a row of 'SCOPE LABEL
+// RESULT' to w for each, and returns the number of labels failed.
+func syncScope(w io.Writer, s *labelScope, labels []labelBrief, dryRun bool) (int, error) {
+	existing, err := listLabels(s.ctx, s.projectID)
+	if err != nil {
+		return 0, err
+	}
+	byName := make(map[string]labelBrief)
+	for _, l := range existing {
+		byName[l.Name] = l
+	}
+
+	failed := 0
+	for _, l := range labels {
+		result := "created"
+		dst := labelBrief{Name: l.Name, Color: l.Color, Description: l.Description}
+		if e, ok := byName[l.Name]; ok {
+			if e.Color == l.Color && e.Description == l.Description {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", s, l.Name, "unchanged")
+				continue
+			}
+			result = "updated"
+			dst.ID = e.ID
+		}
+
+		if dryRun {
+			result = "to be " + result
+		} else if err := saveLabel(s, &dst); err != nil {
+			result = "failed: " + err.Error()
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s, l.Name, result)
+	}
+	return failed, nil
+}
+
+func syncLabels() error {
+	if labelSync.allProjects && len(labelSync.toProjects) > 0 {
+		return errors.New("'--to_project' and '--all_projects' are exclusive")
+	}
+
+	src, err := loadContext(labelSync.fromContext)
+	if err != nil {
+		return err
+	}
+	dst, err := loadContext(labelSync.toContext)
+	if err != nil {
+		return err
+	}
+	sameContext := labelSync.fromContext == labelSync.toContext
+
+	from := &labelScope{ctx: src, project: labelSync.fromProject}
+	if from.project != "" {
+		if from.projectID, err = contextProjectID(src, from.project); err != nil {
+			return err
+		}
+	}
+	labels, err := listLabels(src, from.projectID)
+	if err != nil {
+		return err
+	}
+	if len(labels) == 0 {
+		return fmt.Errorf("no labels in %s", from)
+	}
+
+	var scopes []*labelScope
+	switch {
+	case labelSync.allProjects:
+		projects, err := listContextProjects(dst)
+		if err != nil {
+			return err
+		}
+		for _, p := range projects {
+			if sameContext && p.ProjectID == from.projectID {
+				continue
+			}
+			scopes = append(scopes, &labelScope{dst, p.Name, p.ProjectID})
+		}
+	case len(labelSync.toProjects) > 0:
+		for _, name := range labelSync.toProjects {
+			id, err := contextProjectID(dst, name)
+			if err != nil {
+				return err
+			}
+			scopes = append(scopes, &labelScope{dst, name, id})
+		}
+	default:
+		scopes = append(scopes, &labelScope{ctx: dst})
+	}
+
+	for _, s := range scopes {
+		if sameContext && s.projectID == from.projectID {
+			return fmt.Errorf("can not copy the labels of %s to itself", from)
+		}
+	}
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SCOPE\tLABEL\tRESULT")
+	for _, s := range scopes {
+		n, err := syncScope(w, s, labels, labelSync.dryRun)
+		if err != nil {
+			w.Flush()
+			return err
+		}
+		failed += n
+	}
+	w.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d labels failed to sync", failed)
+	}
+	return nil
+}
@@ -30,7 +30,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var loginURL string
+// loginBase is the base URL of Harbor to log in.
+var loginBase string
 
 // loginCmd represents the login command
 var loginCmd = &cobra.Command{
@@ -40,7 +41,7 @@ var loginCmd = &cobra.Command{
 
 NOTE: each login will update conf/.cookie.yaml`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		loginURL = utils.URLGen("/login")
+		loginBase = utils.URLGen("")
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		loginHarbor()
@@ -81,21 +82,23 @@ func loginHarbor() {
 		fmt.Println("WARNING! Using --password via the CLI is insecure.")
 	}
 
-	targetURL := loginURL
+	req, targetURL, err := utils.LoginRequest(utils.AgentGet(), loginBase)
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
 	fmt.Println("==> POST", targetURL)
 
-	utils.AgentGet().Post(targetURL).
-		Set("Content-Type", "application/x-www-form-urlencoded;param=value").
-		// NOTE:
-		// After some experiments, conclude that the value of Cookie has two forms:
-		// 1. Cookie:rem-username=admin; harbor-lang=zh-cn; beegosessionID=720210**3d76d6
-		// 2. Cookie:rem-username=admin; harbor-lang=zh-cn;
-		//
-		// The fist one reuses the value of beegosessionID in Set-Cookie from response headers.
-		// The second one is equivalent to a fresh login.
-		//
-		// Taking the second form just for long-live coding.
-		Set("Cookie", "harbor-lang=zh-cn").
+	// NOTE:
+	// After some experiments, conclude that the value of Cookie has two forms:
+	// 1. Cookie:rem-username=admin; harbor-lang=zh-cn; beegosessionID=720210**3d76d6
+	// 2. Cookie:rem-username=admin; harbor-lang=zh-cn;
+	//
+	// The fist one reuses the value of beegosessionID in Set-Cookie from response headers.
+	// The second one is equivalent to a fresh login.
+	//
+	// Taking the second form just for long-live coding, which LoginRequest sets.
+	req.
 		Send("principal=" + li.username + "&password=" + url.QueryEscape(li.password)).
 		End(loginProc)
 }
@@ -112,7 +115,7 @@ func loginProc(resp gorequest.Response, body string, errs []error) {
 	cookies := (*http.Response)(resp).Cookies()
 	fmt.Println("<== Cookies:", cookies)
 
-	sid, err := utils.CookieFilter(cookies, "beegosessionID")
+	sid, err := utils.CookieFilter(cookies, utils.SessionCookieName(loginBase))
 	if err != nil {
 		fmt.Println(err)
 		return
@@ -71,4 +71,5 @@ func logoutProc(resp gorequest.Response, body string, errs []error) {
 	fmt.Println("<== Rsp Body:", body)
 
 	utils.CookieClean()
+	utils.CacheClean()
 }
@@ -23,10 +23,12 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"strconv"
 
 	"github.com/moooofly/harborctl/utils"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var projectURL string
@@ -53,6 +55,7 @@ func init() {
 	initProjectUpdate()
 	initProjectList()
 	initProjectCheck()
+	initProjectTemplate()
 }
 
 // projectGetCmd represents the get command
@@ -74,10 +77,10 @@ var prjGet struct {
 func initProjectGet() {
 	projectCmd.AddCommand(projectGetCmd)
 
-	projectGetCmd.Flags().Int64VarP(&prjGet.projectID,
+	projectGetCmd.Flags().VarP(newIDValue(&prjGet.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) Project ID of project which will be get.")
+		"j",
+		"(REQUIRED) Project ID or name of project which will be get.")
 	projectGetCmd.MarkFlagRequired("project_id")
 }
 
@@ -103,10 +106,10 @@ var prjDelete struct {
 func initProjectDelete() {
 	projectCmd.AddCommand(projectDeleteCmd)
 
-	projectDeleteCmd.Flags().Int64VarP(&prjDelete.projectID,
+	projectDeleteCmd.Flags().VarP(newIDValue(&prjDelete.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) Project ID of project which will be deleted.")
+		"j",
+		"(REQUIRED) Project ID or name of project which will be deleted.")
 	projectDeleteCmd.MarkFlagRequired("project_id")
 }
 
@@ -119,14 +122,24 @@ func projectDelete() {
 var projectCreateCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create a new project.",
-	Long:  `This endpoint is for user to create a new project.`,
+	Long: `This endpoint is for user to create a new project.
+
+NOTE: with '--template', the metadata, members, labels and replication policy of the template in the config file are applied to the project, and the metadata flags given explicitly override the ones of the template. See 'harborctl project template --help' for details.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if prjCreate.template != "" {
+			if err := projectCreateFromTemplate(cmd.Flags()); err != nil {
+				fmt.Println("error:", err)
+				os.Exit(1)
+			}
+			return
+		}
 		projectCreate()
 	},
 }
 
 var prjCreate struct {
 	ProjectName string `json:"project_name"`
+	template    string
 
 	// metadata
 	Public                                     int64  `json:"public"`
@@ -167,6 +180,11 @@ func initProjectCreate() {
 		"automatically_scan_images_on_push",
 		"a", false,
 		"Whether scan images automatically when pushing.")
+
+	projectCreateCmd.Flags().StringVarP(&prjCreate.template,
+		"template",
+		"", "",
+		"The name of the project template in the config file to apply.")
 }
 
 func projectCreate() {
@@ -211,10 +229,10 @@ var prjUpdate struct {
 func initProjectUpdate() {
 	projectCmd.AddCommand(projectUpdateCmd)
 
-	projectUpdateCmd.Flags().Int64VarP(&prjUpdate.projectID,
+	projectUpdateCmd.Flags().VarP(newIDValue(&prjUpdate.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) Project ID of project which will be update.")
+		"j",
+		"(REQUIRED) Project ID or name of project which will be update.")
 	projectUpdateCmd.MarkFlagRequired("project_id")
 
 	projectUpdateCmd.Flags().StringVarP(&prjUpdate.ProjectName,
@@ -358,3 +376,59 @@ func projectCheck() {
 	targetURL := projectURL + "?project_name=" + prjCheck.projectName
 	utils.Head(targetURL)
 }
+
+// projectBrief is a project in the list of projects.
+type projectBrief struct {
+	ProjectID int64  `json:"project_id"`
+	Name      string `json:"name"`
+	Metadata  struct {
+		Public             string `json:"public"`
+		EnableContentTrust string `json:"enable_content_trust"`
+	} `json:"metadata"`
+}
+
+// listProjects lists all projects which the user can see, page by page.
+func listProjects() ([]projectBrief, error) {
+	return listContextProjects(utils.CurrentContext())
+}
+
+// listContextProjects is like listProjects but lists the projects of ctx.
+func listContextProjects(ctx *utils.Context) ([]projectBrief, error) {
+	var projects []projectBrief
+	err := utils.GetPages("/api/projects", 100, func(pageURI string) (int, error) {
+		var page []projectBrief
+		if err := ctx.GetStruct(pageURI, &page); err != nil {
+			return 0, err
+		}
+		projects = append(projects, page...)
+		return len(page), nil
+	})
+	return projects, err
+}
+
+func projectCreateFromTemplate(fs *pflag.FlagSet) error {
+	tpl, err := loadProjectTemplate(prjCreate.template)
+	if err != nil {
+		return err
+	}
+
+	// the metadata flags given explicitly, and 'public' of the template defaults to the flag.
+	flags := make(map[string]string)
+	if fs.Changed("public") || tpl.Public == nil {
+		flags["public"] = strconv.FormatBool(prjCreate.Public == 1)
+	}
+	if fs.Changed("enable_content_trust") {
+		flags["enable_content_trust"] = strconv.FormatBool(prjCreate.EnablelontentTrust)
+	}
+	if fs.Changed("prevent_vulnerable_images_from_running") {
+		flags["prevent_vul"] = strconv.FormatBool(prjCreate.PreventVulnerableImagesFromRunning)
+	}
+	if fs.Changed("prevent_vulnerable_images_from_running_severity") {
+		flags["severity"] = prjCreate.PreventVulnerableImagesFromRunningSeverity
+	}
+	if fs.Changed("automatically_scan_images_on_push") {
+		flags["auto_scan"] = strconv.FormatBool(prjCreate.AutomaticallyScanImagesOnPush)
+	}
+
+	return createProjectFromTemplate(prjCreate.ProjectName, tpl, flags)
+}
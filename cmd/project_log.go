@@ -53,10 +53,10 @@ var prjLog struct {
 func init() {
 	projectCmd.AddCommand(projectLogCmd)
 
-	projectLogCmd.Flags().Int64VarP(&prjLog.projectID,
+	projectLogCmd.Flags().VarP(newIDValue(&prjLog.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) Relevant project ID.")
+		"j",
+		"(REQUIRED) Relevant project ID or name.")
 	projectLogCmd.MarkFlagRequired("project_id")
 
 	projectLogCmd.Flags().StringVarP(&prjLog.username,
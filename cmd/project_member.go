@@ -23,7 +23,9 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/moooofly/harborctl/utils"
 	"github.com/spf13/cobra"
@@ -81,21 +83,21 @@ var prjMemberCreate struct {
 func initMemberCreate() {
 	memberCmd.AddCommand(memberCreateCmd)
 
-	memberCreateCmd.Flags().Int64VarP(&prjMemberCreate.projectID,
+	memberCreateCmd.Flags().VarP(newIDValue(&prjMemberCreate.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) The ID of project.")
+		"j",
+		"(REQUIRED) The ID or name of project.")
 	memberCreateCmd.MarkFlagRequired("project_id")
 
-	memberCreateCmd.Flags().Int64VarP(&prjMemberCreate.roleID,
+	memberCreateCmd.Flags().VarP(newIDValue(&prjMemberCreate.roleID, "role"),
 		"role_id",
-		"r", 0,
-		"(REQUIRED) Role ID. 1 for projectAdmin, 2 for developer, 3 for guest.")
+		"r",
+		"(REQUIRED) Role ID or name. 1 for projectAdmin, 2 for developer, 3 for guest.")
 	memberCreateCmd.MarkFlagRequired("role_id")
 
-	memberCreateCmd.Flags().Int64VarP(&prjMemberCreate.userID,
+	memberCreateCmd.Flags().VarP(newIDValue(&prjMemberCreate.userID, "user"),
 		"user_id",
-		"i", 0,
+		"i",
 		`The ID of the user (wrong id cause nothing happend).
 Either user_id or username must be set, and user_id with high priority.`)
 
@@ -157,16 +159,16 @@ var prjMemberGet struct {
 func initMemberGet() {
 	memberCmd.AddCommand(memberGetCmd)
 
-	memberGetCmd.Flags().Int64VarP(&prjMemberGet.projectID,
+	memberGetCmd.Flags().VarP(newIDValue(&prjMemberGet.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) The ID of project.")
+		"j",
+		"(REQUIRED) The ID or name of project.")
 	memberGetCmd.MarkFlagRequired("project_id")
 
-	memberGetCmd.Flags().Int64VarP(&prjMemberGet.mID,
+	memberGetCmd.Flags().VarP(newIDValue(&prjMemberGet.mID, "member").inProject(&prjMemberGet.projectID),
 		"mid",
-		"m", 0,
-		"(REQUIRED) Member ID.")
+		"m",
+		"(REQUIRED) Member ID or name.")
 	memberGetCmd.MarkFlagRequired("mid")
 }
 
@@ -197,22 +199,22 @@ var prjMemberUpdate struct {
 func initMemberUpdate() {
 	memberCmd.AddCommand(memberUpdateCmd)
 
-	memberUpdateCmd.Flags().Int64VarP(&prjMemberUpdate.projectID,
+	memberUpdateCmd.Flags().VarP(newIDValue(&prjMemberUpdate.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) The ID of project.")
+		"j",
+		"(REQUIRED) The ID or name of project.")
 	memberUpdateCmd.MarkFlagRequired("project_id")
 
-	memberUpdateCmd.Flags().Int64VarP(&prjMemberUpdate.mID,
+	memberUpdateCmd.Flags().VarP(newIDValue(&prjMemberUpdate.mID, "member").inProject(&prjMemberUpdate.projectID),
 		"mid",
-		"m", 0,
-		"(REQUIRED) Member ID.")
+		"m",
+		"(REQUIRED) Member ID or name.")
 	memberUpdateCmd.MarkFlagRequired("mid")
 
-	memberUpdateCmd.Flags().Int64VarP(&prjMemberUpdate.RoleID,
+	memberUpdateCmd.Flags().VarP(newIDValue(&prjMemberUpdate.RoleID, "role"),
 		"role_id",
-		"r", 0,
-		"Role ID. 1 for projectAdmin, 2 for developer, 3 for guest.")
+		"r",
+		"Role ID or name. 1 for projectAdmin, 2 for developer, 3 for guest.")
 }
 
 func updateProjectMember() {
@@ -245,16 +247,16 @@ var prjMemberDelete struct {
 func initMemberDelete() {
 	memberCmd.AddCommand(memberDeleteCmd)
 
-	memberDeleteCmd.Flags().Int64VarP(&prjMemberDelete.projectID,
+	memberDeleteCmd.Flags().VarP(newIDValue(&prjMemberDelete.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) The ID of project.")
+		"j",
+		"(REQUIRED) The ID or name of project.")
 	memberDeleteCmd.MarkFlagRequired("project_id")
 
-	memberDeleteCmd.Flags().Int64VarP(&prjMemberDelete.mID,
+	memberDeleteCmd.Flags().VarP(newIDValue(&prjMemberDelete.mID, "member").inProject(&prjMemberDelete.projectID),
 		"mid",
-		"m", 0,
-		"(REQUIRED) The ID of member.")
+		"m",
+		"(REQUIRED) The ID or name of member.")
 	memberDeleteCmd.MarkFlagRequired("mid")
 }
 
@@ -282,10 +284,10 @@ var prjMemberList struct {
 func initMemberList() {
 	memberCmd.AddCommand(memberlistCmd)
 
-	memberlistCmd.Flags().Int64VarP(&prjMemberList.projectID,
+	memberlistCmd.Flags().VarP(newIDValue(&prjMemberList.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) The ID of project.")
+		"j",
+		"(REQUIRED) The ID or name of project.")
 	memberlistCmd.MarkFlagRequired("project_id")
 
 	memberlistCmd.Flags().StringVarP(&prjMemberList.entityname,
@@ -300,3 +302,44 @@ func listProjectMember() {
 
 	utils.Get(targetURL)
 }
+
+// projectMemberBrief is a member in the list of project members.
+type projectMemberBrief struct {
+	ID         int64  `json:"id"`
+	ProjectID  int64  `json:"project_id"`
+	EntityName string `json:"entity_name"`
+	EntityID   int64  `json:"entity_id"`
+	EntityType string `json:"entity_type"`
+	RoleID     int64  `json:"role_id"`
+	RoleName   string `json:"role_name"`
+}
+
+// listProjectMembers lists the members of a project, filtered by entityname
+// if it is not empty. NOTE: the filter of Harbor is a fuzzy match.
+func listProjectMembers(projectID int64, entityname string) ([]projectMemberBrief, error) {
+	targetURL := utils.URLGen("/api/projects/"+strconv.FormatInt(projectID, 10)+"/members") +
+		"?entityname=" + url.QueryEscape(entityname)
+
+	var members []projectMemberBrief
+	if err := utils.GetStruct(targetURL, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// projectRoles are the names of project roles by ID.
+var projectRoles = map[int64]string{
+	1: "projectAdmin",
+	2: "developer",
+	3: "guest",
+}
+
+// parseProjectRole parses a project role by name (case insensitive) or ID.
+func parseProjectRole(s string) (int64, error) {
+	for id, name := range projectRoles {
+		if strings.EqualFold(s, name) || s == strconv.FormatInt(id, 10) {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid role %q, must be one of [projectAdmin|developer|guest] or [1|2|3]", s)
+}
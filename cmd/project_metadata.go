@@ -75,10 +75,10 @@ var prjMetaAdd struct {
 func initMetadataAdd() {
 	metadataCmd.AddCommand(addMetaCmd)
 
-	addMetaCmd.Flags().Int64VarP(&prjMetaAdd.projectID,
+	addMetaCmd.Flags().VarP(newIDValue(&prjMetaAdd.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) Project ID of project which will be get.")
+		"j",
+		"(REQUIRED) Project ID or name of project which will be get.")
 	addMetaCmd.MarkFlagRequired("project_id")
 
 	// metadata
@@ -137,10 +137,10 @@ var prjMetaDel struct {
 func initMetadataDelete() {
 	metadataCmd.AddCommand(deleteMetaCmd)
 
-	deleteMetaCmd.Flags().Int64VarP(&prjMetaDel.projectID,
+	deleteMetaCmd.Flags().VarP(newIDValue(&prjMetaDel.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) Project ID of project which will be deleted.")
+		"j",
+		"(REQUIRED) Project ID or name of project which will be deleted.")
 	deleteMetaCmd.MarkFlagRequired("project_id")
 
 	deleteMetaCmd.Flags().StringVarP(&prjMetaDel.metaName,
@@ -176,10 +176,10 @@ var prjMetaGet struct {
 func initMetadataGet() {
 	metadataCmd.AddCommand(getMetaCmd)
 
-	getMetaCmd.Flags().Int64VarP(&prjMetaGet.projectID,
+	getMetaCmd.Flags().VarP(newIDValue(&prjMetaGet.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) Project ID of project which will be get.")
+		"j",
+		"(REQUIRED) Project ID or name of project which will be get.")
 	getMetaCmd.MarkFlagRequired("project_id")
 
 	getMetaCmd.Flags().StringVarP(&prjMetaGet.metaName,
@@ -214,10 +214,10 @@ var prjMetaList struct {
 func initMetadataList() {
 	metadataCmd.AddCommand(listMetaCmd)
 
-	listMetaCmd.Flags().Int64VarP(&prjMetaList.projectID,
+	listMetaCmd.Flags().VarP(newIDValue(&prjMetaList.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) Project ID of project which will be get.")
+		"j",
+		"(REQUIRED) Project ID or name of project which will be get.")
 	listMetaCmd.MarkFlagRequired("project_id")
 }
 
@@ -246,10 +246,10 @@ var prjMetaUpdate struct {
 func initMetadataUpdate() {
 	metadataCmd.AddCommand(updateMetaCmd)
 
-	updateMetaCmd.Flags().Int64VarP(&prjMetaUpdate.projectID,
+	updateMetaCmd.Flags().VarP(newIDValue(&prjMetaUpdate.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) Project ID of project which will be updated.")
+		"j",
+		"(REQUIRED) Project ID or name of project which will be updated.")
 	updateMetaCmd.MarkFlagRequired("project_id")
 
 	updateMetaCmd.Flags().StringVarP(&prjMetaUpdate.MetaName,
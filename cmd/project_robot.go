@@ -0,0 +1,495 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// robotCmd represents the robot command
+var robotCmd = &cobra.Command{
+	Use:   "robot",
+	Short: "'/projects/{project_id}/robots' API.",
+	Long: `The subcommand of '/projects/{project_id}/robots' hierarchy.
+
+Robot accounts are supported since Harbor 1.8, they log in with their tokens
+e.g. for the pushes and pulls of CI.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Use \"harborctl project robot --help\" for more information about this command.")
+	},
+}
+
+func init() {
+	projectCmd.AddCommand(robotCmd)
+
+	initRobotCreate()
+	initRobotList()
+	initRobotGet()
+	initRobotUpdate()
+	initRobotDisable()
+	initRobotDelete()
+}
+
+// robotBrief is a robot account of a project.
+type robotBrief struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ProjectID   int64  `json:"project_id"`
+	// ExpiresAt is the unix timestamp the token expires at, -1 for never.
+	ExpiresAt    int64  `json:"expires_at"`
+	Disabled     bool   `json:"disabled"`
+	CreationTime string `json:"creation_time"`
+}
+
+func robotsURL(projectID int64) string {
+	return utils.URLGen("/api/projects/" + strconv.FormatInt(projectID, 10) + "/robots")
+}
+
+func robotURL(projectID, robotID int64) string {
+	return robotsURL(projectID) + "/" + strconv.FormatInt(robotID, 10)
+}
+
+// listRobots lists the robot accounts of a project, page by page.
+func listRobots(projectID int64) ([]robotBrief, error) {
+	var robots []robotBrief
+	err := utils.GetPages(robotsURL(projectID), 100, func(pageURL string) (int, error) {
+		var page []robotBrief
+		if err := utils.GetStruct(pageURL, &page); err != nil {
+			return 0, err
+		}
+		robots = append(robots, page...)
+		return len(page), nil
+	})
+	return robots, err
+}
+
+// robotAccess is a permission of a robot account, of action on resource.
+type robotAccess struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+// parseRobotAccess parses the scopes of a robot account, each of which is
+// 'pull', 'push', 'chart-pull' or 'chart-push', and the first two may be
+// limited to a repository of the project like 'push:nginx'. A push implies a
+// pull, as Harbor UI grants.
+func parseRobotAccess(projectID int64, scopes []string) ([]robotAccess, error) {
+	project := "/project/" + strconv.FormatInt(projectID, 10)
+
+	var access []robotAccess
+	seen := make(map[robotAccess]bool)
+	add := func(resource string, actions ...string) {
+		for _, action := range actions {
+			a := robotAccess{resource, action}
+			if !seen[a] {
+				seen[a] = true
+				access = append(access, a)
+			}
+		}
+	}
+
+	for _, s := range scopes {
+		scope, repo := s, ""
+		if i := strings.Index(s, ":"); i >= 0 {
+			scope, repo = s[:i], s[i+1:]
+			if repo == "" {
+				return nil, fmt.Errorf("invalid scope %q, the repository is empty", s)
+			}
+		}
+
+		resource := project + "/repository"
+		if repo != "" {
+			resource += "/" + repo
+		}
+		switch scope {
+		case "pull":
+			add(resource, "pull")
+		case "push":
+			add(resource, "pull", "push")
+		case "chart-pull", "chart-push":
+			if repo != "" {
+				return nil, fmt.Errorf("invalid scope %q, a chart scope is of the whole project", s)
+			}
+			if scope == "chart-pull" {
+				add(project+"/helm-chart", "read")
+			} else {
+				add(project+"/helm-chart-version", "create")
+			}
+		default:
+			return nil, fmt.Errorf("invalid scope %q, must be one of [pull|push|chart-pull|chart-push]", s)
+		}
+	}
+	return access, nil
+}
+
+// durationExprRegexp matches the durations of ParseTimeExpr, e.g. '30d'.
+var durationExprRegexp = regexp.MustCompile(`^\d+[smhdwy]$`)
+
+// parseRobotExpiry parses when the token of a robot account expires, 'never'
+// or a time expression where a duration like '30d' is from now, instead of
+// ago as ParseTimeExpr takes, and returns the unix timestamp.
+func parseRobotExpiry(s string, now time.Time) (int64, error) {
+	if s == "never" {
+		return -1, nil
+	}
+
+	t, err := utils.ParseTimeExpr(s, now)
+	if err != nil {
+		return 0, err
+	}
+	if durationExprRegexp.MatchString(s) {
+		t = now.Add(now.Sub(t))
+	}
+	if !t.After(now) {
+		return 0, fmt.Errorf("expiry %q is not in the future", s)
+	}
+	return t.Unix(), nil
+}
+
+// formatRobotExpiry formats the expiry of a robot account.
+func formatRobotExpiry(expiresAt int64) string {
+	if expiresAt <= 0 {
+		return "never"
+	}
+	return time.Unix(expiresAt, 0).Format("2006-01-02 15:04")
+}
+
+// robotCreateCmd represents the create command
+var robotCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a robot account of a project, and print its token.",
+	Long: `This command creates a robot account of a project with the scopes, and prints the token of the account, which Harbor shows only this time.
+
+The scopes are:
+  - pull, push              pull or push (and pull) the repositories of the project
+  - pull:REPO, push:REPO    the same, but of the repository REPO of the project only
+  - chart-pull, chart-push  download or upload the charts of the project
+
+The account is named 'robot$NAME' by Harbor. The token expires by '--expires', e.g. '30d', '2019-12-31' or 'never', which is the token duration configured in Harbor by default, or if the Harbor version does not support it.
+
+The token can be written into a docker config.json by '--docker_config', merged with the existing credentials, and into a Kubernetes secret manifest of type 'kubernetes.io/dockerconfigjson' by '--k8s_secret', e.g.
+
+  harborctl project robot create -j library -n ci -a push --docker_config ~/.docker/config.json
+  harborctl project robot create -j library -n deploy -a pull --k8s_secret pull-secret.yaml --namespace prod`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := createRobot(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var robotCreate struct {
+	projectID    int64
+	name         string
+	description  string
+	access       []string
+	expires      string
+	dockerConfig string
+	k8sSecret    string
+	secretName   string
+	namespace    string
+}
+
+func initRobotCreate() {
+	robotCmd.AddCommand(robotCreateCmd)
+
+	robotCreateCmd.Flags().VarP(newIDValue(&robotCreate.projectID, "project"),
+		"project_id",
+		"j",
+		"(REQUIRED) The ID or name of project.")
+	robotCreateCmd.MarkFlagRequired("project_id")
+
+	robotCreateCmd.Flags().StringVarP(&robotCreate.name,
+		"name",
+		"n", "",
+		"(REQUIRED) The name of the robot account, which Harbor prefixes with 'robot$'.")
+	robotCreateCmd.MarkFlagRequired("name")
+
+	robotCreateCmd.Flags().StringVarP(&robotCreate.description,
+		"description",
+		"d", "",
+		"The description of the robot account.")
+
+	robotCreateCmd.Flags().StringSliceVarP(&robotCreate.access,
+		"access",
+		"a", nil,
+		"(REQUIRED) The scopes of the robot account, any of 'pull', 'push', 'pull:REPO', 'push:REPO', 'chart-pull' and 'chart-push'.")
+	robotCreateCmd.MarkFlagRequired("access")
+
+	robotCreateCmd.Flags().StringVarP(&robotCreate.expires,
+		"expires",
+		"e", "",
+		"When the token expires, e.g. '30d', '2019-12-31' or 'never'. Default is the token duration configured in Harbor.")
+
+	robotCreateCmd.Flags().StringVar(&robotCreate.dockerConfig,
+		"docker_config", "",
+		"The docker config.json to write the token into, e.g. '~/.docker/config.json'.")
+
+	robotCreateCmd.Flags().StringVar(&robotCreate.k8sSecret,
+		"k8s_secret", "",
+		"The file to write a Kubernetes secret manifest of the token into.")
+
+	robotCreateCmd.Flags().StringVar(&robotCreate.secretName,
+		"secret_name", "",
+		"The name of the Kubernetes secret, default is derived from the name of the robot account.")
+
+	robotCreateCmd.Flags().StringVar(&robotCreate.namespace,
+		"namespace", "",
+		"The namespace of the Kubernetes secret.")
+}
+
+func createRobot() error {
+	access, err := parseRobotAccess(robotCreate.projectID, robotCreate.access)
+	if err != nil {
+		return err
+	}
+
+	req := struct {
+		Name        string        `json:"name"`
+		Description string        `json:"description"`
+		ExpiresAt   int64         `json:"expires_at,omitempty"`
+		Access      []robotAccess `json:"access"`
+	}{Name: robotCreate.name, Description: robotCreate.description, Access: access}
+
+	if robotCreate.expires != "" {
+		if req.ExpiresAt, err = parseRobotExpiry(robotCreate.expires, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	resp, body, err := utils.Send("POST", robotsURL(robotCreate.projectID), &req)
+	if err != nil {
+		return err
+	}
+
+	var robot struct {
+		Name  string `json:"name"`
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &robot); err != nil {
+		return fmt.Errorf("invalid response of creating the robot account: %v", err)
+	}
+	if robot.Token == "" {
+		return errors.New("no token in the response of creating the robot account")
+	}
+
+	fmt.Printf("Created robot account %s (id: %s).\n", robot.Name, path.Base(resp.Header.Get("Location")))
+	fmt.Println("Token (shown only this time, keep it safe):")
+	fmt.Println(robot.Token)
+
+	registry := viper.GetString("address")
+	if robotCreate.dockerConfig != "" {
+		if err := writeDockerConfig(robotCreate.dockerConfig, registry, robot.Name, robot.Token); err != nil {
+			return err
+		}
+		fmt.Println("Written the token into", robotCreate.dockerConfig)
+	}
+	if robotCreate.k8sSecret != "" {
+		name := robotCreate.secretName
+		if name == "" {
+			name = secretNameOf(robot.Name)
+		}
+		if err := writeK8sSecret(robotCreate.k8sSecret, name, robotCreate.namespace, registry, robot.Name, robot.Token); err != nil {
+			return err
+		}
+		fmt.Printf("Written the secret %s into %s\n", name, robotCreate.k8sSecret)
+	}
+	return nil
+}
+
+// robotListCmd represents the list command
+var robotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the robot accounts of a project.",
+	Long:  `This command lists the robot accounts of a project, with their status and expiry.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := listProjectRobots(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var robotList struct {
+	projectID int64
+}
+
+func initRobotList() {
+	robotCmd.AddCommand(robotListCmd)
+
+	robotListCmd.Flags().VarP(newIDValue(&robotList.projectID, "project"),
+		"project_id",
+		"j",
+		"(REQUIRED) The ID or name of project.")
+	robotListCmd.MarkFlagRequired("project_id")
+}
+
+func listProjectRobots() error {
+	robots, err := listRobots(robotList.projectID)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSTATUS\tEXPIRES\tDESCRIPTION")
+	for _, r := range robots {
+		status := "enabled"
+		if r.Disabled {
+			status = "disabled"
+		}
+		if r.ExpiresAt > 0 && time.Unix(r.ExpiresAt, 0).Before(time.Now()) {
+			status += ", expired"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", r.ID, r.Name, status, formatRobotExpiry(r.ExpiresAt), r.Description)
+	}
+	return w.Flush()
+}
+
+// robotGetCmd represents the get command
+var robotGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Get a robot account of a project.",
+	Long:  `This endpoint returns a robot account of a project, with its permissions.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		utils.Get(robotURL(robotGet.projectID, robotGet.robotID))
+	},
+}
+
+var robotGet struct {
+	projectID int64
+	robotID   int64
+}
+
+func initRobotGet() {
+	robotCmd.AddCommand(robotGetCmd)
+	addRobotFlags(robotGetCmd, &robotGet.projectID, &robotGet.robotID)
+}
+
+// addRobotFlags adds the required flags of the project and the robot account.
+func addRobotFlags(cmd *cobra.Command, projectID, robotID *int64) {
+	cmd.Flags().VarP(newIDValue(projectID, "project"),
+		"project_id",
+		"j",
+		"(REQUIRED) The ID or name of project.")
+	cmd.MarkFlagRequired("project_id")
+
+	cmd.Flags().VarP(newIDValue(robotID, "robot").inProject(projectID),
+		"robot_id",
+		"r",
+		"(REQUIRED) The ID or name of the robot account, with or without the prefix 'robot$'.")
+	cmd.MarkFlagRequired("robot_id")
+}
+
+// robotUpdateCmd represents the update command
+var robotUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update a robot account of a project.",
+	Long: `This command enables or disables a robot account of a project.
+
+NOTE: Harbor updates nothing else of a robot account, create a new one to change the permissions.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		updateRobot(robotUpdate.projectID, robotUpdate.robotID, robotUpdate.disabled)
+	},
+}
+
+var robotUpdate struct {
+	projectID int64
+	robotID   int64
+	disabled  bool
+}
+
+func initRobotUpdate() {
+	robotCmd.AddCommand(robotUpdateCmd)
+	addRobotFlags(robotUpdateCmd, &robotUpdate.projectID, &robotUpdate.robotID)
+
+	robotUpdateCmd.Flags().BoolVarP(&robotUpdate.disabled,
+		"disabled",
+		"d", false,
+		"(REQUIRED) Whether the robot account is disabled, e.g. '--disabled=false' to enable it.")
+	robotUpdateCmd.MarkFlagRequired("disabled")
+}
+
+func updateRobot(projectID, robotID int64, disabled bool) {
+	p, err := json.Marshal(&struct {
+		Disabled bool `json:"disabled"`
+	}{disabled})
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+	utils.Put(robotURL(projectID, robotID), string(p))
+}
+
+// robotDisableCmd represents the disable command
+var robotDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Disable a robot account of a project.",
+	Long: `This command disables a robot account of a project, whose token is rejected until enabled again by 'update --disabled=false'.
+
+It is the way to revoke a leaked token at once while keeping the account.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		updateRobot(robotDisable.projectID, robotDisable.robotID, true)
+	},
+}
+
+var robotDisable struct {
+	projectID int64
+	robotID   int64
+}
+
+func initRobotDisable() {
+	robotCmd.AddCommand(robotDisableCmd)
+	addRobotFlags(robotDisableCmd, &robotDisable.projectID, &robotDisable.robotID)
+}
+
+// robotDeleteCmd represents the delete command
+var robotDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a robot account of a project.",
+	Long:  `This endpoint deletes a robot account of a project, and its token is rejected from then on.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		utils.Delete(robotURL(robotDelete.projectID, robotDelete.robotID))
+	},
+}
+
+var robotDelete struct {
+	projectID int64
+	robotID   int64
+}
+
+func initRobotDelete() {
+	robotCmd.AddCommand(robotDeleteCmd)
+	addRobotFlags(robotDeleteCmd, &robotDelete.projectID, &robotDelete.robotID)
+}
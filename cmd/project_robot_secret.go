@@ -0,0 +1,138 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// dockerAuth is the credential of a registry in a docker config.json.
+type dockerAuth struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Auth     string `json:"auth"`
+}
+
+func newDockerAuth(username, password string) dockerAuth {
+	return dockerAuth{
+		Username: username,
+		Password: password,
+		Auth:     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+	}
+}
+
+// writeDockerConfig writes the credential of registry into the docker config
+// file, keeping the other contents of it.
+func writeDockerConfig(file, registry, username, password string) error {
+	file, err := homedir.Expand(file)
+	if err != nil {
+		return err
+	}
+
+	config := make(map[string]interface{})
+	if b, err := ioutil.ReadFile(file); err == nil {
+		if err := json.Unmarshal(b, &config); err != nil {
+			return fmt.Errorf("invalid docker config %s: %v", file, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	auths, _ := config["auths"].(map[string]interface{})
+	if auths == nil {
+		auths = make(map[string]interface{})
+	}
+	// NOTE: docker writes 'auth' only, as the username and password are in it.
+	auths[registry] = dockerAuth{Auth: newDockerAuth(username, password).Auth}
+	config["auths"] = auths
+
+	if store, ok := config["credsStore"].(string); ok && store != "" {
+		if helpers, _ := config["credHelpers"].(map[string]interface{}); helpers[registry] == nil {
+			fmt.Printf("WARNING! %s sets credsStore %q, so docker reads the credential of %s from it instead.\n", file, store, registry)
+		}
+	}
+
+	b, err := json.MarshalIndent(config, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(b, '\n'), 0600)
+}
+
+// k8sSecret is a Kubernetes secret manifest.
+type k8sSecret struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace,omitempty"`
+	} `yaml:"metadata"`
+	Type string            `yaml:"type"`
+	Data map[string]string `yaml:"data"`
+}
+
+// invalidSecretNameRegexp matches the characters not allowed in the name of a
+// Kubernetes object.
+var invalidSecretNameRegexp = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// secretNameOf derives the name of a Kubernetes secret from the name of a
+// robot account, e.g. 'robot-ci' from 'robot$ci'.
+func secretNameOf(robot string) string {
+	return strings.Trim(invalidSecretNameRegexp.ReplaceAllString(strings.ToLower(robot), "-"), "-.")
+}
+
+// writeK8sSecret writes a secret manifest of type 'kubernetes.io/dockerconfigjson'
+// with the credential of registry into file, for the 'imagePullSecrets' of pods.
+func writeK8sSecret(file, name, namespace, registry, username, password string) error {
+	config, err := json.Marshal(map[string]interface{}{
+		"auths": map[string]dockerAuth{registry: newDockerAuth(username, password)},
+	})
+	if err != nil {
+		return err
+	}
+
+	secret := k8sSecret{APIVersion: "v1", Kind: "Secret", Type: "kubernetes.io/dockerconfigjson"}
+	secret.Metadata.Name = name
+	secret.Metadata.Namespace = namespace
+	secret.Data = map[string]string{".dockerconfigjson": base64.StdEncoding.EncodeToString(config)}
+
+	b, err := yaml.Marshal(&secret)
+	if err != nil {
+		return err
+	}
+	file, err = homedir.Expand(file)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, b, 0600)
+}
@@ -0,0 +1,341 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// projectTemplate is a named template of projects under 'project_templates'
+// in the config file, see 'harborctl project template --help' for an example.
+type projectTemplate struct {
+	Public                                     *bool  `mapstructure:"public"`
+	EnableContentTrust                         *bool  `mapstructure:"enable_content_trust"`
+	PreventVulnerableImagesFromRunning         *bool  `mapstructure:"prevent_vulnerable_images_from_running"`
+	PreventVulnerableImagesFromRunningSeverity string `mapstructure:"prevent_vulnerable_images_from_running_severity"`
+	AutomaticallyScanImagesOnPush              *bool  `mapstructure:"automatically_scan_images_on_push"`
+
+	Members []struct {
+		Username    string `mapstructure:"username"`
+		GroupID     int64  `mapstructure:"group_id"`
+		GroupName   string `mapstructure:"group_name"`
+		LdapGroupDn string `mapstructure:"ldap_group_dn"`
+		Role        string `mapstructure:"role"`
+	} `mapstructure:"members"`
+
+	Labels []struct {
+		Name        string `mapstructure:"name"`
+		Description string `mapstructure:"description"`
+		Color       string `mapstructure:"color"`
+	} `mapstructure:"labels"`
+
+	Replication *projectTemplateReplication `mapstructure:"replication"`
+}
+
+// projectTemplateReplication is the replication policy of a project template.
+type projectTemplateReplication struct {
+	Name                      string `mapstructure:"name"`
+	Description               string `mapstructure:"description"`
+	EndpointName              string `mapstructure:"endpoint_name"`
+	TriggerKind               string `mapstructure:"trigger_kind"`
+	ReplicateExistingImageNow bool   `mapstructure:"replicate_existing_image_now"`
+	ReplicateDeletion         bool   `mapstructure:"replicate_deletion"`
+	RepoNameAsFilter          string `mapstructure:"repo_name_as_filter"`
+	TagNameAsFilter           string `mapstructure:"tag_name_as_filter"`
+}
+
+// loadProjectTemplate loads the template name from the config file, and
+// validates it.
+func loadProjectTemplate(name string) (*projectTemplate, error) {
+	key := "project_templates." + name
+	if !viper.IsSet(key) {
+		return nil, fmt.Errorf("no project template %q in config file %s", name, viper.ConfigFileUsed())
+	}
+
+	var tpl projectTemplate
+	if err := viper.UnmarshalKey(key, &tpl); err != nil {
+		return nil, fmt.Errorf("malformed project template %q: %v", name, err)
+	}
+
+	for i, m := range tpl.Members {
+		if m.Username == "" && m.GroupID == 0 && m.GroupName == "" && m.LdapGroupDn == "" {
+			return nil, fmt.Errorf("project template %q: member %d has neither username nor group", name, i+1)
+		}
+		if _, err := parseProjectRole(m.Role); err != nil {
+			return nil, fmt.Errorf("project template %q: member %d: %v", name, i+1, err)
+		}
+	}
+	for i, l := range tpl.Labels {
+		if l.Name == "" {
+			return nil, fmt.Errorf("project template %q: label %d has no name", name, i+1)
+		}
+	}
+	if r := tpl.Replication; r != nil {
+		if r.EndpointName == "" {
+			return nil, fmt.Errorf("project template %q: replication has no endpoint_name", name)
+		}
+		switch r.TriggerKind {
+		case "":
+			r.TriggerKind = "Manual"
+		case "Manual", "Immediate":
+		default:
+			return nil, fmt.Errorf("project template %q: replication trigger_kind must be one of [Manual|Immediate]", name)
+		}
+		if r.Name == "" {
+			r.Name = "{project}-to-" + r.EndpointName
+		}
+	}
+
+	return &tpl, nil
+}
+
+// metadata returns the metadata of projects, with the values of flags
+// overriding the template.
+func (tpl *projectTemplate) metadata(flags map[string]string) map[string]string {
+	md := make(map[string]string)
+	setBool := func(key string, b *bool) {
+		if b != nil {
+			md[key] = strconv.FormatBool(*b)
+		}
+	}
+	setBool("public", tpl.Public)
+	setBool("enable_content_trust", tpl.EnableContentTrust)
+	setBool("prevent_vul", tpl.PreventVulnerableImagesFromRunning)
+	setBool("auto_scan", tpl.AutomaticallyScanImagesOnPush)
+	if tpl.PreventVulnerableImagesFromRunningSeverity != "" {
+		md["severity"] = tpl.PreventVulnerableImagesFromRunningSeverity
+	}
+
+	for k, v := range flags {
+		md[k] = v
+	}
+	return md
+}
+
+type templateMemberUser struct {
+	Username string `json:"username"`
+}
+
+type templateMemberGroup struct {
+	ID          int64  `json:"id,omitempty"`
+	GroupName   string `json:"group_name,omitempty"`
+	GroupType   int64  `json:"group_type,omitempty"`
+	LdapGroupDn string `json:"ldap_group_dn,omitempty"`
+}
+
+// templateMember is a member of a project to add, either a user or a group.
+type templateMember struct {
+	RoleID int64                `json:"role_id"`
+	User   *templateMemberUser  `json:"member_user,omitempty"`
+	Group  *templateMemberGroup `json:"member_group,omitempty"`
+}
+
+// createProjectFromTemplate creates a project with the metadata of the
+// template, and then adds the members, labels and replication policy of it.
+// The steps are reported one by one, and the rest are still tried if one of
+// them fails.
+func createProjectFromTemplate(name string, tpl *projectTemplate, flags map[string]string) error {
+	md := tpl.metadata(flags)
+
+	body := struct {
+		ProjectName string            `json:"project_name"`
+		Public      int64             `json:"public"`
+		Metadata    map[string]string `json:"metadata"`
+	}{ProjectName: name, Metadata: md}
+	if md["public"] == "true" {
+		body.Public = 1
+	}
+
+	resp, _, err := utils.Send("POST", utils.URLGen("/api/projects"), body)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: Harbor responds the location of the project created, i.e. '/api/projects/{project_id}'.
+	projectID, err := strconv.ParseInt(path.Base(resp.Header.Get("Location")), 10, 64)
+	if err != nil {
+		if projectID, err = findProjectID(name); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("<== Project %s created, project_id: %d\n", name, projectID)
+
+	var failed int
+	report := func(what string, err error) {
+		if err != nil {
+			failed++
+			fmt.Printf("<== %s: failed, error: %v\n", what, err)
+		} else {
+			fmt.Printf("<== %s: ok\n", what)
+		}
+	}
+
+	membersURL := utils.URLGen("/api/projects/" + strconv.FormatInt(projectID, 10) + "/members")
+	for _, m := range tpl.Members {
+		roleID, _ := parseProjectRole(m.Role)
+
+		member := templateMember{RoleID: roleID}
+
+		what := "Member "
+		if m.Username != "" {
+			member.User = &templateMemberUser{Username: m.Username}
+			what += m.Username
+		} else {
+			// NOTE: group type 1 is LDAP group, the only type Harbor supports.
+			member.Group = &templateMemberGroup{ID: m.GroupID, GroupName: m.GroupName, GroupType: 1, LdapGroupDn: m.LdapGroupDn}
+			what += "group " + strings.TrimSpace(m.GroupName+" "+m.LdapGroupDn)
+		}
+
+		_, _, err := utils.Send("POST", membersURL, member)
+		report(what+" as "+projectRoles[roleID], err)
+	}
+
+	for _, l := range tpl.Labels {
+		label := struct {
+			Name        string `json:"name"`
+			Description string `json:"description,omitempty"`
+			Color       string `json:"color,omitempty"`
+			Scope       string `json:"scope"`
+			ProjectID   int64  `json:"project_id"`
+		}{l.Name, l.Description, l.Color, "p", projectID}
+
+		_, _, err := utils.Send("POST", utils.URLGen("/api/labels"), label)
+		report("Label "+l.Name, err)
+	}
+
+	if r := tpl.Replication; r != nil {
+		policyName := strings.Replace(r.Name, "{project}", name, -1)
+		report("Replication policy "+policyName, createTemplatePolicy(policyName, name, r))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d steps of the template failed", failed)
+	}
+	return nil
+}
+
+func createTemplatePolicy(policyName, projectName string, r *projectTemplateReplication) error {
+	var pinfo policyInfo
+	pinfo.Name = policyName
+	pinfo.Description = r.Description
+	pinfo.ReplicateExistingImageNow = r.ReplicateExistingImageNow
+	pinfo.ReplicateDeletion = r.ReplicateDeletion
+	pinfo.Trigger.TriggerKind = r.TriggerKind
+
+	if err := pinfo.resolve(projectName, r.EndpointName); err != nil {
+		return err
+	}
+
+	if r.RepoNameAsFilter != "" {
+		pinfo.Filters = append(pinfo.Filters, filter{FilterKind: "repository", Value: r.RepoNameAsFilter})
+	}
+	if r.TagNameAsFilter != "" {
+		pinfo.Filters = append(pinfo.Filters, filter{FilterKind: "tag", Value: r.TagNameAsFilter})
+	}
+
+	_, _, err := utils.Send("POST", utils.URLGen("/api/policies/replication"), &pinfo)
+	return err
+}
+
+// findProjectID finds the ID of the project by its exact name.
+func findProjectID(name string) (int64, error) {
+	return resolveName("project", name, 0)
+}
+
+// projectTemplateCmd represents the template command
+var projectTemplateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "List the project templates in the config file.",
+	Long: `This command lists the project templates under 'project_templates' in the config file, which can be applied by 'project create --template'.
+
+A template bundles the metadata, the members (users or groups) with roles, the project labels and an optional replication policy of projects, e.g.
+
+project_templates:
+  team-default:
+    public: false
+    enable_content_trust: true
+    prevent_vulnerable_images_from_running: true
+    prevent_vulnerable_images_from_running_severity: high
+    automatically_scan_images_on_push: true
+    members:
+      - username: alice
+        role: projectAdmin
+      - ldap_group_dn: cn=devs,ou=groups,dc=example,dc=com
+        role: developer
+    labels:
+      - name: team
+        color: "#A9B6BE"
+    replication:
+      name: "{project}-to-dr"    # '{project}' is replaced by the project name
+      endpoint_name: dr
+      trigger_kind: Immediate    # 'Manual' or 'Immediate'
+      replicate_deletion: true`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := listProjectTemplates(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func initProjectTemplate() {
+	projectCmd.AddCommand(projectTemplateCmd)
+}
+
+func listProjectTemplates() error {
+	var names []string
+	for name := range viper.GetStringMap("project_templates") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TEMPLATE\tMETADATA\tMEMBERS\tLABELS\tREPLICATION")
+	for _, name := range names {
+		tpl, err := loadProjectTemplate(name)
+		if err != nil {
+			return err
+		}
+
+		var md []string
+		for k, v := range tpl.metadata(nil) {
+			md = append(md, k+"="+v)
+		}
+		sort.Strings(md)
+
+		replication := "-"
+		if tpl.Replication != nil {
+			replication = tpl.Replication.EndpointName + " (" + tpl.Replication.TriggerKind + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", name, strings.Join(md, ","), len(tpl.Members), len(tpl.Labels), replication)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,425 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// webhookCmd represents the webhook command
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "'/projects/{project_id}/webhook' API.",
+	Long: `The subcommand of '/projects/{project_id}/webhook' hierarchy.
+
+Webhook policies are supported since Harbor 1.9, they notify the endpoints of the events of a project, e.g. the pushes of images.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Use \"harborctl project webhook --help\" for more information about this command.")
+	},
+}
+
+func init() {
+	projectCmd.AddCommand(webhookCmd)
+
+	initWebhookCreate()
+	initWebhookList()
+	initWebhookUpdate()
+	initWebhookDelete()
+}
+
+// webhookEvents are the event types of webhooks by the names taken by the
+// commands, in Harbor 1.x and 2.x.
+var webhookEvents = map[string][2]string{
+	"push":           {"pushImage", "PUSH_ARTIFACT"},
+	"pull":           {"pullImage", "PULL_ARTIFACT"},
+	"delete":         {"deleteImage", "DELETE_ARTIFACT"},
+	"chart-upload":   {"uploadChart", "UPLOAD_CHART"},
+	"chart-download": {"downloadChart", "DOWNLOAD_CHART"},
+	"chart-delete":   {"deleteChart", "DELETE_CHART"},
+	"scan-completed": {"scanningCompleted", "SCANNING_COMPLETED"},
+	"scan-failed":    {"scanningFailed", "SCANNING_FAILED"},
+}
+
+// webhookEventNames returns the names of webhookEvents in order.
+func webhookEventNames() string {
+	var names []string
+	for name := range webhookEvents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "|")
+}
+
+// webhookGeneration returns the index of the event types of the current
+// Harbor in webhookEvents.
+func webhookGeneration() int {
+	if utils.APIVersion(utils.URLGen("")) == utils.APIv2 {
+		return 1
+	}
+	return 0
+}
+
+// parseWebhookEvents parses the event types by name, and the others are
+// taken as the event types of Harbor as is, e.g. 'QUOTA_EXCEED'.
+func parseWebhookEvents(names []string) []string {
+	gen := webhookGeneration()
+
+	var events []string
+	for _, name := range names {
+		if e, ok := webhookEvents[name]; ok {
+			events = append(events, e[gen])
+		} else {
+			events = append(events, name)
+		}
+	}
+	return events
+}
+
+// webhookEventName returns the name of the event type of Harbor.
+func webhookEventName(event string) string {
+	for name, e := range webhookEvents {
+		if event == e[0] || event == e[1] {
+			return name
+		}
+	}
+	return event
+}
+
+// webhookTarget is an endpoint notified by a webhook policy.
+type webhookTarget struct {
+	// Type is the notify type, 'http' or 'slack' since Harbor 2.0.
+	Type           string `json:"type"`
+	Address        string `json:"address"`
+	AuthHeader     string `json:"auth_header,omitempty"`
+	SkipCertVerify bool   `json:"skip_cert_verify"`
+}
+
+// webhookPolicy is a webhook policy of a project.
+type webhookPolicy struct {
+	ID           int64           `json:"id,omitempty"`
+	Name         string          `json:"name"`
+	Description  string          `json:"description"`
+	ProjectID    int64           `json:"project_id,omitempty"`
+	Targets      []webhookTarget `json:"targets"`
+	EventTypes   []string        `json:"event_types"`
+	Enabled      bool            `json:"enabled"`
+	Creator      string          `json:"creator,omitempty"`
+	CreationTime string          `json:"creation_time,omitempty"`
+	UpdateTime   string          `json:"update_time,omitempty"`
+}
+
+func webhookURL(projectID int64, uri string) string {
+	return utils.URLGen("/api/projects/" + strconv.FormatInt(projectID, 10) + "/webhook" + uri)
+}
+
+func webhookPolicyURL(projectID, policyID int64) string {
+	return webhookURL(projectID, "/policies/"+strconv.FormatInt(policyID, 10))
+}
+
+// listWebhookPolicies lists the webhook policies of a project, page by page.
+func listWebhookPolicies(projectID int64) ([]webhookPolicy, error) {
+	var policies []webhookPolicy
+	err := utils.GetPages(webhookURL(projectID, "/policies"), 100, func(pageURL string) (int, error) {
+		var page []webhookPolicy
+		if err := utils.GetStruct(pageURL, &page); err != nil {
+			return 0, err
+		}
+		policies = append(policies, page...)
+		return len(page), nil
+	})
+	return policies, err
+}
+
+// webhookFlags are the flags of a webhook policy to create or update.
+type webhookFlags struct {
+	name           string
+	description    string
+	endpoint       string
+	events         []string
+	authHeader     string
+	skipCertVerify bool
+	notifyType     string
+	enabled        bool
+}
+
+func addWebhookFlags(cmd *cobra.Command, f *webhookFlags, required bool) {
+	prefix := ""
+	if required {
+		prefix = "(REQUIRED) "
+	}
+
+	cmd.Flags().StringVarP(&f.name,
+		"name",
+		"n", "",
+		prefix+"The name of the webhook policy.")
+
+	cmd.Flags().StringVarP(&f.description,
+		"description",
+		"d", "",
+		"The description of the webhook policy.")
+
+	cmd.Flags().StringVarP(&f.endpoint,
+		"endpoint",
+		"e", "",
+		prefix+"The endpoint URL notified of the events.")
+
+	cmd.Flags().StringSliceVarP(&f.events,
+		"events",
+		"t", nil,
+		prefix+"The event types notified, any of ["+webhookEventNames()+"], or an event type of Harbor as is.")
+
+	cmd.Flags().StringVar(&f.authHeader,
+		"auth_header", "",
+		"The value of the 'Authorization' header sent to the endpoint, e.g. 'Bearer <token>'.")
+
+	cmd.Flags().BoolVar(&f.skipCertVerify,
+		"skip_cert_verify", false,
+		"Whether to skip verifying the certificate of the endpoint.")
+
+	cmd.Flags().StringVar(&f.notifyType,
+		"notify_type", "http",
+		"The notify type, 'http' or 'slack' (since Harbor 2.0).")
+
+	cmd.Flags().BoolVar(&f.enabled,
+		"enabled", true,
+		"Whether the webhook policy is enabled.")
+
+	if required {
+		cmd.MarkFlagRequired("name")
+		cmd.MarkFlagRequired("endpoint")
+		cmd.MarkFlagRequired("events")
+	}
+}
+
+// apply applies the flags changed in fs to p, which has one target at most.
+func (f *webhookFlags) apply(fs *pflag.FlagSet, p *webhookPolicy) {
+	if len(p.Targets) == 0 {
+		p.Targets = []webhookTarget{{Type: "http"}}
+	}
+	t := &p.Targets[0]
+
+	if fs.Changed("name") {
+		p.Name = f.name
+	}
+	if fs.Changed("description") {
+		p.Description = f.description
+	}
+	if fs.Changed("endpoint") {
+		t.Address = f.endpoint
+	}
+	if fs.Changed("events") {
+		p.EventTypes = parseWebhookEvents(f.events)
+	}
+	if fs.Changed("auth_header") {
+		t.AuthHeader = f.authHeader
+	}
+	if fs.Changed("skip_cert_verify") {
+		t.SkipCertVerify = f.skipCertVerify
+	}
+	if fs.Changed("notify_type") || t.Type == "" {
+		t.Type = f.notifyType
+	}
+	if fs.Changed("enabled") {
+		p.Enabled = f.enabled
+	}
+}
+
+// webhookCreateCmd represents the create command
+var webhookCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a webhook policy of a project.",
+	Long: `This command creates a webhook policy notifying the endpoint of the events of a project, e.g.
+
+  harborctl project webhook create -j library -n deploy -e https://ci.example.com/hooks/harbor -t push,scan-completed --auth_header 'Bearer <token>'`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := createWebhookPolicy(cmd.Flags()); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var webhookCreate struct {
+	projectID int64
+	webhookFlags
+}
+
+func initWebhookCreate() {
+	webhookCmd.AddCommand(webhookCreateCmd)
+
+	webhookCreateCmd.Flags().VarP(newIDValue(&webhookCreate.projectID, "project"),
+		"project_id",
+		"j",
+		"(REQUIRED) The ID or name of project.")
+	webhookCreateCmd.MarkFlagRequired("project_id")
+
+	addWebhookFlags(webhookCreateCmd, &webhookCreate.webhookFlags, true)
+}
+
+func createWebhookPolicy(fs *pflag.FlagSet) error {
+	p := webhookPolicy{ProjectID: webhookCreate.projectID, Enabled: true}
+	webhookCreate.apply(fs, &p)
+
+	resp, _, err := utils.Send("POST", webhookURL(webhookCreate.projectID, "/policies"), &p)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created webhook policy %s (id: %s).\n", p.Name, path.Base(resp.Header.Get("Location")))
+	return nil
+}
+
+// webhookListCmd represents the list command
+var webhookListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the webhook policies of a project.",
+	Long:  `This command lists the webhook policies of a project, with their event types and endpoints.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := listProjectWebhooks(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var webhookList struct {
+	projectID int64
+}
+
+func initWebhookList() {
+	webhookCmd.AddCommand(webhookListCmd)
+
+	webhookListCmd.Flags().VarP(newIDValue(&webhookList.projectID, "project"),
+		"project_id",
+		"j",
+		"(REQUIRED) The ID or name of project.")
+	webhookListCmd.MarkFlagRequired("project_id")
+}
+
+func listProjectWebhooks() error {
+	policies, err := listWebhookPolicies(webhookList.projectID)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tENABLED\tEVENTS\tENDPOINT")
+	for _, p := range policies {
+		var events []string
+		for _, e := range p.EventTypes {
+			events = append(events, webhookEventName(e))
+		}
+		var endpoints []string
+		for _, t := range p.Targets {
+			endpoints = append(endpoints, t.Address)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%t\t%s\t%s\n", p.ID, p.Name, p.Enabled, strings.Join(events, ","), strings.Join(endpoints, ","))
+	}
+	return w.Flush()
+}
+
+// addWebhookPolicyFlags adds the required flags of the project and the webhook
+// policy.
+func addWebhookPolicyFlags(cmd *cobra.Command, projectID, policyID *int64) {
+	cmd.Flags().VarP(newIDValue(projectID, "project"),
+		"project_id",
+		"j",
+		"(REQUIRED) The ID or name of project.")
+	cmd.MarkFlagRequired("project_id")
+
+	cmd.Flags().VarP(newIDValue(policyID, "webhook").inProject(projectID),
+		"webhook_id",
+		"i",
+		"(REQUIRED) The ID or name of the webhook policy.")
+	cmd.MarkFlagRequired("webhook_id")
+}
+
+// webhookUpdateCmd represents the update command
+var webhookUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update a webhook policy of a project.",
+	Long: `This command updates the fields given of a webhook policy of a project, and keeps the others, e.g.
+
+  harborctl project webhook update -j library -i deploy -t push,pull
+  harborctl project webhook update -j library -i deploy --enabled=false`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := updateWebhookPolicy(cmd.Flags()); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var webhookUpdate struct {
+	projectID int64
+	policyID  int64
+	webhookFlags
+}
+
+func initWebhookUpdate() {
+	webhookCmd.AddCommand(webhookUpdateCmd)
+	addWebhookPolicyFlags(webhookUpdateCmd, &webhookUpdate.projectID, &webhookUpdate.policyID)
+	addWebhookFlags(webhookUpdateCmd, &webhookUpdate.webhookFlags, false)
+}
+
+func updateWebhookPolicy(fs *pflag.FlagSet) error {
+	targetURL := webhookPolicyURL(webhookUpdate.projectID, webhookUpdate.policyID)
+
+	var p webhookPolicy
+	if err := utils.GetStruct(targetURL, &p); err != nil {
+		return err
+	}
+	webhookUpdate.apply(fs, &p)
+
+	if _, _, err := utils.Send("PUT", targetURL, &p); err != nil {
+		return err
+	}
+	fmt.Printf("Updated webhook policy %s (id: %d).\n", p.Name, p.ID)
+	return nil
+}
+
+// webhookDeleteCmd represents the delete command
+var webhookDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a webhook policy of a project.",
+	Long:  `This endpoint deletes a webhook policy of a project.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		utils.Delete(webhookPolicyURL(webhookDelete.projectID, webhookDelete.policyID))
+	},
+}
+
+var webhookDelete struct {
+	projectID int64
+	policyID  int64
+}
+
+func initWebhookDelete() {
+	webhookCmd.AddCommand(webhookDeleteCmd)
+	addWebhookPolicyFlags(webhookDeleteCmd, &webhookDelete.projectID, &webhookDelete.policyID)
+}
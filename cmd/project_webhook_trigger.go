@@ -0,0 +1,222 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	initWebhookTest()
+	initWebhookLastTrigger()
+}
+
+// webhookTestCmd represents the test command
+var webhookTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a test event to the endpoint of a webhook policy, or to an endpoint.",
+	Long: `This command asks Harbor to send a test event to the endpoint of the webhook policy, or to the endpoint given, e.g.
+
+  harborctl project webhook test -j library -i deploy
+  harborctl project webhook test -j library -e https://ci.example.com/hooks/harbor --auth_header 'Bearer <token>'
+
+It fails if the endpoint is not reachable from Harbor.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := testWebhook(cmd.Flags()); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var webhookTest struct {
+	projectID int64
+	policyID  int64
+	webhookFlags
+}
+
+func initWebhookTest() {
+	webhookCmd.AddCommand(webhookTestCmd)
+
+	webhookTestCmd.Flags().VarP(newIDValue(&webhookTest.projectID, "project"),
+		"project_id",
+		"j",
+		"(REQUIRED) The ID or name of project.")
+	webhookTestCmd.MarkFlagRequired("project_id")
+
+	webhookTestCmd.Flags().VarP(newIDValue(&webhookTest.policyID, "webhook").inProject(&webhookTest.projectID),
+		"webhook_id",
+		"i",
+		"The ID or name of the webhook policy to test. Either webhook_id or endpoint must be set.")
+
+	webhookTestCmd.Flags().StringVarP(&webhookTest.endpoint,
+		"endpoint",
+		"e", "",
+		"The endpoint URL to test. Either webhook_id or endpoint must be set.")
+
+	webhookTestCmd.Flags().StringVar(&webhookTest.authHeader,
+		"auth_header", "",
+		"The value of the 'Authorization' header sent to the endpoint.")
+
+	webhookTestCmd.Flags().BoolVar(&webhookTest.skipCertVerify,
+		"skip_cert_verify", false,
+		"Whether to skip verifying the certificate of the endpoint.")
+
+	webhookTestCmd.Flags().StringVar(&webhookTest.notifyType,
+		"notify_type", "http",
+		"The notify type, 'http' or 'slack' (since Harbor 2.0).")
+}
+
+func testWebhook(fs *pflag.FlagSet) error {
+	var p webhookPolicy
+	switch {
+	case fs.Changed("webhook_id") == fs.Changed("endpoint"):
+		return errors.New("either webhook_id or endpoint must be set")
+	case fs.Changed("webhook_id"):
+		if err := utils.GetStruct(webhookPolicyURL(webhookTest.projectID, webhookTest.policyID), &p); err != nil {
+			return err
+		}
+	}
+	webhookTest.apply(fs, &p)
+
+	if _, _, err := utils.Send("POST", webhookURL(webhookTest.projectID, "/policies/test"), &p); err != nil {
+		return err
+	}
+	for _, t := range p.Targets {
+		fmt.Println("Sent a test event to", t.Address)
+	}
+	return nil
+}
+
+// webhookLastTriggerCmd represents the last-trigger command
+var webhookLastTriggerCmd = &cobra.Command{
+	Use:   "last-trigger",
+	Short: "Show when the webhook policies of a project were last triggered, by event type.",
+	Long:  `This command shows when each webhook policy of a project was last triggered by each event type, with the status of the last delivery, which is one of 'pending', 'running', 'success', 'error' and 'stopped'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := showWebhookLastTrigger(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var webhookLastTrigger struct {
+	projectID int64
+}
+
+func initWebhookLastTrigger() {
+	webhookCmd.AddCommand(webhookLastTriggerCmd)
+
+	webhookLastTriggerCmd.Flags().VarP(newIDValue(&webhookLastTrigger.projectID, "project"),
+		"project_id",
+		"j",
+		"(REQUIRED) The ID or name of project.")
+	webhookLastTriggerCmd.MarkFlagRequired("project_id")
+}
+
+// webhookJob is the delivery of an event by a webhook policy.
+type webhookJob struct {
+	ID        int64  `json:"id"`
+	PolicyID  int64  `json:"policy_id"`
+	EventType string `json:"event_type"`
+	Status    string `json:"status"`
+}
+
+// lastWebhookJobs returns the last deliveries of a webhook policy by event
+// type.
+func lastWebhookJobs(projectID, policyID int64) (map[string]webhookJob, error) {
+	targetURL := webhookURL(projectID, "/jobs") + "?policy_id=" + strconv.FormatInt(policyID, 10)
+
+	last := make(map[string]webhookJob)
+	err := utils.GetPages(targetURL, 100, func(pageURL string) (int, error) {
+		var page []webhookJob
+		if err := utils.GetStruct(pageURL, &page); err != nil {
+			return 0, err
+		}
+		for _, j := range page {
+			if j.ID > last[j.EventType].ID {
+				last[j.EventType] = j
+			}
+		}
+		return len(page), nil
+	})
+	return last, err
+}
+
+// formatTriggerTime formats the time of Harbor, which is zero if never.
+func formatTriggerTime(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	if t.Year() <= 1 {
+		return "never"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+func showWebhookLastTrigger() error {
+	var triggers []struct {
+		PolicyName      string `json:"policy_name"`
+		EventType       string `json:"event_type"`
+		Enabled         bool   `json:"enabled"`
+		CreationTime    string `json:"creation_time"`
+		LastTriggerTime string `json:"last_trigger_time"`
+	}
+	if err := utils.GetStruct(webhookURL(webhookLastTrigger.projectID, "/lasttrigger"), &triggers); err != nil {
+		return err
+	}
+
+	policies, err := listWebhookPolicies(webhookLastTrigger.projectID)
+	if err != nil {
+		return err
+	}
+	jobs := make(map[string]map[string]webhookJob)
+	for _, p := range policies {
+		last, err := lastWebhookJobs(webhookLastTrigger.projectID, p.ID)
+		if err != nil {
+			return err
+		}
+		jobs[p.Name] = last
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "POLICY\tEVENT\tENABLED\tLAST TRIGGERED\tLAST DELIVERY")
+	for _, t := range triggers {
+		status := "-"
+		if j, ok := jobs[t.PolicyName][t.EventType]; ok {
+			status = strings.ToLower(j.Status)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\n",
+			t.PolicyName, webhookEventName(t.EventType), t.Enabled, formatTriggerTime(t.LastTriggerTime), status)
+	}
+	return w.Flush()
+}
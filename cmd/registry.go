@@ -99,10 +99,10 @@ var targetGet struct {
 func initTargetGet() {
 	targetCmd.AddCommand(targetGetCmd)
 
-	targetGetCmd.Flags().Int64VarP(&targetGet.ID,
+	targetGetCmd.Flags().VarP(newIDValue(&targetGet.ID, "target"),
 		"id",
-		"i", 0,
-		"(REQUIRED) The replication's target ID.")
+		"i",
+		"(REQUIRED) The replication's target ID or name.")
 	targetGetCmd.MarkFlagRequired("id")
 }
 
@@ -128,10 +128,10 @@ var targetDelete struct {
 func initTargetDelete() {
 	targetCmd.AddCommand(targetDeleteCmd)
 
-	targetDeleteCmd.Flags().Int64VarP(&targetDelete.ID,
+	targetDeleteCmd.Flags().VarP(newIDValue(&targetDelete.ID, "target"),
 		"id",
-		"i", 0,
-		"(REQUIRED) The replication's target ID.")
+		"i",
+		"(REQUIRED) The replication's target ID or name.")
 	targetDeleteCmd.MarkFlagRequired("id")
 }
 
@@ -227,10 +227,10 @@ var targetUpdate struct {
 func initTargetUpdate() {
 	targetCmd.AddCommand(targetUpdateCmd)
 
-	targetUpdateCmd.Flags().Int64VarP(&targetUpdate.ID,
+	targetUpdateCmd.Flags().VarP(newIDValue(&targetUpdate.ID, "target"),
 		"id",
-		"i", 0,
-		"(REQUIRED) The replication's target ID.")
+		"i",
+		"(REQUIRED) The replication's target ID or name.")
 	targetUpdateCmd.MarkFlagRequired("id")
 
 	targetUpdateCmd.Flags().StringVarP(&targetUpdate.Name,
@@ -0,0 +1,269 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// targetCheckAllCmd represents the check-all command
+var targetCheckAllCmd = &cobra.Command{
+	Use:   "check-all",
+	Short: "Ping all the replication targets and report their health.",
+	Long: `This command pings all the replication targets concurrently with their saved credentials, and reports the latency, the targets which are unreachable or reject the credentials, and the replication policies affected by them.
+
+It exits with status 1 if any target is unhealthy, so it can be used as a health check of cron. With '--watch', the check is repeated at the interval, and a status line is printed after each check. It exits with status 1 on the first check which fails, unless '--keep_going' is given, with which it is repeated until interrupted.
+
+The session of harborctl is checked before pinging, and a target whose ping is rejected for authentication is reported as 'auth_failed' only if the session is still valid, otherwise the command fails for the session.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if targetCheckAll.watch > 0 {
+			for {
+				fmt.Printf("==== %s\n", time.Now().Format(time.RFC3339))
+				healthy, err := checkAllTargets()
+				if err != nil {
+					fmt.Println("error:", err)
+				}
+				if err == nil && healthy {
+					fmt.Println("status: healthy")
+				} else {
+					fmt.Println("status: unhealthy")
+					if !targetCheckAll.keepGoing {
+						os.Exit(1)
+					}
+				}
+				time.Sleep(targetCheckAll.watch)
+				fmt.Println()
+			}
+		}
+
+		healthy, err := checkAllTargets()
+		if err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+		if !healthy {
+			os.Exit(1)
+		}
+	},
+}
+
+var targetCheckAll struct {
+	concurrency int
+	timeout     time.Duration
+	watch       time.Duration
+	keepGoing   bool
+}
+
+func init() {
+	targetCmd.AddCommand(targetCheckAllCmd)
+
+	targetCheckAllCmd.Flags().IntVarP(&targetCheckAll.concurrency,
+		"concurrency",
+		"c", 8,
+		"The number of targets pinged at the same time.")
+
+	targetCheckAllCmd.Flags().DurationVarP(&targetCheckAll.timeout,
+		"timeout",
+		"", 10*time.Second,
+		"The timeout of pinging a target.")
+
+	targetCheckAllCmd.Flags().DurationVarP(&targetCheckAll.watch,
+		"watch",
+		"w", 0,
+		"Repeat the check at the interval, e.g. '1m'.")
+
+	targetCheckAllCmd.Flags().BoolVarP(&targetCheckAll.keepGoing,
+		"keep_going",
+		"", false,
+		"Keep checking after an unhealthy check with '--watch', rather than exiting with status 1.")
+}
+
+// targetBrief is a replication target without credentials.
+type targetBrief struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+	Username string `json:"username"`
+	Insecure bool   `json:"insecure"`
+}
+
+func listTargets() ([]targetBrief, error) {
+	var targets []targetBrief
+	if err := utils.GetStruct(utils.URLGen("/api/targets"), &targets); err != nil {
+		return nil, err
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ID < targets[j].ID })
+	return targets, nil
+}
+
+// targetHealth is the result of pinging a target.
+type targetHealth struct {
+	status  string
+	latency time.Duration
+	message string
+}
+
+func (h *targetHealth) ok() bool {
+	return h.status == "ok"
+}
+
+// pingSavedTarget pings the target with its saved endpoint and credentials.
+// It is safe for concurrent use, as the request is sent by its own agent and
+// the state shared by the requests in utils is guarded.
+func pingSavedTarget(id int64, timeout time.Duration) *targetHealth {
+	body := struct {
+		ID int64 `json:"id"`
+	}{id}
+
+	start := time.Now()
+	_, _, err := utils.SendWith(utils.NewAgent().Timeout(timeout), "POST", utils.URLGen("/api/targets/ping"), &body)
+	h := &targetHealth{status: "ok", latency: time.Since(start)}
+
+	if e, ok := err.(*utils.StatusError); ok {
+		h.message = e.Body
+		if e.StatusCode == http.StatusUnauthorized {
+			h.status = "auth_failed"
+		} else {
+			h.status = "unreachable"
+		}
+	} else if err != nil {
+		h.status = "unreachable"
+		h.message = err.Error()
+	}
+	return h
+}
+
+// checkSession checks the session of harborctl to Harbor is valid, by getting
+// the current user.
+func checkSession() error {
+	var u struct {
+		UserID int64 `json:"user_id"`
+	}
+	err := utils.GetStruct(utils.URLGen("/api/users/current"), &u)
+	if utils.IsStatus(err, http.StatusUnauthorized) {
+		return errors.New("the session of harborctl is not valid, run 'harborctl login' again")
+	}
+	return err
+}
+
+// pingSavedTargets pings the targets concurrently.
+func pingSavedTargets(targets []targetBrief, concurrency int, timeout time.Duration) []*targetHealth {
+	results := make([]*targetHealth, len(targets))
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i := range targets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = pingSavedTarget(targets[i].ID, timeout)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// checkAllTargets prints the health of the targets, and reports whether all of
+// them are healthy.
+func checkAllTargets() (bool, error) {
+	if targetCheckAll.concurrency < 1 {
+		return false, errors.New("concurrency must be at least 1")
+	}
+
+	if err := checkSession(); err != nil {
+		return false, err
+	}
+
+	targets, err := listTargets()
+	if err != nil {
+		return false, err
+	}
+	if len(targets) == 0 {
+		fmt.Println("No replication target.")
+		return true, nil
+	}
+
+	results := pingSavedTargets(targets, targetCheckAll.concurrency, targetCheckAll.timeout)
+
+	// NOTE: a ping rejected by Harbor for the session expired is not of the
+	// target, which responds 401 too.
+	for _, h := range results {
+		if h.status == "auth_failed" {
+			if err := checkSession(); err != nil {
+				return false, err
+			}
+			break
+		}
+	}
+
+	var down []int
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tENDPOINT\tSTATUS\tLATENCY\tMESSAGE")
+	for i, t := range targets {
+		h := results[i]
+		if !h.ok() {
+			down = append(down, i)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Endpoint, h.status,
+			h.latency.Round(time.Millisecond), truncate(h.message, 60))
+	}
+	w.Flush()
+
+	if len(down) == 0 {
+		return true, nil
+	}
+
+	fmt.Printf("\n%d of %d targets are unhealthy, the affected policies:\n", len(down), len(targets))
+	for _, i := range down {
+		t := targets[i]
+
+		var policies []policyRequest
+		targetURL := utils.URLGen("/api/targets/" + strconv.FormatInt(t.ID, 10) + "/policies")
+		if err := utils.GetStruct(targetURL, &policies); err != nil {
+			return false, err
+		}
+
+		names := make([]string, 0, len(policies))
+		for _, p := range policies {
+			names = append(names, fmt.Sprintf("%s (id: %d)", p.Name, p.ID))
+		}
+		if len(names) == 0 {
+			names = append(names, "-")
+		}
+		fmt.Printf("  %s: %s\n", t.Name, strings.Join(names, ", "))
+	}
+	return false, nil
+}
@@ -49,10 +49,10 @@ var targetPing struct {
 func init() {
 	targetCmd.AddCommand(targetPingCmd)
 
-	targetPingCmd.Flags().Int64VarP(&targetPing.ID,
+	targetPingCmd.Flags().VarP(newIDValue(&targetPing.ID, "target"),
 		"id",
-		"i", 0,
-		"(REQUIRED) The replication's target ID.")
+		"i",
+		"(REQUIRED) The replication's target ID or name.")
 	targetPingCmd.MarkFlagRequired("id")
 
 	targetPingCmd.Flags().StringVarP(&targetPing.Endpoint,
@@ -44,10 +44,10 @@ var targetPolicyList struct {
 func init() {
 	targetCmd.AddCommand(targetPolicyListCmd)
 
-	targetPolicyListCmd.Flags().Int64VarP(&targetPolicyList.ID,
+	targetPolicyListCmd.Flags().VarP(newIDValue(&targetPolicyList.ID, "target"),
 		"id",
-		"i", 0,
-		"(REQUIRED) The replication's target ID.")
+		"i",
+		"(REQUIRED) The replication's target ID or name.")
 	targetPolicyListCmd.MarkFlagRequired("id")
 }
 
@@ -0,0 +1,209 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// targetRotateCmd represents the rotate-credentials command
+var targetRotateCmd = &cobra.Command{
+	Use:   "rotate-credentials",
+	Short: "Update the password of a service account on all the targets using it.",
+	Long: `This command updates the password of the targets whose username is '--username' (and whose endpoint matches '--match' if given), then pings each of them, and rolls back the ones failing to ping to the current password.
+
+As Harbor never returns the saved passwords, the current password is required for rolling back. With '--password_stdin', the new password is read from the first line of stdin, and the current one from the second line, e.g.
+
+  printf '%s\n%s\n' "$NEW_PASSWORD" "$OLD_PASSWORD" | harborctl registry rotate-credentials -u svc --password_stdin
+
+Otherwise both are prompted for.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := rotateCredentials(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var targetRotate struct {
+	username      string
+	passwordStdin bool
+	match         string
+	timeout       time.Duration
+	dryRun        bool
+}
+
+func init() {
+	targetCmd.AddCommand(targetRotateCmd)
+
+	targetRotateCmd.Flags().StringVarP(&targetRotate.username,
+		"username",
+		"u", "",
+		"(REQUIRED) The username of the service account.")
+	targetRotateCmd.MarkFlagRequired("username")
+
+	targetRotateCmd.Flags().BoolVarP(&targetRotate.passwordStdin,
+		"password_stdin",
+		"", false,
+		"Read the new and the current passwords from stdin.")
+
+	targetRotateCmd.Flags().StringVarP(&targetRotate.match,
+		"match",
+		"m", "",
+		"Only update the targets whose endpoint matches the regular expression.")
+
+	targetRotateCmd.Flags().DurationVarP(&targetRotate.timeout,
+		"timeout",
+		"", 10*time.Second,
+		"The timeout of pinging a target.")
+
+	targetRotateCmd.Flags().BoolVarP(&targetRotate.dryRun,
+		"dry_run",
+		"", false,
+		"Show the targets to update only.")
+}
+
+// readPasswords reads the new and the current passwords.
+func readPasswords() (string, string, error) {
+	var passwords []string
+	if targetRotate.passwordStdin {
+		scanner := bufio.NewScanner(os.Stdin)
+		for len(passwords) < 2 && scanner.Scan() {
+			passwords = append(passwords, strings.TrimRight(scanner.Text(), "\r"))
+		}
+		if err := scanner.Err(); err != nil {
+			return "", "", err
+		}
+	} else {
+		for _, prompt := range []string{"New password of " + targetRotate.username, "Current password of " + targetRotate.username} {
+			fmt.Println(prompt)
+			p, err := utils.ReadPasswordFromTerm()
+			if err != nil {
+				return "", "", err
+			}
+			passwords = append(passwords, p)
+		}
+	}
+
+	if len(passwords) < 2 || passwords[0] == "" || passwords[1] == "" {
+		return "", "", errors.New("both the new and the current passwords are required")
+	}
+	if passwords[0] == passwords[1] {
+		return "", "", errors.New("the new password is the same as the current one")
+	}
+	return passwords[0], passwords[1], nil
+}
+
+// updateTargetPassword updates the target with the password.
+func updateTargetPassword(t *targetBrief, password string) error {
+	body := struct {
+		Name     string `json:"name"`
+		Endpoint string `json:"endpoint"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Insecure bool   `json:"insecure"`
+	}{t.Name, t.Endpoint, t.Username, password, t.Insecure}
+
+	_, _, err := utils.Send("PUT", utils.URLGen("/api/targets/"+strconv.FormatInt(t.ID, 10)), &body)
+	return err
+}
+
+func rotateCredentials() error {
+	var match *regexp.Regexp
+	if targetRotate.match != "" {
+		var err error
+		if match, err = regexp.Compile(targetRotate.match); err != nil {
+			return fmt.Errorf("invalid match: %v", err)
+		}
+	}
+
+	all, err := listTargets()
+	if err != nil {
+		return err
+	}
+
+	var targets []targetBrief
+	for _, t := range all {
+		if t.Username != targetRotate.username {
+			continue
+		}
+		if match != nil && !match.MatchString(t.Endpoint) {
+			continue
+		}
+		targets = append(targets, t)
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no target of username %q matches", targetRotate.username)
+	}
+
+	if targetRotate.dryRun {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tENDPOINT")
+		for _, t := range targets {
+			fmt.Fprintf(w, "%d\t%s\t%s\n", t.ID, t.Name, t.Endpoint)
+		}
+		w.Flush()
+		return nil
+	}
+
+	newPassword, oldPassword, err := readPasswords()
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tENDPOINT\tRESULT\tMESSAGE")
+	for i := range targets {
+		t := &targets[i]
+
+		result, message := "rotated", ""
+		if err := updateTargetPassword(t, newPassword); err != nil {
+			result, message = "failed", err.Error()
+		} else if h := pingSavedTarget(t.ID, targetRotate.timeout); !h.ok() {
+			result, message = "rolled back", h.status+": "+h.message
+			if err := updateTargetPassword(t, oldPassword); err != nil {
+				result, message = "rollback failed", message+"; "+err.Error()
+			}
+		}
+		if result != "rotated" {
+			failed++
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Endpoint, result, message)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d targets are not rotated", failed, len(targets))
+	}
+	return nil
+}
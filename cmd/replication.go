@@ -82,10 +82,10 @@ var jobReplicationList struct {
 func initJobReplicationList() {
 	replicationCmd.AddCommand(jobReplicationListCmd)
 
-	jobReplicationListCmd.Flags().Int64VarP(&jobReplicationList.policyID,
+	jobReplicationListCmd.Flags().VarP(newIDValue(&jobReplicationList.policyID, "policy"),
 		"policy_id",
-		"i", 0,
-		"(REQUIRED) The ID of the policy that triggered this job.")
+		"i",
+		"(REQUIRED) The ID or name of the policy that triggered this job.")
 	jobReplicationListCmd.MarkFlagRequired("policy_id")
 
 	jobReplicationListCmd.Flags().Int64VarP(&jobReplicationList.num,
@@ -185,10 +185,10 @@ var jobStatusUpdate struct {
 func initJobStatusUpdate() {
 	replicationCmd.AddCommand(jobReplicationUpdateCmd)
 
-	jobReplicationUpdateCmd.Flags().Int64VarP(&jobStatusUpdate.PolicyID,
+	jobReplicationUpdateCmd.Flags().VarP(newIDValue(&jobStatusUpdate.PolicyID, "policy"),
 		"policy_id",
-		"i", 0,
-		"(REQUIRED) The ID of the policy that triggered this job.")
+		"i",
+		"(REQUIRED) The ID or name of the policy that triggered this job.")
 	jobReplicationUpdateCmd.MarkFlagRequired("policy_id")
 
 	jobReplicationUpdateCmd.Flags().StringVarP(&jobStatusUpdate.Status,
@@ -23,6 +23,7 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -53,6 +54,7 @@ func init() {
 	initPolicyDelete()
 	initPolicyCreate()
 	initPolicyUpdate()
+	initPolicyApply()
 }
 
 // policyListCmd represents the list command
@@ -68,9 +70,9 @@ var policyListCmd = &cobra.Command{
 var policyList struct {
 	name string
 	// NOTE:
-	// Change the type of projectID from int64 to string, as the default value of int64
-	// is 0 which will make the results filtered by "project_id=0"
-	projectID string
+	// projectID is 0 if not set, which is not sent, as "project_id=0" filters
+	// out all the results.
+	projectID int64
 	page      int64
 	pageSize  int64
 }
@@ -83,10 +85,10 @@ func initPolicyList() {
 		"n", "",
 		"The replication’s policy name.")
 
-	policyListCmd.Flags().StringVarP(&policyList.projectID,
+	policyListCmd.Flags().VarP(newIDValue(&policyList.projectID, "project"),
 		"project_id",
-		"j", "",
-		"The ID of project.")
+		"j",
+		"The ID or name of project.")
 
 	policyListCmd.Flags().Int64VarP(&policyList.page,
 		"page",
@@ -101,9 +103,11 @@ func initPolicyList() {
 
 func listPolicy() {
 	targetURL := policyURL + "/replication?name=" + policyList.name +
-		"&project_id=" + policyList.projectID +
 		"&page=" + strconv.FormatInt(policyList.page, 10) +
 		"&page_size=" + strconv.FormatInt(policyList.pageSize, 10)
+	if policyList.projectID != 0 {
+		targetURL += "&project_id=" + strconv.FormatInt(policyList.projectID, 10)
+	}
 	utils.Get(targetURL)
 }
 
@@ -124,10 +128,10 @@ var policyGet struct {
 func initPolicyGet() {
 	policyReplicationCmd.AddCommand(policyGetCmd)
 
-	policyGetCmd.Flags().Int64VarP(&policyGet.ID,
+	policyGetCmd.Flags().VarP(newIDValue(&policyGet.ID, "policy"),
 		"id",
-		"i", 0,
-		"(REQUIRED) The ID of the policy.")
+		"i",
+		"(REQUIRED) The ID or name of the policy.")
 	policyGetCmd.MarkFlagRequired("id")
 }
 
@@ -153,10 +157,10 @@ var policyDelete struct {
 func initPolicyDelete() {
 	policyReplicationCmd.AddCommand(policyDeleteCmd)
 
-	policyDeleteCmd.Flags().Int64VarP(&policyDelete.ID,
+	policyDeleteCmd.Flags().VarP(newIDValue(&policyDelete.ID, "policy"),
 		"id",
-		"i", 0,
-		"(REQUIRED) The ID of the policy.")
+		"i",
+		"(REQUIRED) The ID or name of the policy.")
 	policyDeleteCmd.MarkFlagRequired("id")
 }
 
@@ -358,13 +362,10 @@ func createPolicy() {
 	// NOTE: not identify whether this replication rule exists or not by name, but it's ok, I think
 	pinfo.Name = policyCreate.replicationRuleName
 
-	getSrcPrjURL := utils.URLGen("/api/projects") + "?name=" + policyCreate.sourceProjectName
-	utils.GetStruct(getSrcPrjURL, &pinfo.Projects)
-	//fmt.Println("pinfo.Project =>", pinfo.Projects)
-
-	getDstTargetURL := utils.URLGen("/api/targets") + "?name=" + policyCreate.endpointName
-	utils.GetStruct(getDstTargetURL, &pinfo.Targets)
-	//fmt.Println("pinfo.Targets =>", pinfo.Targets)
+	if err := pinfo.resolve(policyCreate.sourceProjectName, policyCreate.endpointName); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
 
 	if policyCreate.filterByRepoName == "" && policyCreate.filterByTagName == "" && policyCreate.filterByLabelIDs == "" {
 		// FIXME: it seems that the value of "filters" after json.Marshal can either "null" or "[]"
@@ -457,10 +458,10 @@ var policyUpdate struct {
 func initPolicyUpdate() {
 	policyReplicationCmd.AddCommand(policyUpdateCmd)
 
-	policyUpdateCmd.Flags().Int64VarP(&policyUpdate.ID,
+	policyUpdateCmd.Flags().VarP(newIDValue(&policyUpdate.ID, "policy"),
 		"id",
-		"i", 0,
-		"(REQUIRED) The ID of the policy to be updated.")
+		"i",
+		"(REQUIRED) The ID or name of the policy to be updated.")
 	policyUpdateCmd.MarkFlagRequired("id")
 
 	policyUpdateCmd.Flags().StringVarP(&policyUpdate.triggerKind,
@@ -538,13 +539,10 @@ func updatePolicy() {
 	// NOTE: not identify whether this replication rule exists or not by name, but it's ok, I think
 	pinfo.Name = policyUpdate.replicationRuleName
 
-	getSrcPrjURL := utils.URLGen("/api/projects") + "?name=" + policyUpdate.sourceProjectName
-	utils.GetStruct(getSrcPrjURL, &pinfo.Projects)
-	//fmt.Println("pinfo.Project =>", pinfo.Projects)
-
-	getDstTargetURL := utils.URLGen("/api/targets") + "?name=" + policyUpdate.endpointName
-	utils.GetStruct(getDstTargetURL, &pinfo.Targets)
-	//fmt.Println("pinfo.Targets =>", pinfo.Targets)
+	if err := pinfo.resolve(policyUpdate.sourceProjectName, policyUpdate.endpointName); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
 
 	if policyUpdate.filterByRepoName == "" && policyUpdate.filterByTagName == "" && policyUpdate.filterByLabelIDs == "" {
 		// FIXME: it seems that the value of "filters" after json.Marshal can either "null" or "[]"
@@ -591,3 +589,44 @@ func updatePolicy() {
 
 	utils.Put(targetURL, string(p))
 }
+
+// resolve gets the source project and the endpoint of the policy by their
+// exact names, see resolveName.
+//
+// NOTE: the name filters of '/projects' and '/targets' are fuzzy match, so
+// the results are filtered again by the IDs resolved.
+func (pinfo *policyInfo) resolve(projectName, endpointName string) error {
+	projectID, err := resolveName("project", projectName, 0)
+	if err != nil {
+		return err
+	}
+	getSrcPrjURL := utils.URLGen("/api/projects") + "?name=" + url.QueryEscape(projectName)
+	if err := utils.GetStruct(getSrcPrjURL, &pinfo.Projects); err != nil {
+		return err
+	}
+	projects := pinfo.Projects[:0]
+	for _, p := range pinfo.Projects {
+		if int64(p.ProjectID) == projectID {
+			projects = append(projects, p)
+		}
+	}
+	pinfo.Projects = projects
+
+	targetID, err := resolveName("target", endpointName, 0)
+	if err != nil {
+		return err
+	}
+	getDstTargetURL := utils.URLGen("/api/targets") + "?name=" + url.QueryEscape(endpointName)
+	if err := utils.GetStruct(getDstTargetURL, &pinfo.Targets); err != nil {
+		return err
+	}
+	targets := pinfo.Targets[:0]
+	for _, t := range pinfo.Targets {
+		if int64(t.ID) == targetID {
+			targets = append(targets, t)
+		}
+	}
+	pinfo.Targets = targets
+
+	return nil
+}
@@ -0,0 +1,510 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// policyApplyCmd represents the apply command
+var policyApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Create or update replication policies from a YAML spec.",
+	Long: `This command creates the replication policies in a YAML file, or updates the existing ones with the same names, showing the diff against them.
+
+The file holds one or more policies separated by '---', e.g.
+
+name: library-to-dr
+description: replicate library to the DR site
+project: library
+targets:
+  - dr
+trigger:
+  kind: Scheduled          # 'Manual', 'Immediate' or 'Scheduled'
+  schedule:
+    type: Weekly           # 'Daily' or 'Weekly'
+    weekday: 1             # 1-7, only for 'Weekly'
+    offtime: 7200          # the seconds after UTC 00:00
+filters:
+  - kind: repository
+    value: library/nginx*
+  - kind: tag
+    value: v*
+  - kind: label            # the name or the ID of a global label or a label of the project
+    value: prod
+replicate_existing_image_now: true
+replicate_deletion: false
+
+Before submitting, the spec is validated: unknown fields are rejected, and the project, the targets and the labels must exist. A warning is shown if a repository filter matches no repository of the project.
+
+NOTE: Harbor replicates a policy to one target, so a policy with multiple targets is applied as one policy per target, named '<name>-<target>'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := applyPolicies(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var policyApply struct {
+	file   string
+	dryRun bool
+}
+
+func initPolicyApply() {
+	policyReplicationCmd.AddCommand(policyApplyCmd)
+
+	policyApplyCmd.Flags().StringVarP(&policyApply.file,
+		"file",
+		"f", "",
+		"(REQUIRED) The YAML file of replication policies.")
+	policyApplyCmd.MarkFlagRequired("file")
+
+	policyApplyCmd.Flags().BoolVarP(&policyApply.dryRun,
+		"dry_run",
+		"", false,
+		"Validate the policies and show the diff only.")
+}
+
+// policySpec is a replication policy in YAML.
+type policySpec struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Project     string   `yaml:"project"`
+	Targets     []string `yaml:"targets"`
+	Trigger     struct {
+		Kind     string `yaml:"kind"`
+		Schedule struct {
+			Type    string `yaml:"type"`
+			Weekday int64  `yaml:"weekday"`
+			Offtime int64  `yaml:"offtime"`
+		} `yaml:"schedule"`
+	} `yaml:"trigger"`
+	Filters []struct {
+		Kind  string `yaml:"kind"`
+		Value string `yaml:"value"`
+	} `yaml:"filters"`
+	ReplicateExistingImageNow bool `yaml:"replicate_existing_image_now"`
+	ReplicateDeletion         bool `yaml:"replicate_deletion"`
+}
+
+// policyRequest is a replication policy of the API.
+//
+// NOTE: the value of a label filter is the label ID in number, while the
+// others are patterns in string.
+type policyRequest struct {
+	ID          int64  `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Projects    []struct {
+		ProjectID int64  `json:"project_id"`
+		Name      string `json:"name"`
+	} `json:"projects"`
+	Targets []struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"targets"`
+	Trigger struct {
+		Kind          string `json:"kind"`
+		ScheduleParam *struct {
+			Type    string `json:"type"`
+			Weekday int64  `json:"weekday"`
+			Offtime int64  `json:"offtime"`
+		} `json:"schedule_param,omitempty"`
+	} `json:"trigger"`
+	Filters []struct {
+		Kind  string      `json:"kind"`
+		Value interface{} `json:"value"`
+	} `json:"filters"`
+	ReplicateExistingImageNow bool `json:"replicate_existing_image_now"`
+	ReplicateDeletion         bool `json:"replicate_deletion"`
+}
+
+// readPolicySpecs reads the policies from r, rejecting unknown fields.
+func readPolicySpecs(r io.Reader) ([]policySpec, error) {
+	dec := yaml.NewDecoder(r)
+	dec.SetStrict(true)
+
+	var specs []policySpec
+	for i := 1; ; i++ {
+		var spec policySpec
+		err := dec.Decode(&spec)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("policy %d: %v", i, err)
+		}
+		specs = append(specs, spec)
+	}
+	if len(specs) == 0 {
+		return nil, errors.New("no policy in file")
+	}
+	return specs, nil
+}
+
+func (spec *policySpec) validate() error {
+	if spec.Name == "" {
+		return errors.New("name is required")
+	}
+	if spec.Project == "" {
+		return errors.New("project is required")
+	}
+	if len(spec.Targets) == 0 {
+		return errors.New("at least one target is required")
+	}
+
+	switch spec.Trigger.Kind {
+	case "":
+		spec.Trigger.Kind = "Manual"
+	case "Manual", "Immediate":
+	case "Scheduled":
+		s := spec.Trigger.Schedule
+		switch s.Type {
+		case "Daily":
+		case "Weekly":
+			if s.Weekday < 1 || s.Weekday > 7 {
+				return errors.New("trigger.schedule.weekday must be in range [1, 7]")
+			}
+		default:
+			return errors.New("trigger.schedule.type must be one of [Daily|Weekly]")
+		}
+		if s.Offtime < 0 || s.Offtime >= 86400 {
+			return errors.New("trigger.schedule.offtime must be in range [0, 86400)")
+		}
+	default:
+		return errors.New("trigger.kind must be one of [Manual|Immediate|Scheduled]")
+	}
+
+	for i, f := range spec.Filters {
+		switch f.Kind {
+		case "repository", "tag", "label":
+		default:
+			return fmt.Errorf("filters[%d].kind must be one of [repository|tag|label], got %q", i, f.Kind)
+		}
+		if f.Value == "" {
+			return fmt.Errorf("filters[%d].value is empty", i)
+		}
+		if f.Kind != "label" {
+			if _, err := path.Match(f.Value, ""); err != nil {
+				return fmt.Errorf("filters[%d].value %q: %v", i, f.Value, err)
+			}
+		}
+	}
+	return nil
+}
+
+// requests resolves the project, targets and labels of the spec, and returns
+// the policies to submit, one per target.
+func (spec *policySpec) requests() ([]policyRequest, map[int64]string, error) {
+	projectID, err := findProjectID(spec.Project)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	labels, err := availableLabels(utils.CurrentContext(), projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	labelNames := make(map[int64]string)
+	for _, l := range labels {
+		labelNames[l.ID] = l.Name
+	}
+
+	var base policyRequest
+	base.Name = spec.Name
+	base.Description = spec.Description
+	base.Projects = append(base.Projects, struct {
+		ProjectID int64  `json:"project_id"`
+		Name      string `json:"name"`
+	}{projectID, spec.Project})
+	base.Trigger.Kind = spec.Trigger.Kind
+	if spec.Trigger.Kind == "Scheduled" {
+		s := spec.Trigger.Schedule
+		base.Trigger.ScheduleParam = &struct {
+			Type    string `json:"type"`
+			Weekday int64  `json:"weekday"`
+			Offtime int64  `json:"offtime"`
+		}{s.Type, s.Weekday, s.Offtime}
+	}
+	base.ReplicateExistingImageNow = spec.ReplicateExistingImageNow
+	base.ReplicateDeletion = spec.ReplicateDeletion
+
+	for _, f := range spec.Filters {
+		var value interface{} = f.Value
+		if f.Kind == "label" {
+			l, err := findLabel(labels, f.Value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("label filter: %v", err)
+			}
+			value = l.ID
+		}
+		base.Filters = append(base.Filters, struct {
+			Kind  string      `json:"kind"`
+			Value interface{} `json:"value"`
+		}{f.Kind, value})
+	}
+
+	var reqs []policyRequest
+	for _, name := range spec.Targets {
+		var targets []struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		}
+		if err := utils.GetStruct(utils.URLGen("/api/targets")+"?name="+url.QueryEscape(name), &targets); err != nil {
+			return nil, nil, err
+		}
+
+		req := base
+		for _, t := range targets {
+			if t.Name == name {
+				req.Targets = append(req.Targets[:0:0], t)
+			}
+		}
+		if len(req.Targets) == 0 {
+			return nil, nil, fmt.Errorf("no endpoint named %q", name)
+		}
+
+		if len(spec.Targets) > 1 {
+			req.Name = spec.Name + "-" + name
+		}
+		reqs = append(reqs, req)
+	}
+
+	if err := warnUnmatchedRepoFilters(spec, projectID); err != nil {
+		return nil, nil, err
+	}
+
+	return reqs, labelNames, nil
+}
+
+// warnUnmatchedRepoFilters warns about the repository filters which match no
+// repository of the project, which are probably typos.
+func warnUnmatchedRepoFilters(spec *policySpec, projectID int64) error {
+	var patterns []string
+	for _, f := range spec.Filters {
+		if f.Kind == "repository" {
+			patterns = append(patterns, f.Value)
+		}
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	var repos []string
+	targetURL := utils.URLGen("/api/repositories") + "?project_id=" + strconv.FormatInt(projectID, 10)
+	err := utils.GetPages(targetURL, 100, func(pageURL string) (int, error) {
+		var page []struct {
+			Name string `json:"name"`
+		}
+		if err := utils.GetStruct(pageURL, &page); err != nil {
+			return 0, err
+		}
+		for _, r := range page {
+			repos = append(repos, r.Name)
+		}
+		return len(page), nil
+	})
+	if err != nil || len(repos) == 0 {
+		return err
+	}
+
+	for _, p := range patterns {
+		var matched bool
+		for _, r := range repos {
+			// NOTE: the pattern may be given with or without the project name.
+			if ok, _ := path.Match(p, r); ok {
+				matched = true
+				break
+			}
+			if ok, _ := path.Match(p, strings.TrimPrefix(r, spec.Project+"/")); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fmt.Printf("WARNING: policy %s: repository filter %q matches no repository of project %s now\n", spec.Name, p, spec.Project)
+		}
+	}
+	return nil
+}
+
+// findPolicy gets the policy by its exact name, and returns nil if not found.
+func findPolicy(name string) (*policyRequest, error) {
+	var policies []policyRequest
+	if err := utils.GetStruct(utils.URLGen("/api/policies/replication")+"?name="+url.QueryEscape(name), &policies); err != nil {
+		return nil, err
+	}
+	for i := range policies {
+		if policies[i].Name == name {
+			return &policies[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// policyFields flattens the policy into fields for comparing, with label IDs
+// shown by names.
+func policyFields(p *policyRequest, labelNames map[int64]string) [][2]string {
+	var projects, targets, filters []string
+	for _, pr := range p.Projects {
+		projects = append(projects, pr.Name)
+	}
+	for _, t := range p.Targets {
+		targets = append(targets, t.Name)
+	}
+	for _, f := range p.Filters {
+		v := fmt.Sprint(f.Value)
+		if f.Kind == "label" {
+			var id int64
+			switch n := f.Value.(type) {
+			case float64:
+				id = int64(n)
+			case int64:
+				id = n
+			case string:
+				id, _ = strconv.ParseInt(n, 10, 64)
+			}
+			v = strconv.FormatInt(id, 10)
+			if name, ok := labelNames[id]; ok {
+				v += "(" + name + ")"
+			}
+		}
+		filters = append(filters, f.Kind+"="+v)
+	}
+	sort.Strings(filters)
+
+	trigger := p.Trigger.Kind
+	if s := p.Trigger.ScheduleParam; s != nil && trigger == "Scheduled" {
+		trigger += fmt.Sprintf(" %s weekday=%d offtime=%d", s.Type, s.Weekday, s.Offtime)
+		if s.Type == "Daily" {
+			trigger = fmt.Sprintf("Scheduled Daily offtime=%d", s.Offtime)
+		}
+	}
+
+	return [][2]string{
+		{"description", p.Description},
+		{"project", strings.Join(projects, ",")},
+		{"targets", strings.Join(targets, ",")},
+		{"trigger", trigger},
+		{"filters", strings.Join(filters, ", ")},
+		{"replicate_existing_image_now", strconv.FormatBool(p.ReplicateExistingImageNow)},
+		{"replicate_deletion", strconv.FormatBool(p.ReplicateDeletion)},
+	}
+}
+
+// diffPolicy returns the lines of the fields changed from old to new.
+func diffPolicy(old, new *policyRequest, labelNames map[int64]string) []string {
+	o := policyFields(old, labelNames)
+	n := policyFields(new, labelNames)
+
+	var lines []string
+	for i := range o {
+		if o[i][1] != n[i][1] {
+			lines = append(lines, fmt.Sprintf("  %s:\n    - %s\n    + %s", o[i][0], o[i][1], n[i][1]))
+		}
+	}
+	return lines
+}
+
+func applyPolicies() error {
+	f, err := os.Open(policyApply.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	specs, err := readPolicySpecs(f)
+	if err != nil {
+		return err
+	}
+
+	// validate all the policies before submitting any of them.
+	type pending struct {
+		req        policyRequest
+		labelNames map[int64]string
+	}
+	var all []pending
+	for i := range specs {
+		if err := specs[i].validate(); err != nil {
+			return fmt.Errorf("policy %d (%s): %v", i+1, specs[i].Name, err)
+		}
+		reqs, labelNames, err := specs[i].requests()
+		if err != nil {
+			return fmt.Errorf("policy %d (%s): %v", i+1, specs[i].Name, err)
+		}
+		for _, req := range reqs {
+			all = append(all, pending{req, labelNames})
+		}
+	}
+
+	for _, p := range all {
+		req := p.req
+
+		old, err := findPolicy(req.Name)
+		if err != nil {
+			return err
+		}
+
+		if old == nil {
+			fmt.Printf("<== Policy %s: create\n", req.Name)
+			for _, field := range policyFields(&req, p.labelNames) {
+				fmt.Printf("  %s: %s\n", field[0], field[1])
+			}
+			if !policyApply.dryRun {
+				if _, _, err := utils.Send("POST", utils.URLGen("/api/policies/replication"), &req); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		diff := diffPolicy(old, &req, p.labelNames)
+		if len(diff) == 0 {
+			fmt.Printf("<== Policy %s (id: %d): unchanged\n", req.Name, old.ID)
+			continue
+		}
+
+		fmt.Printf("<== Policy %s (id: %d): update\n", req.Name, old.ID)
+		fmt.Println(strings.Join(diff, "\n"))
+		if !policyApply.dryRun {
+			req.ID = old.ID
+			targetURL := utils.URLGen("/api/policies/replication/" + strconv.FormatInt(old.ID, 10))
+			if _, _, err := utils.Send("PUT", targetURL, &req); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
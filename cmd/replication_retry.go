@@ -0,0 +1,310 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// replicationRetryCmd represents the retry command
+var replicationRetryCmd = &cobra.Command{
+	Use:   "retry",
+	Short: "Re-trigger the replication of a policy, or only of its failed repositories.",
+	Long: `This command re-triggers the replication of the policy. With '--failed', only the repositories whose latest jobs since '--since' failed are replicated again.
+
+NOTE: Harbor can only trigger a whole policy, so for each failed repository a temporary manual policy is created, with the same project, target and filters but only the repository, and is deleted after its jobs are done. The failed deletions are listed but not retried.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := retryReplication(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var replicationRetry struct {
+	policyID int64
+	failed   bool
+	since    string
+	dryRun   bool
+	timeout  time.Duration
+	interval time.Duration
+}
+
+func init() {
+	replicationCmd.AddCommand(replicationRetryCmd)
+
+	replicationRetryCmd.Flags().VarP(newIDValue(&replicationRetry.policyID, "policy"),
+		"policy",
+		"i",
+		"(REQUIRED) The ID or name of the replication policy.")
+	replicationRetryCmd.MarkFlagRequired("policy")
+
+	replicationRetryCmd.Flags().BoolVarP(&replicationRetry.failed,
+		"failed",
+		"", false,
+		"Re-trigger the failed repositories only.")
+
+	replicationRetryCmd.Flags().StringVarP(&replicationRetry.since,
+		"since",
+		"", "7d",
+		"Look for the failed jobs since the time, e.g. '12h', '7d' or '2018-10-01'.")
+
+	replicationRetryCmd.Flags().BoolVarP(&replicationRetry.dryRun,
+		"dry_run",
+		"", false,
+		"Show the failed repositories only.")
+
+	replicationRetryCmd.Flags().DurationVarP(&replicationRetry.timeout,
+		"timeout",
+		"", 30*time.Minute,
+		"The time to wait for the jobs of the failed repositories.")
+
+	replicationRetryCmd.Flags().DurationVarP(&replicationRetry.interval,
+		"interval",
+		"", 10*time.Second,
+		"The interval of polling the jobs.")
+}
+
+// retryPolicy is a temporary policy replicating a failed repository.
+type retryPolicy struct {
+	id         int64
+	name       string
+	repository string
+	status     string
+}
+
+func triggerReplication(policyID int64) error {
+	body := struct {
+		PolicyID int64 `json:"policy_id"`
+	}{policyID}
+	_, _, err := utils.Send("POST", utils.URLGen("/api/replications"), &body)
+	return err
+}
+
+func retryReplication() error {
+	if !replicationRetry.failed {
+		return triggerReplication(replicationRetry.policyID)
+	}
+	if replicationRetry.interval < time.Second {
+		return errors.New("interval must be at least 1s")
+	}
+
+	since, err := utils.ParseTimeExpr(replicationRetry.since, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var policy policyRequest
+	if err := utils.GetStruct(utils.URLGen("/api/policies/replication/"+strconv.FormatInt(replicationRetry.policyID, 10)), &policy); err != nil {
+		return err
+	}
+
+	jobs, err := fetchReplicationJobs(policy.ID, since)
+	if err != nil {
+		return err
+	}
+
+	var repos []string
+	for _, j := range failedJobs(jobs) {
+		if j.Operation == "delete" {
+			fmt.Printf("skip: the deletion of %s (job %d) is not retried\n", j.Repository, j.ID)
+			continue
+		}
+		repos = append(repos, j.Repository)
+	}
+	if len(repos) == 0 {
+		fmt.Printf("No failed repository of policy %s (id: %d) since %s.\n", policy.Name, policy.ID, since.Format(time.RFC3339))
+		return nil
+	}
+
+	fmt.Printf("%d failed repositories of policy %s (id: %d):\n", len(repos), policy.Name, policy.ID)
+	for _, r := range repos {
+		fmt.Println("  " + r)
+	}
+	if replicationRetry.dryRun {
+		return nil
+	}
+
+	start := time.Now()
+	var retries []*retryPolicy
+	// NOTE: the report below deletes the temporary policies when done, and
+	// this does when returning by an error before it.
+	reported := false
+	defer func() {
+		if !reported {
+			cleanupRetryPolicies(retries)
+		}
+	}()
+	for i, repo := range repos {
+		rp, err := createRetryPolicy(&policy, repo, fmt.Sprintf("%s-retry-%d-%d", policy.Name, start.Unix(), i+1))
+		if err != nil {
+			return fmt.Errorf("%s: %v", repo, err)
+		}
+		retries = append(retries, rp)
+	}
+
+	// wait for the jobs of all the temporary policies.
+	deadline := start.Add(replicationRetry.timeout)
+	for {
+		done := true
+		for _, rp := range retries {
+			if rp.status != "" && rp.status != "pending" {
+				continue
+			}
+			rp.status, err = retryStatus(rp, start.Add(-time.Minute))
+			if err != nil {
+				return err
+			}
+			if rp.status == "pending" {
+				done = false
+			}
+		}
+		if done || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(replicationRetry.interval)
+	}
+
+	reported = true
+	var failed int
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tSTATUS\tTEMPORARY POLICY")
+	for _, rp := range retries {
+		note := "deleted"
+		if rp.status == "pending" {
+			note = fmt.Sprintf("%s (id: %d), kept as jobs are not done", rp.name, rp.id)
+		} else if err := deleteRetryPolicy(rp.id); err != nil {
+			note = fmt.Sprintf("%s (id: %d), failed to delete: %v", rp.name, rp.id, err)
+		}
+		if rp.status != "success" {
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", rp.repository, rp.status, note)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d repositories are not replicated", failed, len(retries))
+	}
+	return nil
+}
+
+func deleteRetryPolicy(id int64) error {
+	_, _, err := utils.Send("DELETE", utils.URLGen("/api/policies/replication/"+strconv.FormatInt(id, 10)), nil)
+	return err
+}
+
+// cleanupRetryPolicies deletes the temporary policies when the retry fails,
+// but keeps the ones whose jobs are known to be pending.
+func cleanupRetryPolicies(retries []*retryPolicy) {
+	for _, rp := range retries {
+		if rp.status == "pending" {
+			fmt.Printf("cleanup: kept the temporary policy %s (id: %d) as jobs are not done\n", rp.name, rp.id)
+		} else if err := deleteRetryPolicy(rp.id); err != nil {
+			fmt.Printf("cleanup: failed to delete the temporary policy %s (id: %d): %v\n", rp.name, rp.id, err)
+		} else {
+			fmt.Printf("cleanup: deleted the temporary policy %s (id: %d)\n", rp.name, rp.id)
+		}
+	}
+}
+
+// createRetryPolicy creates a manual policy like p but replicating the repository
+// only, and triggers it.
+func createRetryPolicy(p *policyRequest, repo, name string) (*retryPolicy, error) {
+	req := *p
+	req.ID = 0
+	req.Name = name
+	req.Description = fmt.Sprintf("retry %s of policy %s (id: %d)", repo, p.Name, p.ID)
+	req.Trigger.Kind = "Manual"
+	req.Trigger.ScheduleParam = nil
+	req.ReplicateExistingImageNow = false
+	req.ReplicateDeletion = false
+
+	// NOTE: the pattern of a repository filter matches the name without the project.
+	req.Filters = nil
+	for _, f := range p.Filters {
+		if f.Kind != "repository" {
+			req.Filters = append(req.Filters, f)
+		}
+	}
+	req.Filters = append(req.Filters, struct {
+		Kind  string      `json:"kind"`
+		Value interface{} `json:"value"`
+	}{"repository", repo[strings.Index(repo, "/")+1:]})
+
+	resp, _, err := utils.Send("POST", utils.URLGen("/api/policies/replication"), &req)
+	if err != nil {
+		return nil, err
+	}
+
+	rp := &retryPolicy{name: name, repository: repo}
+	if rp.id, err = strconv.ParseInt(path.Base(resp.Header.Get("Location")), 10, 64); err != nil {
+		created, err := findPolicy(name)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s is created but its ID is unknown, delete it by name: %v", name, err)
+		}
+		if created == nil {
+			return nil, fmt.Errorf("policy %s not found after creating", name)
+		}
+		rp.id = created.ID
+	}
+
+	if err := triggerReplication(rp.id); err != nil {
+		if derr := deleteRetryPolicy(rp.id); derr != nil {
+			return nil, fmt.Errorf("%v, and failed to delete the policy %s (id: %d): %v", err, name, rp.id, derr)
+		}
+		return nil, err
+	}
+	return rp, nil
+}
+
+// retryStatus returns the status of the temporary policy: 'pending' if any job
+// is not done, 'failed' if any job failed, otherwise 'success'.
+func retryStatus(rp *retryPolicy, since time.Time) (string, error) {
+	jobs, err := fetchReplicationJobs(rp.id, since)
+	if err != nil {
+		return "", err
+	}
+	if len(jobs) == 0 {
+		return "pending", nil
+	}
+
+	status := "success"
+	for _, j := range jobs {
+		switch jobStatusGroup[j.Status] {
+		case "pending":
+			return "pending", nil
+		case "failed", "stopped":
+			status = "failed"
+		}
+	}
+	return status, nil
+}
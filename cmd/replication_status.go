@@ -0,0 +1,216 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// replicationStatusCmd represents the status command
+var replicationStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Summarize the replication jobs of policies.",
+	Long: `This command summarizes the replication jobs since '--since' per policy: the last run time, the counts of the succeeded, failed and pending jobs, and the repositories failing to replicate.
+
+A repository is failing if its latest job is in 'error' status, so the ones replicated successfully after a failure are not listed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := replicationStatus(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var replicationStatusOpts struct {
+	policyID int64
+	since    string
+}
+
+func init() {
+	replicationCmd.AddCommand(replicationStatusCmd)
+
+	replicationStatusCmd.Flags().VarP(newIDValue(&replicationStatusOpts.policyID, "policy"),
+		"policy",
+		"i",
+		"Show the policy of the ID or name only, default is all the policies.")
+
+	replicationStatusCmd.Flags().StringVarP(&replicationStatusOpts.since,
+		"since",
+		"", "7d",
+		"Summarize the jobs since the time, e.g. '12h', '7d' or '2018-10-01'.")
+}
+
+// replicationJob is a replication job of a repository.
+type replicationJob struct {
+	ID           int64  `json:"id"`
+	Status       string `json:"status"`
+	Repository   string `json:"repository"`
+	PolicyID     int64  `json:"policy_id"`
+	Operation    string `json:"operation"`
+	Tags         string `json:"tags"`
+	CreationTime string `json:"creation_time"`
+	UpdateTime   string `json:"update_time"`
+}
+
+// jobStatusGroup maps the job statuses to the groups summarized.
+var jobStatusGroup = map[string]string{
+	"finished": "success",
+	"error":    "failed",
+	"pending":  "pending",
+	"running":  "pending",
+	"retrying": "pending",
+	"stopped":  "stopped",
+	"canceled": "stopped",
+}
+
+// fetchReplicationJobs fetches all the jobs of the policy created since the time.
+func fetchReplicationJobs(policyID int64, since time.Time) ([]replicationJob, error) {
+	targetURL := utils.URLGen("/api/jobs/replication") + "?policy_id=" + strconv.FormatInt(policyID, 10) +
+		"&start_time=" + strconv.FormatInt(since.Unix(), 10) +
+		"&end_time=" + strconv.FormatInt(time.Now().Unix(), 10)
+
+	var jobs []replicationJob
+	err := utils.GetPages(targetURL, 100, func(pageURL string) (int, error) {
+		var page []replicationJob
+		if err := utils.GetStruct(pageURL, &page); err != nil {
+			return 0, err
+		}
+		jobs = append(jobs, page...)
+		return len(page), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs, nil
+}
+
+// latestJobs returns the latest job of each repository and operation.
+func latestJobs(jobs []replicationJob) map[string]replicationJob {
+	latest := make(map[string]replicationJob)
+	for _, j := range jobs {
+		// NOTE: jobs are sorted by ID, the later one wins.
+		latest[j.Repository+" "+j.Operation] = j
+	}
+	return latest
+}
+
+// failedJobs returns the latest jobs of the repositories failing to replicate,
+// sorted by repository.
+func failedJobs(jobs []replicationJob) []replicationJob {
+	var failed []replicationJob
+	for _, j := range latestJobs(jobs) {
+		if j.Status == "error" {
+			failed = append(failed, j)
+		}
+	}
+	sort.Slice(failed, func(i, j int) bool {
+		if failed[i].Repository != failed[j].Repository {
+			return failed[i].Repository < failed[j].Repository
+		}
+		return failed[i].Operation < failed[j].Operation
+	})
+	return failed
+}
+
+func replicationStatus() error {
+	since, err := utils.ParseTimeExpr(replicationStatusOpts.since, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var policies []policyRequest
+	if replicationStatusOpts.policyID != 0 {
+		var p policyRequest
+		if err := utils.GetStruct(utils.URLGen("/api/policies/replication/"+strconv.FormatInt(replicationStatusOpts.policyID, 10)), &p); err != nil {
+			return err
+		}
+		policies = append(policies, p)
+	} else {
+		if err := utils.GetStruct(utils.URLGen("/api/policies/replication"), &policies); err != nil {
+			return err
+		}
+		if len(policies) == 0 {
+			return errors.New("no replication policy")
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].ID < policies[j].ID })
+
+	failing := make(map[int64][]replicationJob)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tTARGET\tLAST RUN\tSUCCESS\tFAILED\tPENDING\tFAILING REPOS")
+	for _, p := range policies {
+		jobs, err := fetchReplicationJobs(p.ID, since)
+		if err != nil {
+			return err
+		}
+
+		counts := make(map[string]int)
+		var lastRun string
+		for _, j := range jobs {
+			counts[jobStatusGroup[j.Status]]++
+			if j.CreationTime > lastRun {
+				lastRun = j.CreationTime
+			}
+		}
+		if lastRun == "" {
+			lastRun = "-"
+		}
+
+		var targets []string
+		for _, t := range p.Targets {
+			targets = append(targets, t.Name)
+		}
+
+		failing[p.ID] = failedJobs(jobs)
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%d\t%d\t%d\n", p.ID, p.Name, strings.Join(targets, ","),
+			lastRun, counts["success"], counts["failed"], counts["pending"], len(failing[p.ID]))
+	}
+	w.Flush()
+
+	for _, p := range policies {
+		if len(failing[p.ID]) == 0 {
+			continue
+		}
+
+		fmt.Printf("\nFailing repositories of policy %s (id: %d):\n", p.Name, p.ID)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  REPOSITORY\tOPERATION\tTAGS\tJOB ID\tFAILED AT")
+		for _, j := range failing[p.ID] {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%s\n", j.Repository, j.Operation, j.Tags, j.ID, j.UpdateTime)
+		}
+		w.Flush()
+	}
+	return nil
+}
@@ -45,10 +45,10 @@ var replicationTrigger struct {
 func init() {
 	replicationCmd.AddCommand(triggerCmd)
 
-	triggerCmd.Flags().Int64VarP(&replicationTrigger.PolicyID,
+	triggerCmd.Flags().VarP(newIDValue(&replicationTrigger.PolicyID, "policy"),
 		"policy_id",
-		"i", 0,
-		"(REQUIRED) The ID of replication policy.")
+		"i",
+		"(REQUIRED) The ID or name of replication policy.")
 	triggerCmd.MarkFlagRequired("policy_id")
 }
 
@@ -84,10 +84,10 @@ var repoGet struct {
 func initRepoGet() {
 	repositoryCmd.AddCommand(repoGetCmd)
 
-	repoGetCmd.Flags().Int64VarP(&repoGet.projectID,
+	repoGetCmd.Flags().VarP(newIDValue(&repoGet.projectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) Relevant project ID.")
+		"j",
+		"(REQUIRED) Relevant project ID or name.")
 	repoGetCmd.MarkFlagRequired("project_id")
 
 	repoGetCmd.Flags().StringVarP(&repoGet.q,
@@ -100,10 +100,10 @@ func initRepoGet() {
 		"o", "",
 		"Sort method, valid values include: 'name’, '-name’, 'creation_time’, '-creation_time’, 'update_time’, '-update_time’. Here '-' stands for descending order.")
 
-	repoGetCmd.Flags().Int64VarP(&repoGet.labelID,
+	repoGetCmd.Flags().VarP(newIDValue(&repoGet.labelID, "label").inProject(&repoGet.projectID),
 		"label_id",
-		"l", 0,
-		"The ID of label used to filter the result.")
+		"l",
+		"The ID or name of label used to filter the result.")
 
 	repoGetCmd.Flags().Int64VarP(&repoGet.page,
 		"page",
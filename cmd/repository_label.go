@@ -85,10 +85,10 @@ func initRepoLabelAdd() {
 		"(REQUIRED) The name of repository that you want to add a label.")
 	repoLabelAddCmd.MarkFlagRequired("repo_name")
 
-	repoLabelAddCmd.Flags().Int64VarP(&repoLabelAdd.ID,
+	repoLabelAddCmd.Flags().VarP(newIDValue(&repoLabelAdd.ID, "label").inProject(&repoLabelAdd.ProjectID),
 		"id",
-		"i", 0,
-		"(REQUIRED) The ID of the already existing label.")
+		"i",
+		"(REQUIRED) The ID or name of the already existing label.")
 	repoLabelAddCmd.MarkFlagRequired("id")
 
 	repoLabelAddCmd.Flags().StringVarP(&repoLabelAdd.Name,
@@ -111,10 +111,10 @@ func initRepoLabelAdd() {
 		"s", "",
 		"The scope of this label. ('p' for project scope, 'g' for global scope)")
 
-	repoLabelAddCmd.Flags().Int64VarP(&repoLabelAdd.ProjectID,
+	repoLabelAddCmd.Flags().VarP(newIDValue(&repoLabelAdd.ProjectID, "project"),
 		"project_id",
-		"j", 0,
-		"The project ID if the label is a project label. ('0' indicates global label, others indicate specific project)")
+		"j",
+		"The project ID or name if the label is a project label. ('0' indicates global label, others indicate specific project)")
 
 	repoLabelAddCmd.Flags().StringVarP(&repoLabelAdd.CreationTime,
 		"creation_time",
@@ -168,10 +168,10 @@ func initRepoLabelDelete() {
 		"(REQUIRED) The name of repository.")
 	repoLabelDeleteCmd.MarkFlagRequired("repo_name")
 
-	repoLabelDeleteCmd.Flags().Int64VarP(&repoLabelDelete.labelID,
+	repoLabelDeleteCmd.Flags().VarP(newIDValue(&repoLabelDelete.labelID, "label"),
 		"label_id",
-		"l", 0,
-		"(REQUIRED) The ID of label.")
+		"l",
+		"(REQUIRED) The ID or name of label.")
 	repoLabelDeleteCmd.MarkFlagRequired("label_id")
 }
 
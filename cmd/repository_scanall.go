@@ -51,10 +51,10 @@ var scan struct {
 func init() {
 	repositoryCmd.AddCommand(scanallCmd)
 
-	scanallCmd.Flags().Int64VarP(&scan.ProjectID,
+	scanallCmd.Flags().VarP(newIDValue(&scan.ProjectID, "project"),
 		"project_id",
-		"j", 0,
-		"(REQUIRED) When this parameter is set, only the images under the project identified by project_id will be scanned.")
+		"j",
+		"(REQUIRED) When this parameter is set, only the images under the project identified by project_id (ID or name) will be scanned.")
 	scanallCmd.MarkFlagRequired("project_id")
 }
 
@@ -21,17 +21,29 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/moooofly/harborctl/utils"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // tagCmd represents the tag command
 var tagCmd = &cobra.Command{
 	Use:   "tag",
 	Short: "'/repositories/{repo_name}/tags' API.",
-	Long:  `The subcommand of '/repositories/{repo_name}/tags' hierarchy.`,
+	Long: `The subcommand of '/repositories/{repo_name}/tags' hierarchy.
+
+NOTE: most subcommands accept an image reference (IMAGE) in place of '--repo_name' and '--tag', which is of format
+
+    [host/]project/repo[:tag][@digest]
+
+- If host is given, it must match the current address.
+- If neither tag nor digest is given, 'latest' is used.
+- If digest is given, it is resolved to a tag by the tag list of the repository.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("Use \"harborctl repository tag --help\" for more information about this command.")
 	},
@@ -45,9 +57,84 @@ func init() {
 	initTagList()
 }
 
+// tagDetail is the tag meta info returned by '/repositories/{repo_name}/tags'.
+type tagDetail struct {
+	Digest        string `json:"digest"`
+	Name          string `json:"name"`
+	Size          int64  `json:"size"`
+	Architecture  string `json:"architecture"`
+	OS            string `json:"os"`
+	DockerVersion string `json:"docker_version"`
+	Author        string `json:"author"`
+	Created       string `json:"created"`
+}
+
+// parseImage parses an image reference, and rejects the one whose host does
+// not match the current address.
+func parseImage(s string) (*utils.Reference, error) {
+	ref, err := utils.ParseReference(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref.Host != "" && !strings.EqualFold(ref.Host, viper.GetString("address")) {
+		return nil, fmt.Errorf("the host of %q does not match the current address %q", s, viper.GetString("address"))
+	}
+	return ref, nil
+}
+
+// resolveImage sets repoName and tag from the image reference given as the
+// only positional argument, which takes precedence over '--repo_name' and '--tag'.
+func resolveImage(args []string, repoName, tag *string) error {
+	if len(args) == 0 {
+		if *repoName == "" || *tag == "" {
+			return errors.New("either IMAGE or both '--repo_name' and '--tag' must be set")
+		}
+		return nil
+	}
+
+	ref, err := parseImage(args[0])
+	if err != nil {
+		return err
+	}
+
+	*repoName = ref.Repository
+	*tag = ref.Tag
+
+	if ref.Digest == "" {
+		if *tag == "" {
+			*tag = "latest"
+		}
+		return nil
+	}
+
+	var tags []tagDetail
+	if err := utils.GetStruct(repositoryURL+"/"+ref.Repository+"/tags", &tags); err != nil {
+		return err
+	}
+
+	for _, t := range tags {
+		if t.Digest != ref.Digest {
+			continue
+		}
+		if ref.Tag == "" {
+			*tag = t.Name
+			return nil
+		}
+		if ref.Tag == t.Name {
+			return nil
+		}
+	}
+
+	if ref.Tag != "" {
+		return fmt.Errorf("the digest of %s:%s is not %s", ref.Repository, ref.Tag, ref.Digest)
+	}
+	return fmt.Errorf("no tag of %s has digest %s", ref.Repository, ref.Digest)
+}
+
 // tagGetCmd represents the get command
 var tagGetCmd = &cobra.Command{
-	Use:   "get",
+	Use:   "get [IMAGE]",
 	Short: "Get a tag of a relevant repository.",
 	Long: `This endpoint aims to retrieve a tag's meta info from a relevant repository.
 
@@ -55,7 +142,12 @@ NOTE:
 - If deployed with Notary, the 'signature' within response represents whether the image is singed or not.
 - If the value of 'signature' is null, the image is unsigned.
 - This endpoint can be used without cookie.`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := resolveImage(args, &repoTagGet.repoName, &repoTagGet.tag); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
 		getRepoTag()
 	},
 }
@@ -71,14 +163,12 @@ func initTagGet() {
 	tagGetCmd.Flags().StringVarP(&repoTagGet.repoName,
 		"repo_name",
 		"r", "",
-		"(REQUIRED) The name of repository.")
-	tagGetCmd.MarkFlagRequired("repo_name")
+		"The name of repository. (REQUIRED if IMAGE is not given)")
 
 	tagGetCmd.Flags().StringVarP(&repoTagGet.tag,
 		"tag",
 		"t", "",
-		"(REQUIRED) The name of tag.")
-	tagGetCmd.MarkFlagRequired("tag")
+		"The name of tag. (REQUIRED if IMAGE is not given)")
 }
 
 func getRepoTag() {
@@ -89,10 +179,15 @@ func getRepoTag() {
 
 // tagDeleteCmd represents the delete command
 var tagDeleteCmd = &cobra.Command{
-	Use:   "delete",
+	Use:   "delete [IMAGE]",
 	Short: "Delete a tag in a repository.",
 	Long:  `This endpoint let user delete a tag by tag's name.`,
+	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := resolveImage(args, &repoTagDelete.repoName, &repoTagDelete.tag); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
 		deleteRepoTag()
 	},
 }
@@ -108,14 +203,12 @@ func initTagDelete() {
 	tagDeleteCmd.Flags().StringVarP(&repoTagDelete.repoName,
 		"repo_name",
 		"r", "",
-		"(REQUIRED) The name of repository.")
-	tagDeleteCmd.MarkFlagRequired("repo_name")
+		"The name of repository. (REQUIRED if IMAGE is not given)")
 
 	tagDeleteCmd.Flags().StringVarP(&repoTagDelete.tag,
 		"tag",
 		"t", "",
-		"(REQUIRED) The name of tag.")
-	tagDeleteCmd.MarkFlagRequired("tag")
+		"The name of tag. (REQUIRED if IMAGE is not given)")
 }
 
 func deleteRepoTag() {
@@ -23,6 +23,7 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"strconv"
 
 	"github.com/moooofly/harborctl/utils"
@@ -49,13 +50,18 @@ func init() {
 
 // tagLabelAddCmd represents the add command
 var tagLabelAddCmd = &cobra.Command{
-	Use:   "add",
+	Use:   "add [IMAGE]",
 	Short: "Add a label to the image under specific repository.",
 	Long: `This endpoint adds a label to the image under specific repository.
 
 WARNING:
 - '--deleted' should not be used unless knowing what you are doing`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := resolveImage(args, &repoTagLabelAdd.repoName, &repoTagLabelAdd.tag); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
 		addImageLabel()
 	},
 }
@@ -83,19 +89,17 @@ func initRepoTagLabelAdd() {
 	tagLabelAddCmd.Flags().StringVarP(&repoTagLabelAdd.repoName,
 		"repo_name",
 		"r", "",
-		"(REQUIRED) The name of repository.")
-	tagLabelAddCmd.MarkFlagRequired("repo_name")
+		"The name of repository. (REQUIRED if IMAGE is not given)")
 
 	tagLabelAddCmd.Flags().StringVarP(&repoTagLabelAdd.tag,
 		"tag",
 		"t", "",
-		"(REQUIRED) The tag of the image under the repository specified by repo_name.")
-	tagLabelAddCmd.MarkFlagRequired("tag")
+		"The tag of the image under the repository specified by repo_name. (REQUIRED if IMAGE is not given)")
 
-	tagLabelAddCmd.Flags().Int64VarP(&repoTagLabelAdd.ID,
+	tagLabelAddCmd.Flags().VarP(newIDValue(&repoTagLabelAdd.ID, "label").inProject(&repoTagLabelAdd.ProjectID),
 		"id",
-		"i", 0,
-		"(REQUIRED) The ID of the already existing label.")
+		"i",
+		"(REQUIRED) The ID or name of the already existing label.")
 	tagLabelAddCmd.MarkFlagRequired("id")
 
 	tagLabelAddCmd.Flags().StringVarP(&repoTagLabelAdd.Name,
@@ -118,10 +122,10 @@ func initRepoTagLabelAdd() {
 		"s", "",
 		"The scope of this label. ('p' for project scope, 'g' for global scope)")
 
-	tagLabelAddCmd.Flags().Int64VarP(&repoTagLabelAdd.ProjectID,
+	tagLabelAddCmd.Flags().VarP(newIDValue(&repoTagLabelAdd.ProjectID, "project"),
 		"project_id",
-		"j", 0,
-		"The project ID if the label is a project label. ('0' indicates global label, others indicate specific project)")
+		"j",
+		"The project ID or name if the label is a project label. ('0' indicates global label, others indicate specific project)")
 
 	tagLabelAddCmd.Flags().StringVarP(&repoTagLabelAdd.CreationTime,
 		"creation_time",
@@ -153,10 +157,15 @@ func addImageLabel() {
 
 // tagLabelDeleteCmd represents the delete command
 var tagLabelDeleteCmd = &cobra.Command{
-	Use:   "delete",
+	Use:   "delete [IMAGE]",
 	Short: "Delete the label from the image under a specific repository.",
 	Long:  `Delete the label from the image specified by the repo_name and tag.`,
+	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := resolveImage(args, &imageLabelDelete.repoName, &imageLabelDelete.tag); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
 		deleteImageLabel()
 	},
 }
@@ -173,19 +182,17 @@ func initRepoTagLabelDelete() {
 	tagLabelDeleteCmd.Flags().StringVarP(&imageLabelDelete.repoName,
 		"repo_name",
 		"r", "",
-		"(REQUIRED) The name of repository.")
-	tagLabelDeleteCmd.MarkFlagRequired("repo_name")
+		"The name of repository. (REQUIRED if IMAGE is not given)")
 
 	tagLabelDeleteCmd.Flags().StringVarP(&imageLabelDelete.tag,
 		"tag",
 		"t", "",
-		"(REQUIRED) The tag of image.")
-	tagLabelDeleteCmd.MarkFlagRequired("tag")
+		"The tag of image. (REQUIRED if IMAGE is not given)")
 
-	tagLabelDeleteCmd.Flags().Int64VarP(&imageLabelDelete.labelID,
+	tagLabelDeleteCmd.Flags().VarP(newIDValue(&imageLabelDelete.labelID, "label"),
 		"label_id",
-		"l", 0,
-		"(REQUIRED) The ID of label.")
+		"l",
+		"(REQUIRED) The ID or name of label.")
 	tagLabelDeleteCmd.MarkFlagRequired("label_id")
 }
 
@@ -198,10 +205,15 @@ func deleteImageLabel() {
 
 // tagLabelGetCmd represents the get command
 var tagLabelGetCmd = &cobra.Command{
-	Use:   "get",
+	Use:   "get [IMAGE]",
 	Short: "Get labels of an image under a specific repository.",
 	Long:  `This endpoint gets labels of an image under a repository specified by the repo_name and tag.`,
+	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := resolveImage(args, &imageLabelGet.repoName, &imageLabelGet.tag); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
 		getImageLabel()
 	},
 }
@@ -217,14 +229,12 @@ func initRepoTagLabelGet() {
 	tagLabelGetCmd.Flags().StringVarP(&imageLabelGet.repoName,
 		"repo_name",
 		"r", "",
-		"(REQUIRED) The name of repository.")
-	tagLabelGetCmd.MarkFlagRequired("repo_name")
+		"The name of repository. (REQUIRED if IMAGE is not given)")
 
 	tagLabelGetCmd.Flags().StringVarP(&imageLabelGet.tag,
 		"tag",
 		"t", "",
-		"(REQUIRED) The tag of image.")
-	tagLabelGetCmd.MarkFlagRequired("tag")
+		"The tag of image. (REQUIRED if IMAGE is not given)")
 }
 
 func getImageLabel() {
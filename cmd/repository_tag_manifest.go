@@ -21,18 +21,26 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/moooofly/harborctl/utils"
 	"github.com/spf13/cobra"
 )
 
 // manifestCmd represents the manifest command
 var manifestCmd = &cobra.Command{
-	Use:   "manifest",
+	Use:   "manifest [IMAGE]",
 	Short: "Get manifests of a relevant repository.",
 	Long: `This endpoint aims to retrieve manifests from a relevant repository.
 
 NOTE: This endpoint can be used without cookie.`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := resolveImage(args, &repoTagManifestGet.repoName, &repoTagManifestGet.tag); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
 		getRepoTagManifest()
 	},
 }
@@ -49,14 +57,12 @@ func init() {
 	manifestCmd.Flags().StringVarP(&repoTagManifestGet.repoName,
 		"repo_name",
 		"r", "",
-		"(REQUIRED) The name of repository.")
-	manifestCmd.MarkFlagRequired("repo_name")
+		"The name of repository. (REQUIRED if IMAGE is not given)")
 
 	manifestCmd.Flags().StringVarP(&repoTagManifestGet.tag,
 		"tag",
 		"t", "",
-		"(REQUIRED) The name of tag.")
-	manifestCmd.MarkFlagRequired("tag")
+		"The name of tag. (REQUIRED if IMAGE is not given)")
 
 	manifestCmd.Flags().StringVarP(&repoTagManifestGet.version,
 		"version",
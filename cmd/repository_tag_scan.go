@@ -21,18 +21,26 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/moooofly/harborctl/utils"
 	"github.com/spf13/cobra"
 )
 
 // tagScanCmd represents the scan command
 var tagScanCmd = &cobra.Command{
-	Use:   "scan",
+	Use:   "scan [IMAGE]",
 	Short: "Scan the image. (NOTE: need Clair deployment)",
 	Long: `Trigger jobservice to call Clair API to scan the image identified by the repo_name and tag.
 
 NOTE: Only project admins have permission to scan images under the project.`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := resolveImage(args, &repoTagScan.repoName, &repoTagScan.tag); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
 		scanRepoTag()
 	},
 }
@@ -49,14 +57,12 @@ func init() {
 	tagScanCmd.Flags().StringVarP(&repoTagScan.repoName,
 		"repo_name",
 		"r", "",
-		"(REQUIRED) The name of repository.")
-	tagScanCmd.MarkFlagRequired("repo_name")
+		"The name of repository. (REQUIRED if IMAGE is not given)")
 
 	tagScanCmd.Flags().StringVarP(&repoTagScan.tag,
 		"tag",
 		"t", "",
-		"(REQUIRED) The name of tag.")
-	tagScanCmd.MarkFlagRequired("tag")
+		"The name of tag. (REQUIRED if IMAGE is not given)")
 }
 
 func scanRepoTag() {
@@ -21,16 +21,24 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/moooofly/harborctl/utils"
 	"github.com/spf13/cobra"
 )
 
 // vulnerabilityCmd represents the vulnerability command
 var vulnerabilityCmd = &cobra.Command{
-	Use:   "vulnerability",
+	Use:   "vulnerability [IMAGE]",
 	Short: "Get vulnerability details of the image. (NOTE: need Clair service deployed)",
 	Long:  `Call Clair API to get the vulnerability based on the previous successful scan.`,
+	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := resolveImage(args, &repoTagVul.repoName, &repoTagVul.tag); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
 		getVulnerabilityDetails()
 	},
 }
@@ -46,14 +54,12 @@ func init() {
 	vulnerabilityCmd.Flags().StringVarP(&repoTagVul.repoName,
 		"repo_name",
 		"r", "",
-		"(REQUIRED) The name of repository.")
-	vulnerabilityCmd.MarkFlagRequired("repo_name")
+		"The name of repository. (REQUIRED if IMAGE is not given)")
 
 	vulnerabilityCmd.Flags().StringVarP(&repoTagVul.tag,
 		"tag",
 		"t", "",
-		"(REQUIRED) The name of tag.")
-	vulnerabilityCmd.MarkFlagRequired("tag")
+		"The name of tag. (REQUIRED if IMAGE is not given)")
 }
 
 func getVulnerabilityDetails() {
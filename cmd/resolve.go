@@ -0,0 +1,374 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/moooofly/harborctl/utils"
+)
+
+// namedResource is a resource which may be referred to by name.
+type namedResource struct {
+	ID   int64
+	Name string
+	// Note tells the resources of the same name apart, e.g. the scope of a label.
+	Note string
+}
+
+// nameResolvers list the resources of each kind which may be named name, e.g.
+// by a fuzzy query, and the exact matches are picked by resolveName. The
+// labels, members and policies are of the project of projectID if not 0.
+var nameResolvers = map[string]func(name string, projectID int64) ([]namedResource, error){
+	"project":   resolveProjects,
+	"user":      resolveUsers,
+	"usergroup": resolveUsergroups,
+	"member":    resolveMembers,
+	"robot":     resolveRobots,
+	"webhook":   resolveWebhooks,
+	"role":      resolveRoles,
+	"label":     resolveLabels,
+	"target":    resolveTargets,
+	"policy":    resolvePolicies,
+}
+
+// resolveName resolves the name of a resource of kind to its ID. An error is
+// returned if no resource or more than one is named so.
+func resolveName(kind, name string, projectID int64) (int64, error) {
+	resources, err := nameResolvers[kind](name, projectID)
+	if err != nil {
+		return 0, err
+	}
+
+	var matches []namedResource
+	for _, r := range resources {
+		if r.Name == name {
+			matches = append(matches, r)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("no %s named %q", kind, name)
+	case 1:
+		return matches[0].ID, nil
+	}
+
+	var ids []string
+	for _, m := range matches {
+		s := strconv.FormatInt(m.ID, 10)
+		if m.Note != "" {
+			s += " (" + m.Note + ")"
+		}
+		ids = append(ids, s)
+	}
+	sort.Strings(ids)
+	return 0, fmt.Errorf("%s name %q is ambiguous, use one of the IDs: %s", kind, name, strings.Join(ids, ", "))
+}
+
+func resolveProjects(name string, projectID int64) ([]namedResource, error) {
+	var projects []projectBrief
+	if err := utils.GetStruct(utils.URLGen("/api/projects")+"?name="+url.QueryEscape(name), &projects); err != nil {
+		return nil, err
+	}
+
+	var resources []namedResource
+	for _, p := range projects {
+		resources = append(resources, namedResource{p.ProjectID, p.Name, ""})
+	}
+	return resources, nil
+}
+
+func resolveUsers(name string, projectID int64) ([]namedResource, error) {
+	var users []userBrief
+	if err := utils.GetStruct(utils.URLGen("/api/users")+"?username="+url.QueryEscape(name), &users); err != nil {
+		return nil, err
+	}
+
+	var resources []namedResource
+	for _, u := range users {
+		resources = append(resources, namedResource{u.UserID, u.Username, u.Email})
+	}
+	return resources, nil
+}
+
+func resolveUsergroups(name string, projectID int64) ([]namedResource, error) {
+	var groups []struct {
+		ID          int64  `json:"id"`
+		GroupName   string `json:"group_name"`
+		LdapGroupDN string `json:"ldap_group_dn"`
+	}
+	if err := utils.GetStruct(utils.URLGen("/api/usergroups"), &groups); err != nil {
+		return nil, err
+	}
+
+	var resources []namedResource
+	for _, g := range groups {
+		resources = append(resources, namedResource{g.ID, g.GroupName, g.LdapGroupDN})
+	}
+	return resources, nil
+}
+
+func resolveMembers(name string, projectID int64) ([]namedResource, error) {
+	if projectID == 0 {
+		return nil, fmt.Errorf("the project is required to find the member %q", name)
+	}
+	members, err := listProjectMembers(projectID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []namedResource
+	for _, m := range members {
+		note := "user"
+		if m.EntityType == "g" {
+			note = "group"
+		}
+		resources = append(resources, namedResource{m.ID, m.EntityName, note})
+	}
+	return resources, nil
+}
+
+// resolveRobots lists the robot accounts of the project, which may be named
+// without the prefix 'robot$' too.
+func resolveRobots(name string, projectID int64) ([]namedResource, error) {
+	if projectID == 0 {
+		return nil, fmt.Errorf("the project is required to find the robot account %q", name)
+	}
+	robots, err := listRobots(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []namedResource
+	for _, r := range robots {
+		resources = append(resources, namedResource{r.ID, r.Name, ""})
+		if short := strings.TrimPrefix(r.Name, "robot$"); short != r.Name {
+			resources = append(resources, namedResource{r.ID, short, ""})
+		}
+	}
+	return resources, nil
+}
+
+func resolveWebhooks(name string, projectID int64) ([]namedResource, error) {
+	if projectID == 0 {
+		return nil, fmt.Errorf("the project is required to find the webhook policy %q", name)
+	}
+	policies, err := listWebhookPolicies(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []namedResource
+	for _, p := range policies {
+		resources = append(resources, namedResource{p.ID, p.Name, ""})
+	}
+	return resources, nil
+}
+
+func resolveRoles(name string, projectID int64) ([]namedResource, error) {
+	id, err := parseProjectRole(name)
+	if err != nil {
+		return nil, err
+	}
+	return []namedResource{{id, name, ""}}, nil
+}
+
+// resolveLabels lists the global labels, and the labels of the project. If
+// projectID is 0, the labels of all the projects are listed only if no global
+// label is named name.
+func resolveLabels(name string, projectID int64) ([]namedResource, error) {
+	ctx := utils.CurrentContext()
+
+	labels, err := listLabels(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	projectIDs := []int64{projectID}
+	if projectID == 0 {
+		for _, l := range labels {
+			if l.Name == name {
+				return labelResources(labels), nil
+			}
+		}
+
+		projects, err := listProjects()
+		if err != nil {
+			return nil, err
+		}
+		projectIDs = projectIDs[:0]
+		for _, p := range projects {
+			projectIDs = append(projectIDs, p.ProjectID)
+		}
+	}
+	for _, id := range projectIDs {
+		projectLabels, err := listLabels(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, projectLabels...)
+	}
+	return labelResources(labels), nil
+}
+
+func labelResources(labels []labelBrief) []namedResource {
+	var resources []namedResource
+	for _, l := range labels {
+		note := "global"
+		if l.Scope == "p" {
+			note = "project " + strconv.FormatInt(l.ProjectID, 10)
+		}
+		resources = append(resources, namedResource{l.ID, l.Name, note})
+	}
+	return resources
+}
+
+func resolveTargets(name string, projectID int64) ([]namedResource, error) {
+	var targets []targetBrief
+	if err := utils.GetStruct(utils.URLGen("/api/targets")+"?name="+url.QueryEscape(name), &targets); err != nil {
+		return nil, err
+	}
+
+	var resources []namedResource
+	for _, t := range targets {
+		resources = append(resources, namedResource{t.ID, t.Name, t.Endpoint})
+	}
+	return resources, nil
+}
+
+func resolvePolicies(name string, projectID int64) ([]namedResource, error) {
+	targetURL := utils.URLGen("/api/policies/replication") + "?name=" + url.QueryEscape(name)
+	if projectID != 0 {
+		targetURL += "&project_id=" + strconv.FormatInt(projectID, 10)
+	}
+
+	var policies []struct {
+		ID       int64  `json:"id"`
+		Name     string `json:"name"`
+		Projects []struct {
+			Name string `json:"name"`
+		} `json:"projects"`
+	}
+	if err := utils.GetStruct(targetURL, &policies); err != nil {
+		return nil, err
+	}
+
+	var resources []namedResource
+	for _, p := range policies {
+		var note string
+		if len(p.Projects) > 0 {
+			note = "project " + p.Projects[0].Name
+		}
+		resources = append(resources, namedResource{p.ID, p.Name, note})
+	}
+	return resources, nil
+}
+
+// idValue is the value of a flag of the ID of a resource, which accepts the
+// name of the resource too, prefixed by 'name:' if it is all digits, e.g.
+// 'name:2018'. The name is resolved to the ID by resolveNamedIDs after the
+// configuration is loaded.
+type idValue struct {
+	id   *int64
+	kind string
+	name string
+	// projectID is the ID of the project the resource is of, if the kind is
+	// scoped by project, see nameResolvers.
+	projectID *int64
+}
+
+// idNamePrefix marks the value of an ID flag as a name, for the names which
+// are all digits.
+const idNamePrefix = "name:"
+
+// pendingIDs are the flags given names, to be resolved.
+var pendingIDs []*idValue
+
+func newIDValue(id *int64, kind string) *idValue {
+	return &idValue{id: id, kind: kind}
+}
+
+// inProject scopes the resolving of the name by the project of projectID.
+func (v *idValue) inProject(projectID *int64) *idValue {
+	v.projectID = projectID
+	return v
+}
+
+func (v *idValue) Set(s string) error {
+	if name := strings.TrimPrefix(s, idNamePrefix); name != s {
+		s = name
+	} else if id, err := strconv.ParseInt(s, 10, 64); err == nil {
+		*v.id, v.name = id, ""
+		return nil
+	}
+	if s == "" {
+		return fmt.Errorf("empty %s ID or name", v.kind)
+	}
+
+	if v.name == "" {
+		pendingIDs = append(pendingIDs, v)
+	}
+	v.name = s
+	return nil
+}
+
+func (v *idValue) String() string {
+	if v.name != "" {
+		return v.name
+	}
+	return strconv.FormatInt(*v.id, 10)
+}
+
+func (v *idValue) Type() string {
+	return v.kind
+}
+
+// resolveNamedIDs resolves the names given to the ID flags. The projects are
+// resolved first, as the other kinds may be scoped by them.
+func resolveNamedIDs() {
+	sort.SliceStable(pendingIDs, func(i, j int) bool {
+		return pendingIDs[i].kind == "project" && pendingIDs[j].kind != "project"
+	})
+
+	for _, v := range pendingIDs {
+		if v.name == "" {
+			continue
+		}
+
+		var projectID int64
+		if v.projectID != nil {
+			projectID = *v.projectID
+		}
+		id, err := resolveName(v.kind, v.name, projectID)
+		if err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+		*v.id, v.name = id, ""
+	}
+	pendingIDs = nil
+}
@@ -0,0 +1,59 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import "testing"
+
+func TestIDValueSet(t *testing.T) {
+	defer func() { pendingIDs = nil }()
+
+	tests := []struct {
+		in   string
+		id   int64
+		name string
+	}{
+		{"12", 12, ""},
+		{"0", 0, ""},
+		{"library", -1, "library"},
+		{"name:2018", -1, "2018"},
+		{"name:library", -1, "library"},
+		{"name:name:x", -1, "name:x"},
+		{"12a", -1, "12a"},
+	}
+	for _, tt := range tests {
+		id := int64(-1)
+		v := newIDValue(&id, "project")
+		if err := v.Set(tt.in); err != nil {
+			t.Errorf("Set(%q): %v", tt.in, err)
+			continue
+		}
+		if id != tt.id || v.name != tt.name {
+			t.Errorf("Set(%q): id = %d, name = %q, want %d, %q", tt.in, id, v.name, tt.id, tt.name)
+		}
+	}
+
+	for _, in := range []string{"", "name:"} {
+		id := int64(-1)
+		if err := newIDValue(&id, "project").Set(in); err == nil {
+			t.Errorf("Set(%q) succeeded, want error", in)
+		}
+	}
+}
@@ -37,7 +37,9 @@ var address string
 var rootCmd = &cobra.Command{
 	Use:   "harborctl",
 	Short: "A CLI tool for the Docker Registry Harbor.",
-	Long:  `This project offer a command-line interface to the Harbor API, you can use it to manager your users, projects, repositories, etc.`,
+	Long: `This project offer a command-line interface to the Harbor API, you can use it to manager your users, projects, repositories, etc.
+
+The flags of IDs accept the names of the resources too, and a name of all digits is given as 'name:2018'.`,
 	Version: fmt.Sprintf("%s\n%s\n| % -20s | % -40s |\n| % -20s | % -40s |\n| % -20s | % -40s |\n| % -20s | % -40s |\n| % -20s | % -40s |\n| % -20s | % -40s |\n%s\n",
 		utils.Logo,
 		utils.Mark,
@@ -63,7 +65,7 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
+	cobra.OnInitialize(initConfig, resolveNamedIDs)
 
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
@@ -0,0 +1,226 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// trustCmd represents the trust command
+var trustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "Verify the content trust of images.",
+	Long: `The subcommand of verifying the images signed by Notary against the manifests in the registry.
+
+The status of a tag is one of:
+
+  signed    the digest signed is the digest of the manifest
+  unsigned  the tag is not signed
+  mismatch  the digest signed is not the digest of the manifest, e.g. the tag was pushed again without signing, and pulling it with content trust fails
+  signed (digest not verifiable)
+            the tag is signed, but the digest signed is unknown, as Harbor 2.x does not expose it
+
+NOTE: for the raw signatures of a repository, see 'harborctl repository signature'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Use \"harborctl trust --help\" for more information about this command.")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(trustCmd)
+}
+
+// trustResult is the content trust status of a tag.
+type trustResult struct {
+	Repository string
+	Tag        string
+	// Digest is the digest of the manifest.
+	Digest string
+	// Signed is the digest signed, empty if the tag is not signed, or
+	// unverifiableDigest if the digest signed is unknown.
+	Signed string
+}
+
+// unverifiableDigest is the digest signed of the tags which are signed, but
+// the digest signed is not exposed, as by Harbor 2.x.
+const unverifiableDigest = "?"
+
+func (r *trustResult) Status() string {
+	switch {
+	case r.Signed == "":
+		return "unsigned"
+	case r.Signed == unverifiableDigest:
+		return "signed (digest not verifiable)"
+	case r.Signed != r.Digest:
+		return "mismatch"
+	default:
+		return "signed"
+	}
+}
+
+// fetchSignedDigests returns the digests signed of the tags of the repository
+// by tag, from the Notary instance of Harbor.
+func fetchSignedDigests(repo string) (map[string]string, error) {
+	var signatures []struct {
+		Tag    string            `json:"tag"`
+		Hashes map[string]string `json:"hashes"`
+	}
+	if err := utils.GetStruct(utils.URLGen("/api/repositories/"+repo+"/signatures"), &signatures); err != nil {
+		return nil, err
+	}
+
+	digests := make(map[string]string)
+	for _, s := range signatures {
+		if s.Hashes["sha256"] == "" {
+			digests[s.Tag] = unverifiableDigest
+			continue
+		}
+		// NOTE: the hashes are base64 encoded, while the digests are hex encoded.
+		h, err := base64.StdEncoding.DecodeString(s.Hashes["sha256"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature of %s:%s: %v", repo, s.Tag, err)
+		}
+		digests[s.Tag] = "sha256:" + hex.EncodeToString(h)
+	}
+	return digests, nil
+}
+
+// verifyTags returns the content trust status of the tags of the repository,
+// or all the tags if none given.
+func verifyTags(repo string, digests map[string]string, tags ...string) ([]*trustResult, error) {
+	signed, err := fetchSignedDigests(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tags) == 0 {
+		for tag := range digests {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+	}
+
+	var results []*trustResult
+	for _, tag := range tags {
+		digest, ok := digests[tag]
+		if !ok {
+			return nil, fmt.Errorf("no tag %s:%s", repo, tag)
+		}
+		results = append(results, &trustResult{repo, tag, digest, signed[tag]})
+	}
+	return results, nil
+}
+
+// fetchTagDigests returns the manifest digests of the tags of the repository
+// by tag.
+func fetchTagDigests(repo string) (map[string]string, error) {
+	var tags []tagDetail
+	if err := utils.GetStruct(utils.URLGen("/api/repositories/"+repo+"/tags"), &tags); err != nil {
+		return nil, err
+	}
+
+	digests := make(map[string]string)
+	for _, t := range tags {
+		digests[t.Name] = t.Digest
+	}
+	return digests, nil
+}
+
+// trustVerifyCmd represents the verify command
+var trustVerifyCmd = &cobra.Command{
+	Use:   "verify IMAGE...",
+	Short: "Verify the content trust of images.",
+	Long: `This command compares the digest signed in Notary with the digest of the manifest for each IMAGE of format 'project/repo:tag', or for all the tags of 'project/repo'.
+
+It exits with 1 if any image is unsigned or mismatched, e.g. for gating a deployment. With Harbor 2.x, which does not expose the digests signed, the signed images are 'signed (digest not verifiable)' and are not taken as trusted either.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := verifyTrust(args); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	trustCmd.AddCommand(trustVerifyCmd)
+}
+
+func verifyTrust(args []string) error {
+	var results []*trustResult
+	for _, arg := range args {
+		ref, err := parseImage(arg)
+		if err != nil {
+			return err
+		}
+		if ref.Digest != "" {
+			return fmt.Errorf("image %q must be referred by tag, not digest", arg)
+		}
+
+		digests, err := fetchTagDigests(ref.Repository)
+		if err != nil {
+			return err
+		}
+		var tags []string
+		if ref.Tag != "" {
+			tags = append(tags, ref.Tag)
+		}
+		rs, err := verifyTags(ref.Repository, digests, tags...)
+		if err != nil {
+			return err
+		}
+		results = append(results, rs...)
+	}
+
+	untrusted := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "IMAGE\tDIGEST\tSIGNED DIGEST\tSTATUS")
+	for _, r := range results {
+		if r.Status() != "signed" {
+			untrusted++
+		}
+		printTrustResult(w, r)
+	}
+	w.Flush()
+
+	if untrusted > 0 {
+		return fmt.Errorf("%d of %d images are not trusted", untrusted, len(results))
+	}
+	return nil
+}
+
+// printTrustResult writes a row of 'IMAGE DIGEST SIGNED DIGEST STATUS' to w.
+func printTrustResult(w io.Writer, r *trustResult) {
+	signed := "-"
+	if r.Signed != "" && r.Signed != unverifiableDigest {
+		signed = shortDigest(r.Signed)
+	}
+	fmt.Fprintf(w, "%s:%s\t%s\t%s\t%s\n", r.Repository, r.Tag, shortDigest(r.Digest), signed, r.Status())
+}
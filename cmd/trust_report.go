@@ -0,0 +1,139 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// trustReportCmd represents the report command
+var trustReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report the images not trusted in the projects with content trust enabled.",
+	Long: `This command verifies all the tags in the projects with 'enable_content_trust' on, or in the projects of '--project', and lists the unsigned and mismatched ones, see 'harborctl trust --help'.
+
+It exits with 1 if any tag is mismatched, as pulling it with content trust fails.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := reportTrust(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var trustReport struct {
+	projects []string
+	all      bool
+}
+
+func init() {
+	trustCmd.AddCommand(trustReportCmd)
+
+	trustReportCmd.Flags().StringSliceVarP(&trustReport.projects,
+		"project",
+		"p", nil,
+		"The names of the projects, separated by commas or by repeating the flag, default is all the projects with content trust enabled.")
+
+	trustReportCmd.Flags().BoolVarP(&trustReport.all,
+		"all",
+		"a", false,
+		"List the signed tags too.")
+}
+
+func reportTrust() error {
+	projects, err := listProjects()
+	if err != nil {
+		return err
+	}
+
+	var selected []projectBrief
+	for _, name := range trustReport.projects {
+		found := false
+		for _, p := range projects {
+			if p.Name == name {
+				selected, found = append(selected, p), true
+			}
+		}
+		if !found {
+			return fmt.Errorf("no project named %q", name)
+		}
+	}
+	if len(trustReport.projects) == 0 {
+		for _, p := range projects {
+			if p.Metadata.EnableContentTrust == "true" {
+				selected = append(selected, p)
+			}
+		}
+		if len(selected) == 0 {
+			fmt.Println("no project has content trust enabled")
+			return nil
+		}
+	}
+
+	counts := make(map[string]int)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "IMAGE\tDIGEST\tSIGNED DIGEST\tSTATUS")
+	for _, p := range selected {
+		digests, err := contextTagDigests(utils.CurrentContext(), p.ProjectID)
+		if err != nil {
+			w.Flush()
+			return err
+		}
+
+		var repos []string
+		for repo := range digests {
+			repos = append(repos, repo)
+		}
+		sort.Strings(repos)
+
+		for _, repo := range repos {
+			results, err := verifyTags(repo, digests[repo])
+			if err != nil {
+				w.Flush()
+				return err
+			}
+			for _, r := range results {
+				counts[r.Status()]++
+				if trustReport.all || r.Status() != "signed" {
+					printTrustResult(w, r)
+				}
+			}
+		}
+	}
+	w.Flush()
+
+	fmt.Printf("\nprojects: %d, signed: %d, unsigned: %d, mismatch: %d",
+		len(selected), counts["signed"], counts["unsigned"], counts["mismatch"])
+	if n := counts["signed (digest not verifiable)"]; n > 0 {
+		fmt.Printf(", signed (digest not verifiable): %d", n)
+	}
+	fmt.Println()
+	if counts["mismatch"] > 0 {
+		return fmt.Errorf("%d tags are mismatched with their signatures", counts["mismatch"])
+	}
+	return nil
+}
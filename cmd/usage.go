@@ -0,0 +1,345 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/moooofly/harborctl/utils/registry"
+	"github.com/spf13/cobra"
+)
+
+// usageCmd represents the usage command
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Compute storage usage per project, repository or tag.",
+	Long: `This command computes the storage used by each project, repository or tag, by summing the sizes of the config and layers of image manifests, and ranks the biggest consumers.
+
+NOTE:
+- A blob shared by several tags is counted once in the usage of a repository, and a blob shared by several repositories is counted once in the usage of a project, which is what the registry really stores.
+- The usage of a tag is the whole size of its image, so the usages of tags do not sum up to the one of their repository.
+- Blobs of docker schema1 manifests are not known, the size reported by Harbor is used instead.
+- The requests are traced to stderr, so that CSV and JSON can be piped safely.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := getUsage(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var usage struct {
+	level    string
+	projects string
+	top      int
+	format   string
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+
+	usageCmd.Flags().StringVarP(&usage.level,
+		"level",
+		"l", "project",
+		"The level to account on, valid values are 'project', 'repository' and 'tag'.")
+
+	usageCmd.Flags().StringVarP(&usage.projects,
+		"project",
+		"", "",
+		"The names of projects to account only. NOTE: you can specify multiple names separated by comma.")
+
+	usageCmd.Flags().IntVarP(&usage.top,
+		"top",
+		"t", 0,
+		"Show the N biggest consumers only. (all if not set)")
+
+	usageCmd.Flags().StringVarP(&usage.format,
+		"format",
+		"f", "table",
+		"The output format, valid values are 'table', 'csv' and 'json'.")
+}
+
+// usageEntry is the storage usage of a project, a repository or a tag.
+type usageEntry struct {
+	Project      string `json:"project"`
+	Repository   string `json:"repository,omitempty"`
+	Tag          string `json:"tag,omitempty"`
+	Repositories int    `json:"repositories,omitempty"`
+	Tags         int    `json:"tags,omitempty"`
+	Size         int64  `json:"size"`
+}
+
+// blobSet is a set of blobs by digest, with their sizes.
+type blobSet map[string]int64
+
+func (s blobSet) add(o blobSet) {
+	for d, size := range o {
+		s[d] = size
+	}
+}
+
+func (s blobSet) size() int64 {
+	var n int64
+	for _, size := range s {
+		n += size
+	}
+	return n
+}
+
+func getUsage() error {
+	switch usage.level {
+	case "project", "repository", "tag":
+	default:
+		return fmt.Errorf("level must be one of [project|repository|tag], got %q", usage.level)
+	}
+	switch usage.format {
+	case "table", "csv", "json":
+	default:
+		return fmt.Errorf("format must be one of [table|csv|json], got %q", usage.format)
+	}
+
+	projects, err := listUsageProjects()
+	if err != nil {
+		return err
+	}
+
+	var entries []usageEntry
+	total := blobSet{}
+	for _, p := range projects {
+		es, blobs, err := projectUsage(p.Name, p.ProjectID)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, es...)
+		total.add(blobs)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Size > entries[j].Size
+	})
+	if usage.top > 0 && len(entries) > usage.top {
+		entries = entries[:usage.top]
+	}
+
+	return printUsage(entries, total.size())
+}
+
+func listUsageProjects() ([]projectBrief, error) {
+	projects, err := listProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	if usage.projects == "" {
+		return projects, nil
+	}
+
+	byName := make(map[string]projectBrief)
+	for _, p := range projects {
+		byName[p.Name] = p
+	}
+
+	var selected []projectBrief
+	for _, name := range strings.Split(usage.projects, ",") {
+		p, ok := byName[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("no project named %q", name)
+		}
+		selected = append(selected, p)
+	}
+	return selected, nil
+}
+
+// projectUsage computes the usage of a project, and returns the entries of
+// the level asked for with the blobs stored by the project.
+func projectUsage(name string, id int64) ([]usageEntry, blobSet, error) {
+	var repos []struct {
+		Name string `json:"name"`
+	}
+	targetURL := utils.URLGen("/api/repositories") + "?project_id=" + strconv.FormatInt(id, 10)
+	err := utils.GetPages(targetURL, 100, func(pageURL string) (int, error) {
+		var page []struct {
+			Name string `json:"name"`
+		}
+		if err := utils.GetStruct(pageURL, &page); err != nil {
+			return 0, err
+		}
+		repos = append(repos, page...)
+		return len(page), nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var entries []usageEntry
+	projectBlobs := blobSet{}
+	var tagCount int
+	for _, r := range repos {
+		var tags []tagDetail
+		if err := utils.GetStruct(utils.URLGen("/api/repositories")+"/"+r.Name+"/tags", &tags); err != nil {
+			return nil, nil, err
+		}
+
+		repoBlobs := blobSet{}
+		// NOTE: tags of the same digest share one manifest.
+		manifests := make(map[string]blobSet)
+		for _, t := range tags {
+			blobs, ok := manifests[t.Digest]
+			if !ok {
+				if blobs, err = manifestBlobs(r.Name, t); err != nil {
+					return nil, nil, err
+				}
+				manifests[t.Digest] = blobs
+			}
+			repoBlobs.add(blobs)
+
+			if usage.level == "tag" {
+				entries = append(entries, usageEntry{Project: name, Repository: r.Name, Tag: t.Name, Size: blobs.size()})
+			}
+		}
+
+		if usage.level == "repository" {
+			entries = append(entries, usageEntry{Project: name, Repository: r.Name, Tags: len(tags), Size: repoBlobs.size()})
+		}
+		projectBlobs.add(repoBlobs)
+		tagCount += len(tags)
+	}
+
+	if usage.level == "project" {
+		entries = append(entries, usageEntry{Project: name, Repositories: len(repos), Tags: tagCount, Size: projectBlobs.size()})
+	}
+	return entries, projectBlobs, nil
+}
+
+// manifestBlobs returns the config and layers of the manifest of tag, which
+// are of all the manifests of a manifest list or an image index, fetched by
+// their digests.
+func manifestBlobs(repo string, tag tagDetail) (blobSet, error) {
+	var resp struct {
+		Manifest json.RawMessage `json:"manifest"`
+	}
+	targetURL := utils.URLGen("/api/repositories") + "/" + repo + "/tags/" + url.PathEscape(tag.Name) + "/manifest?version=v2"
+	if err := utils.GetStruct(targetURL, &resp); err != nil {
+		return nil, err
+	}
+
+	mediaType := registry.DetectMediaType(resp.Manifest)
+	switch {
+	case registry.IsIndex(mediaType):
+		var idx registry.Index
+		if err := json.Unmarshal(resp.Manifest, &idx); err != nil {
+			return nil, err
+		}
+		blobs := blobSet{}
+		for _, d := range idx.Manifests {
+			b, err := manifestBlobs(repo, tagDetail{Name: d.Digest, Digest: d.Digest, Size: d.Size})
+			if err != nil {
+				return nil, fmt.Errorf("manifest %s in the index of %s:%s: %v", d.Digest, repo, tag.Name, err)
+			}
+			blobs.add(b)
+		}
+		return blobs, nil
+
+	case registry.IsSchema1(mediaType):
+		if tag.Digest == "" {
+			return nil, errors.New("no digest of " + repo + ":" + tag.Name)
+		}
+		return blobSet{tag.Digest: tag.Size}, nil
+	}
+
+	var m registry.Manifest
+	if err := json.Unmarshal(resp.Manifest, &m); err != nil {
+		return nil, err
+	}
+	if m.Config.Digest == "" {
+		return nil, fmt.Errorf("no config in the manifest of %s:%s, of media type %q", repo, tag.Name, mediaType)
+	}
+
+	blobs := blobSet{m.Config.Digest: m.Config.Size}
+	for _, l := range m.Layers {
+		blobs[l.Digest] = l.Size
+	}
+	return blobs, nil
+}
+
+func printUsage(entries []usageEntry, total int64) error {
+	switch usage.format {
+	case "json":
+		if entries == nil {
+			entries = []usageEntry{}
+		}
+		b, err := json.MarshalIndent(struct {
+			Level   string       `json:"level"`
+			Total   int64        `json:"total"`
+			Entries []usageEntry `json:"entries"`
+		}{usage.level, total, entries}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"project", "repository", "tag", "repositories", "tags", "size"})
+		for _, e := range entries {
+			w.Write([]string{
+				e.Project, e.Repository, e.Tag,
+				strconv.Itoa(e.Repositories), strconv.Itoa(e.Tags),
+				strconv.FormatInt(e.Size, 10),
+			})
+		}
+		w.Flush()
+		return w.Error()
+
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		switch usage.level {
+		case "project":
+			fmt.Fprintln(w, "PROJECT\tREPOSITORIES\tTAGS\tSIZE")
+			for _, e := range entries {
+				fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", e.Project, e.Repositories, e.Tags, humanSize(e.Size))
+			}
+		case "repository":
+			fmt.Fprintln(w, "REPOSITORY\tTAGS\tSIZE")
+			for _, e := range entries {
+				fmt.Fprintf(w, "%s\t%d\t%s\n", e.Repository, e.Tags, humanSize(e.Size))
+			}
+		case "tag":
+			fmt.Fprintln(w, "IMAGE\tSIZE")
+			for _, e := range entries {
+				fmt.Fprintf(w, "%s:%s\t%s\n", e.Repository, e.Tag, humanSize(e.Size))
+			}
+		}
+		w.Flush()
+		fmt.Println("Total:", humanSize(total))
+	}
+	return nil
+}
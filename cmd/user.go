@@ -120,10 +120,10 @@ var userGet struct {
 func initUserGet() {
 	userCmd.AddCommand(userGetCmd)
 
-	userGetCmd.Flags().Int64VarP(&userGet.userID,
+	userGetCmd.Flags().VarP(newIDValue(&userGet.userID, "user"),
 		"user_id",
-		"i", 0,
-		"(REQUIRED) Registered user ID.")
+		"i",
+		"(REQUIRED) Registered user ID or username.")
 	userGetCmd.MarkFlagRequired("user_id")
 }
 
@@ -149,10 +149,10 @@ var userDelete struct {
 func initUserDelete() {
 	userCmd.AddCommand(userDeleteCmd)
 
-	userDeleteCmd.Flags().Int64VarP(&userDelete.userID,
+	userDeleteCmd.Flags().VarP(newIDValue(&userDelete.userID, "user"),
 		"user_id",
-		"i", 0,
-		"(REQUIRED) Registered user ID.")
+		"i",
+		"(REQUIRED) Registered user ID or username.")
 	userDeleteCmd.MarkFlagRequired("user_id")
 }
 
@@ -298,10 +298,10 @@ var userUpdate struct {
 func initUserUpdate() {
 	userCmd.AddCommand(userUpdateCmd)
 
-	userUpdateCmd.Flags().Int64VarP(&userUpdate.userID,
+	userUpdateCmd.Flags().VarP(newIDValue(&userUpdate.userID, "user"),
 		"user_id",
-		"i", 0,
-		"(REQUIRED) Registered user ID.")
+		"i",
+		"(REQUIRED) Registered user ID or username.")
 	userUpdateCmd.MarkFlagRequired("user_id")
 
 	userUpdateCmd.Flags().StringVarP(&userUpdate.Email,
@@ -333,3 +333,28 @@ func updateUser() {
 
 	utils.Put(targetURL, string(p))
 }
+
+// userBrief is a user in the list of users.
+type userBrief struct {
+	UserID       int64  `json:"user_id"`
+	Username     string `json:"username"`
+	Email        string `json:"email"`
+	Realname     string `json:"realname"`
+	Comment      string `json:"comment"`
+	HasAdminRole bool   `json:"has_admin_role"`
+	CreationTime string `json:"creation_time"`
+}
+
+// listUsers lists all registered users (not include admin user), page by page.
+func listUsers() ([]userBrief, error) {
+	var users []userBrief
+	err := utils.GetPages(utils.URLGen("/api/users"), 100, func(pageURL string) (int, error) {
+		var page []userBrief
+		if err := utils.GetStruct(pageURL, &page); err != nil {
+			return 0, err
+		}
+		users = append(users, page...)
+		return len(page), nil
+	})
+	return users, err
+}
@@ -0,0 +1,177 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// userDeactivateCmd represents the deactivate command
+var userDeactivateCmd = &cobra.Command{
+	Use:   "deactivate",
+	Short: "Deactivate users which have been inactive for a time, by deleting them, which can not be undone.",
+	Long: `This command finds the users which have no access logs (push, pull, create or delete) since '--inactive_since' and were registered before it, and deactivates them.
+
+The candidates are only listed by default. With '--yes', they are listed and deleted after confirmed a second time, by typing the number of them, or by '--confirm' for scripts, which must be the number listed by a dry run.
+
+NOTE:
+- The users are deleted by 'user delete', as Harbor has no API to disable a user. It marks them as removed, which can not be undone by API.
+- Access logs purged, e.g. by the log rotation of Harbor, are not taken into account, so that the users active before the logs kept are taken as inactive. Check the list before confirming.
+- Activities like logging in the UI are not recorded in access logs, and not taken into account.
+- Administrators are skipped unless '--include_admin' is set.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := deactivateUsers(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var userDeactivate struct {
+	inactiveSince string
+	includeAdmin  bool
+	yes           bool
+	confirm       int
+}
+
+func init() {
+	userCmd.AddCommand(userDeactivateCmd)
+
+	userDeactivateCmd.Flags().StringVarP(&userDeactivate.inactiveSince,
+		"inactive_since",
+		"s", "",
+		"(REQUIRED) The time since which users have been inactive, e.g. '90d' or '2018-01-01'.")
+	userDeactivateCmd.MarkFlagRequired("inactive_since")
+
+	userDeactivateCmd.Flags().BoolVarP(&userDeactivate.includeAdmin,
+		"include_admin",
+		"", false,
+		"Deactivate inactive administrators too.")
+
+	userDeactivateCmd.Flags().BoolVarP(&userDeactivate.yes,
+		"yes",
+		"y", false,
+		"Delete the users indeed after confirmed, rather than list them only.")
+
+	userDeactivateCmd.Flags().IntVarP(&userDeactivate.confirm,
+		"confirm",
+		"", -1,
+		"Confirm deleting the users without prompting, by the number of them listed by a dry run.")
+}
+
+func deactivateUsers() error {
+	since, err := utils.ParseTimeExpr(userDeactivate.inactiveSince, time.Now())
+	if err != nil {
+		return err
+	}
+
+	logs, err := fetchAuditLogs(&auditFilter{pageSize: 100}, since.Unix(), 0, 0)
+	if err != nil {
+		return err
+	}
+	active := make(map[string]bool)
+	for _, l := range logs {
+		active[l.Username] = true
+	}
+
+	users, err := listUsers()
+	if err != nil {
+		return err
+	}
+
+	var inactive []userBrief
+	for _, u := range users {
+		if active[u.Username] || (u.HasAdminRole && !userDeactivate.includeAdmin) {
+			continue
+		}
+		if created, err := time.Parse(time.RFC3339, u.CreationTime); err == nil && created.After(since) {
+			continue
+		}
+		inactive = append(inactive, u)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "USER_ID\tUSERNAME\tEMAIL\tCREATED")
+	for _, u := range inactive {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", u.UserID, u.Username, u.Email, u.CreationTime)
+	}
+	w.Flush()
+
+	fmt.Printf("<== %d of %d users inactive since %s\n", len(inactive), len(users), since.Format(time.RFC3339))
+	if len(inactive) == 0 {
+		return nil
+	}
+	if !userDeactivate.yes {
+		fmt.Println("<== Nothing is changed, set '--yes' to delete them.")
+		return nil
+	}
+	if err := confirmDeactivate(len(inactive)); err != nil {
+		return err
+	}
+
+	var failed int
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "USER_ID\tUSERNAME\tRESULT")
+	for _, u := range inactive {
+		result := "deleted"
+		if _, _, err := utils.Send("DELETE", utils.URLGen("/api/users/"+strconv.FormatInt(u.UserID, 10)), nil); err != nil {
+			result = "failed, error: " + err.Error()
+			failed++
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", u.UserID, u.Username, result)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// confirmDeactivate confirms deleting n users by '--confirm', or by typing
+// n if not set.
+func confirmDeactivate(n int) error {
+	confirm := userDeactivate.confirm
+	if confirm < 0 {
+		fmt.Printf("==> Delete the %d users above, which can not be undone? Type the number of them to confirm: ", n)
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("not confirmed, nothing is changed: %v", err)
+		}
+		if confirm, err = strconv.Atoi(strings.TrimSpace(line)); err != nil {
+			return errors.New("not confirmed, nothing is changed")
+		}
+	}
+	if confirm != n {
+		return fmt.Errorf("not confirmed by %d, which must be the number of users listed, %d, nothing is changed", confirm, n)
+	}
+	return nil
+}
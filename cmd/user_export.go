@@ -0,0 +1,129 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// userExportCmd represents the export command
+var userExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export users with their admin flags and project memberships as CSV.",
+	Long: `This command exports the registered users (not include admin user) as CSV, in the format which 'user import' reads. The password column is left empty.
+
+NOTE: collecting memberships walks the members of all projects, use '--memberships=false' to skip it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := exportUsers(); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var userExport struct {
+	file        string
+	memberships bool
+}
+
+func init() {
+	userCmd.AddCommand(userExportCmd)
+
+	userExportCmd.Flags().StringVarP(&userExport.file,
+		"file",
+		"f", "",
+		"The file to write into. (stdout if not set)")
+
+	userExportCmd.Flags().BoolVarP(&userExport.memberships,
+		"memberships",
+		"m", true,
+		"Export the project memberships of users.")
+}
+
+func exportUsers() error {
+	users, err := listUsers()
+	if err != nil {
+		return err
+	}
+
+	memberships := make(map[string][]string)
+	if userExport.memberships {
+		projects, err := listProjects()
+		if err != nil {
+			return err
+		}
+		for _, p := range projects {
+			members, err := listProjectMembers(p.ProjectID, "")
+			if err != nil {
+				return err
+			}
+			for _, m := range members {
+				if m.EntityType != "u" {
+					continue
+				}
+				role, ok := projectRoles[m.RoleID]
+				if !ok {
+					role = m.RoleName
+				}
+				memberships[m.EntityName] = append(memberships[m.EntityName], p.Name+":"+role)
+			}
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+
+	var w io.Writer = os.Stdout
+	if userExport.file != "" {
+		f, err := os.Create(userExport.file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(userCSVHeader)
+	for _, u := range users {
+		cw.Write([]string{
+			u.Username, u.Email, u.Realname, "",
+			strconv.FormatBool(u.HasAdminRole), u.Comment,
+			strings.Join(memberships[u.Username], ";"),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "<== Exported %d users\n", len(users))
+	return nil
+}
@@ -0,0 +1,521 @@
+// Copyright © 2018 moooofly <centos.sf@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/moooofly/harborctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// userImportCmd represents the import command
+var userImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Create users, set admin flags and add project memberships from a CSV file.",
+	Long: `This command imports users from a CSV file, which has a header row naming the columns:
+
+  username,email,realname,password,admin,comment,projects
+  alice,alice@example.com,Alice,,false,,library:developer;team:projectAdmin
+
+- 'username' is required, the other columns are optional, and their order does not matter.
+- A user which does not exist is created. If 'password' is empty, a password is generated and written to '--password_file', created with mode 0600, as 'username,password' rows; such a row fails if '--password_file' is not set.
+- An existing user is kept as is, unless '--update' is set to update its email, realname and comment, each of which is left as is if empty.
+- 'admin' of 'true' or 'false' sets the user as an administrator of Harbor or not, it is left as is if empty.
+- 'projects' are the memberships separated by ';', each of format 'project:role', where role is one of projectAdmin, developer and guest. The role of an existing member is updated.
+
+The result of each row is reported, and the command exits with 1 if any row failed. The file of 'user export' can be imported as is.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := importUsers(); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var userImport struct {
+	file         string
+	passwordFile string
+	update       bool
+	dryRun       bool
+}
+
+func init() {
+	userCmd.AddCommand(userImportCmd)
+
+	userImportCmd.Flags().StringVarP(&userImport.file,
+		"file",
+		"f", "",
+		"(REQUIRED) The CSV file to import users from.")
+	userImportCmd.MarkFlagRequired("file")
+
+	userImportCmd.Flags().StringVarP(&userImport.passwordFile,
+		"password_file",
+		"", "",
+		"The file to write the generated passwords to, which must not exist.")
+
+	userImportCmd.Flags().BoolVarP(&userImport.update,
+		"update",
+		"", false,
+		"Update the email, realname and comment of existing users.")
+
+	userImportCmd.Flags().BoolVarP(&userImport.dryRun,
+		"dry_run",
+		"", false,
+		"Validate the file and show what would be done only.")
+}
+
+// userCSVHeader is the header of CSV files of 'user import' and 'user export'.
+var userCSVHeader = []string{"username", "email", "realname", "password", "admin", "comment", "projects"}
+
+type userMembership struct {
+	project string
+	roleID  int64
+}
+
+// userRow is a row of users CSV.
+type userRow struct {
+	line        int
+	username    string
+	email       string
+	realname    string
+	password    string
+	comment     string
+	admin       *bool
+	memberships []userMembership
+	err         error
+}
+
+// userRowResult is the result of importing a row.
+type userRowResult struct {
+	line     int
+	username string
+	actions  []string
+	err      error
+}
+
+// readUserCSV reads rows from r. A malformed row is returned with its error,
+// rather than failing the whole file.
+func readUserCSV(r io.Reader) ([]userRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %v", err)
+	}
+
+	columns := make(map[string]int)
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := columns["username"]; !ok {
+		return nil, errors.New("no 'username' column in header")
+	}
+
+	var rows []userRow
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := userRow{
+			line:     line,
+			username: get("username"),
+			email:    get("email"),
+			realname: get("realname"),
+			password: get("password"),
+			comment:  get("comment"),
+		}
+		row.err = row.parse(get("admin"), get("projects"))
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (row *userRow) parse(admin, projects string) error {
+	if row.username == "" {
+		return errors.New("username is empty")
+	}
+
+	if admin != "" {
+		b, err := strconv.ParseBool(admin)
+		if err != nil {
+			return fmt.Errorf("invalid admin %q", admin)
+		}
+		row.admin = &b
+	}
+
+	for _, m := range strings.Split(projects, ";") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+
+		i := strings.LastIndex(m, ":")
+		if i <= 0 {
+			return fmt.Errorf("invalid membership %q, must be of format 'project:role'", m)
+		}
+		roleID, err := parseProjectRole(m[i+1:])
+		if err != nil {
+			return err
+		}
+		row.memberships = append(row.memberships, userMembership{project: m[:i], roleID: roleID})
+	}
+	return nil
+}
+
+func importUsers() error {
+	f, err := os.Open(userImport.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rows, err := readUserCSV(f)
+	if err != nil {
+		return err
+	}
+
+	users, err := listUsers()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]userBrief)
+	for _, u := range users {
+		byName[u.Username] = u
+	}
+
+	projects, err := listProjects()
+	if err != nil {
+		return err
+	}
+	projectIDs := make(map[string]int64)
+	for _, p := range projects {
+		projectIDs[p.Name] = p.ProjectID
+	}
+
+	// NOTE: created exclusively, so that the passwords are neither readable
+	// by others nor overwriting the ones generated before.
+	var passwords *csv.Writer
+	if userImport.passwordFile != "" && !userImport.dryRun {
+		pf, err := os.OpenFile(userImport.passwordFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		defer pf.Close()
+		passwords = csv.NewWriter(pf)
+		passwords.Write([]string{"username", "password"})
+	}
+
+	var results []userRowResult
+	var failed int
+	for _, row := range rows {
+		r := importUserRow(row, byName, projectIDs, passwords)
+		if r.err != nil {
+			failed++
+		}
+		results = append(results, r)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE\tUSERNAME\tRESULT\tDETAIL")
+	for _, r := range results {
+		result := "ok"
+		if userImport.dryRun {
+			result = "valid"
+		}
+		detail := strings.Join(r.actions, ", ")
+		if r.err != nil {
+			result = "failed"
+			if detail != "" {
+				detail += ", "
+			}
+			detail += "error: " + r.err.Error()
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", r.line, r.username, result, detail)
+	}
+	w.Flush()
+
+	fmt.Printf("<== %d rows, %d succeeded, %d failed\n", len(results), len(results)-failed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// importUserRow imports a row, and stops at the first failed step of it. The
+// password generated is written to passwords.
+func importUserRow(row userRow, users map[string]userBrief, projectIDs map[string]int64, passwords *csv.Writer) userRowResult {
+	r := userRowResult{line: row.line, username: row.username}
+	if row.err != nil {
+		r.err = row.err
+		return r
+	}
+
+	for _, m := range row.memberships {
+		if _, ok := projectIDs[m.project]; !ok {
+			r.err = fmt.Errorf("no project named %q", m.project)
+			return r
+		}
+	}
+
+	u, exists := users[row.username]
+	if !exists && row.email == "" {
+		r.err = errors.New("email is required to create a user")
+		return r
+	}
+
+	if !exists && row.password == "" && passwords == nil && !userImport.dryRun {
+		r.err = errors.New("no password given, set '--password_file' to generate one")
+		return r
+	}
+
+	if userImport.dryRun {
+		if exists {
+			r.actions = append(r.actions, "exists")
+		} else {
+			r.actions = append(r.actions, "create")
+		}
+		for _, m := range row.memberships {
+			r.actions = append(r.actions, m.project+":"+projectRoles[m.roleID])
+		}
+		return r
+	}
+
+	switch {
+	case !exists:
+		id, password, err := createImportedUser(row)
+		if err != nil {
+			r.err = err
+			return r
+		}
+		u = userBrief{UserID: id, Username: row.username}
+		users[row.username] = u
+		if row.password == "" {
+			passwords.Write([]string{row.username, password})
+			if passwords.Flush(); passwords.Error() != nil {
+				r.err = fmt.Errorf("created, but the password generated is not written: %v", passwords.Error())
+				return r
+			}
+			r.actions = append(r.actions, "created with password written to "+userImport.passwordFile)
+		} else {
+			r.actions = append(r.actions, "created")
+		}
+
+	case userImport.update:
+		body := struct {
+			Email    string `json:"email"`
+			Realname string `json:"realname"`
+			Comment  string `json:"comment"`
+		}{row.email, row.realname, row.comment}
+		if body.Email == "" {
+			body.Email = u.Email
+		}
+		if body.Realname == "" {
+			body.Realname = u.Realname
+		}
+		if body.Comment == "" {
+			body.Comment = u.Comment
+		}
+		if _, _, err := utils.Send("PUT", utils.URLGen("/api/users/"+strconv.FormatInt(u.UserID, 10)), body); err != nil {
+			r.err = err
+			return r
+		}
+		r.actions = append(r.actions, "updated")
+
+	default:
+		r.actions = append(r.actions, "exists")
+	}
+
+	if row.admin != nil && *row.admin != u.HasAdminRole {
+		targetURL := utils.URLGen("/api/users/" + strconv.FormatInt(u.UserID, 10) + "/sysadmin")
+		body := struct {
+			HasAdminRole bool `json:"has_admin_role"`
+		}{*row.admin}
+		if _, _, err := utils.Send("PUT", targetURL, body); err != nil {
+			r.err = err
+			return r
+		}
+		r.actions = append(r.actions, "admin="+strconv.FormatBool(*row.admin))
+	}
+
+	for _, m := range row.memberships {
+		action, err := addImportedMember(projectIDs[m.project], row.username, m.roleID)
+		if err != nil {
+			r.err = fmt.Errorf("%s: %v", m.project, err)
+			return r
+		}
+		r.actions = append(r.actions, m.project+":"+projectRoles[m.roleID]+" "+action)
+	}
+
+	return r
+}
+
+// createImportedUser creates the user of row, and returns its ID with its
+// password, which is generated if not given.
+func createImportedUser(row userRow) (int64, string, error) {
+	password := row.password
+	if password == "" {
+		var err error
+		if password, err = generatePassword(12); err != nil {
+			return 0, "", err
+		}
+	}
+
+	realname := row.realname
+	if realname == "" {
+		realname = row.username
+	}
+
+	body := struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+		Realname string `json:"realname"`
+		Comment  string `json:"comment"`
+	}{row.username, row.email, password, realname, row.comment}
+
+	resp, _, err := utils.Send("POST", utils.URLGen("/api/users"), body)
+	if err != nil {
+		return 0, "", err
+	}
+
+	// NOTE: Harbor responds the location of the user created, i.e. '/api/users/{user_id}'.
+	if id, err := strconv.ParseInt(path.Base(resp.Header.Get("Location")), 10, 64); err == nil {
+		return id, password, nil
+	}
+
+	var users []userBrief
+	if err := utils.GetStruct(utils.URLGen("/api/users")+"?username="+url.QueryEscape(row.username), &users); err != nil {
+		return 0, "", err
+	}
+	for _, u := range users {
+		if u.Username == row.username {
+			return u.UserID, password, nil
+		}
+	}
+	return 0, "", errors.New("user created but not found")
+}
+
+// addImportedMember adds the user to a project, or updates its role if it is
+// a member already.
+func addImportedMember(projectID int64, username string, roleID int64) (string, error) {
+	targetURL := utils.URLGen("/api/projects/" + strconv.FormatInt(projectID, 10) + "/members")
+
+	var body struct {
+		RoleID int64 `json:"role_id"`
+		User   struct {
+			Username string `json:"username"`
+		} `json:"member_user"`
+	}
+	body.RoleID = roleID
+	body.User.Username = username
+
+	_, _, err := utils.Send("POST", targetURL, body)
+	if err == nil {
+		return "added", nil
+	}
+	if !utils.IsStatus(err, 409) {
+		return "", err
+	}
+
+	members, err := listProjectMembers(projectID, username)
+	if err != nil {
+		return "", err
+	}
+	for _, m := range members {
+		if m.EntityType != "u" || m.EntityName != username {
+			continue
+		}
+		if m.RoleID == roleID {
+			return "unchanged", nil
+		}
+
+		update := struct {
+			RoleID int64 `json:"role_id"`
+		}{roleID}
+		if _, _, err := utils.Send("PUT", targetURL+"/"+strconv.FormatInt(m.ID, 10), update); err != nil {
+			return "", err
+		}
+		return "updated", nil
+	}
+	return "", errors.New("conflicted but not a member")
+}
+
+// generatePassword generates a password of n characters, which contains at
+// least one uppercase letter, one lowercase letter and one digit as Harbor
+// requires.
+func generatePassword(n int) (string, error) {
+	const (
+		upper = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+		lower = "abcdefghijkmnopqrstuvwxyz"
+		digit = "23456789"
+	)
+	sets := []string{upper, lower, digit}
+
+	b := make([]byte, n)
+	for i := range b {
+		set := upper + lower + digit
+		if i < len(sets) {
+			set = sets[i]
+		}
+		k, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = set[k.Int64()]
+	}
+
+	// shuffle, so that the first characters are not predictable by class.
+	for i := len(b) - 1; i > 0; i-- {
+		k, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		j := k.Int64()
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b), nil
+}
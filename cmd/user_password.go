@@ -55,10 +55,10 @@ var updateUserPassword struct {
 func init() {
 	userCmd.AddCommand(passwordCmd)
 
-	passwordCmd.Flags().Int64VarP(&updateUserPassword.userID,
+	passwordCmd.Flags().VarP(newIDValue(&updateUserPassword.userID, "user"),
 		"user_id",
-		"i", 0,
-		"(REQUIRED) Registered user ID.")
+		"i",
+		"(REQUIRED) Registered user ID or username.")
 	passwordCmd.MarkFlagRequired("user_id")
 
 	passwordCmd.Flags().StringVarP(&updateUserPassword.OldPassword,
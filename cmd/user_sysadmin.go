@@ -48,10 +48,10 @@ var updateUserSysadmin struct {
 func init() {
 	userCmd.AddCommand(sysadminCmd)
 
-	sysadminCmd.Flags().Int64VarP(&updateUserSysadmin.userID,
+	sysadminCmd.Flags().VarP(newIDValue(&updateUserSysadmin.userID, "user"),
 		"user_id",
-		"i", 0,
-		"(REQUIRED) Registered user ID.")
+		"i",
+		"(REQUIRED) Registered user ID or username.")
 	sysadminCmd.MarkFlagRequired("user_id")
 
 	sysadminCmd.Flags().BoolVarP(&updateUserSysadmin.HasAdminRole,
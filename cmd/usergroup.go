@@ -90,10 +90,10 @@ var usergroupGet struct {
 func initUsergroupGet() {
 	usergroupCmd.AddCommand(usergroupGetCmd)
 
-	usergroupGetCmd.Flags().Int64VarP(&usergroupGet.groupID,
+	usergroupGetCmd.Flags().VarP(newIDValue(&usergroupGet.groupID, "usergroup"),
 		"group_id",
-		"i", 0,
-		"The Group ID.")
+		"i",
+		"The Group ID or name.")
 	usergroupGetCmd.MarkFlagRequired("group_id")
 }
 
@@ -119,10 +119,10 @@ var usergroupDelete struct {
 func initUsergroupDelete() {
 	usergroupCmd.AddCommand(usergroupDeleteCmd)
 
-	usergroupDeleteCmd.Flags().Int64VarP(&usergroupDelete.groupID,
+	usergroupDeleteCmd.Flags().VarP(newIDValue(&usergroupDelete.groupID, "usergroup"),
 		"group_id",
-		"i", 0,
-		"(REQUIRED) Group ID.")
+		"i",
+		"(REQUIRED) Group ID or name.")
 	usergroupDeleteCmd.MarkFlagRequired("group_id")
 }
 
@@ -205,10 +205,10 @@ var usergroupUpdate struct {
 func initUsergroupUpdate() {
 	usergroupCmd.AddCommand(usergroupUpdateCmd)
 
-	usergroupUpdateCmd.Flags().Int64VarP(&usergroupUpdate.ID,
+	usergroupUpdateCmd.Flags().VarP(newIDValue(&usergroupUpdate.ID, "usergroup"),
 		"id",
-		"i", 0,
-		"(REQUIRED) The ID of the user group.")
+		"i",
+		"(REQUIRED) The ID or name of the user group.")
 	usergroupUpdateCmd.MarkFlagRequired("id")
 
 	usergroupUpdateCmd.Flags().StringVarP(&usergroupUpdate.GroupName,
@@ -2,7 +2,13 @@ package utils
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/parnurzeal/gorequest"
 )
@@ -15,80 +21,345 @@ func AgentGet() *gorequest.SuperAgent {
 	return request
 }
 
-func GetStruct(targetURL string, st interface{}) {
-	fmt.Println("==> GET (with struct)", targetURL)
+// NewAgent returns a new SuperAgent with the same setting as the shared one.
+// As a SuperAgent is not safe for concurrent use, each goroutine sending
+// requests must use its own.
+func NewAgent() *gorequest.SuperAgent {
+	return gorequest.New().TLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+}
 
+// GetStruct decodes the JSON body of a GET request into st. An error is
+// returned if the request failed or the response status is not 200.
+//
+// NOTE: the request is traced to stderr, so that the commands which output
+// CSV or JSON can be piped.
+func GetStruct(targetURL string, st interface{}) error {
 	c, _ := CookieLoad()
-	request.Get(targetURL).
-		Set("Cookie", "harbor-lang=zh-cn; beegosessionID="+c).
-		EndStruct(st)
+	return getStruct(request, c, targetURL, st)
+}
+
+func getStruct(agent *gorequest.SuperAgent, session, targetURL string, st interface{}) error {
+	_, routedURL, _, get, err := routeAPI(agent, session, "GET", targetURL, nil)
+	if err != nil {
+		return err
+	}
+	if get != nil {
+		fmt.Fprintln(os.Stderr, "==> GET (routed to API v2.0)", targetURL)
+		v, err := get()
+		if err != nil {
+			return err
+		}
+		return convertJSON(v, st)
+	}
+	targetURL = routedURL
+
+	fmt.Fprintln(os.Stderr, "==> GET (with struct)", targetURL)
+
+	resp, body, errs := agent.Get(targetURL).
+		Set("Cookie", sessionCookie(targetURL, session)).
+		EndBytes()
+	for _, e := range errs {
+		if e != nil {
+			return e
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{Method: "GET", URL: targetURL, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	return json.Unmarshal(body, st)
+}
+
+// StatusError is returned when the response status is unexpected.
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	s := e.Method + " " + e.URL + ": " + e.Status
+	if e.Body != "" {
+		s += ": " + e.Body
+	}
+	return s
+}
+
+// IsStatus reports whether err is a StatusError with code.
+func IsStatus(err error, code int) bool {
+	e, ok := err.(*StatusError)
+	return ok && e.StatusCode == code
+}
+
+// Send sends a request of method with st encoded as the JSON body if it is
+// not nil, and returns the response with its body. An error is returned if
+// the request failed or the response status is not 2xx.
+//
+// NOTE: unlike Post, Put and Delete, the request is traced to stderr and the
+// response is left to the caller.
+func Send(method, targetURL string, st interface{}) (gorequest.Response, []byte, error) {
+	return SendWith(request, method, targetURL, st)
+}
+
+// SendWith is like Send but sends the request by agent.
+func SendWith(agent *gorequest.SuperAgent, method, targetURL string, st interface{}) (gorequest.Response, []byte, error) {
+	c, _ := CookieLoad()
+	return send(agent, c, method, targetURL, st)
+}
+
+func send(agent *gorequest.SuperAgent, session, method, targetURL string, st interface{}) (gorequest.Response, []byte, error) {
+	method, targetURL, st, get, err := routeAPI(agent, session, method, targetURL, st)
+	if err != nil {
+		return nil, nil, err
+	}
+	if get != nil {
+		fmt.Fprintln(os.Stderr, "==> GET (routed to API v2.0)", targetURL)
+		v, err := get()
+		if err != nil {
+			return nil, nil, err
+		}
+		body, err := json.Marshal(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &http.Response{Status: "200 OK", StatusCode: http.StatusOK, Header: http.Header{}}, body, nil
+	}
+
+	fmt.Fprintln(os.Stderr, "==>", method, targetURL)
+
+	req := agent.CustomMethod(method, targetURL).
+		Set("Cookie", sessionCookie(targetURL, session))
+	if req, err = withCSRFToken(req, method, targetURL, session); err != nil {
+		return nil, nil, err
+	}
+	if st != nil {
+		b, err := json.Marshal(st)
+		if err != nil {
+			return nil, nil, err
+		}
+		req = req.Type("json").SendString(string(b))
+	}
+
+	resp, body, errs := req.EndBytes()
+	for _, e := range errs {
+		if e != nil {
+			return nil, nil, e
+		}
+	}
+
+	if resp.StatusCode/100 != 2 {
+		return resp, body, &StatusError{
+			Method:     method,
+			URL:        targetURL,
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       strings.TrimSpace(string(body)),
+		}
+	}
+	return resp, body, nil
+}
+
+// GetPages fetches all pages of targetURL, by calling fetch with the URL of
+// each page in order. fetch returns the number of items in the page, and the
+// fetching stops at the first page holding less than pageSize items.
+func GetPages(targetURL string, pageSize int, fetch func(pageURL string) (int, error)) error {
+	sep := "?"
+	if strings.Contains(targetURL, "?") {
+		sep = "&"
+	}
+
+	for page := 1; ; page++ {
+		n, err := fetch(targetURL + sep + "page=" + strconv.Itoa(page) + "&page_size=" + strconv.Itoa(pageSize))
+		if err != nil {
+			return err
+		}
+		if n < pageSize {
+			return nil
+		}
+	}
 }
 
 func Get(targetURL string) {
 	fmt.Println("==> GET", targetURL)
 
 	c, _ := CookieLoad()
-	request.Get(targetURL).
-		Set("Cookie", "harbor-lang=zh-cn; beegosessionID="+c).
+	_, routedURL, _, get, err := routeAPI(request, c, "GET", targetURL, nil)
+	if err != nil {
+		printStatus(nil, "", []error{err})
+		return
+	}
+	if get != nil {
+		printRouted(get)
+		return
+	}
+
+	request.Get(routedURL).
+		Set("Cookie", sessionCookie(routedURL, c)).
 		End(printStatus)
 }
 
 func Delete(targetURL string) {
 	fmt.Println("==> DELETE", targetURL)
-
-	c, _ := CookieLoad()
-	request.Delete(targetURL).
-		Set("Cookie", "harbor-lang=zh-cn; beegosessionID="+c).
-		End(printStatus)
+	sendRouted("DELETE", targetURL, "")
 }
 
 func Post(targetURL string, body string) {
 	fmt.Println("==> POST", targetURL)
+	sendRouted("POST", targetURL, body)
+}
 
+// sendRouted sends a request of Delete, Post or Put, routed to API v2.0 for
+// Harbor 2.x.
+func sendRouted(method, targetURL string, body string) {
 	c, _ := CookieLoad()
-	request.Post(targetURL).
-		Set("Cookie", "harbor-lang=zh-cn; beegosessionID="+c).
-		Send(body).
-		End(printStatus)
+
+	var st interface{}
+	if body != "" {
+		st = json.RawMessage(body)
+	}
+	method, targetURL, st, _, err := routeAPI(request, c, method, targetURL, st)
+	if err != nil {
+		printStatus(nil, "", []error{err})
+		return
+	}
+
+	req := request.CustomMethod(method, targetURL).
+		Set("Cookie", sessionCookie(targetURL, c))
+	if req, err = withCSRFToken(req, method, targetURL, c); err != nil {
+		printStatus(nil, "", []error{err})
+		return
+	}
+	switch b := st.(type) {
+	case nil:
+	case json.RawMessage:
+		req = req.Send(string(b))
+	default:
+		b2, err := json.Marshal(b)
+		if err != nil {
+			printStatus(nil, "", []error{err})
+			return
+		}
+		req = req.Send(string(b2))
+	}
+	req.End(printStatus)
+}
+
+// printRouted prints the response of a GET request routed to API v2.0, like
+// printStatus.
+func printRouted(get func() (interface{}, error)) {
+	v, err := get()
+	if err != nil {
+		printStatus(nil, "", []error{err})
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		printStatus(nil, "", []error{err})
+		return
+	}
+	printStatus(&http.Response{Status: "200 OK", StatusCode: http.StatusOK}, string(b), nil)
 }
 
-func Multipart(targetURL string, files ...string) {
+// FormFile is a file sent in a multipart form.
+type FormFile struct {
+	// Field is the name of the form field, e.g. 'chart'.
+	Field string
+	// Path is the file to send, whose base name is the file name in the form.
+	Path string
+	// Data is sent instead of reading Path if not nil.
+	Data []byte
+}
+
+func Multipart(targetURL string, files ...FormFile) {
 	fmt.Println("==> POST (Multipart)", targetURL)
 
-	c, _ := CookieLoad()
-	switch len(files) {
-	case 1:
-		request.Post(targetURL).
-			Set("Cookie", "harbor-lang=zh-cn; beegosessionID="+c).
-			Type("multipart").
-			SendFile(files[0]).
-			End(printStatus)
-	case 2:
-		request.Post(targetURL).
-			Set("Cookie", "harbor-lang=zh-cn; beegosessionID="+c).
-			Type("multipart").
-			SendFile(files[0]).
-			SendFile(files[1]).
-			End(printStatus)
-	default:
-		fmt.Println("Should not be here!")
+	req, err := multipartRequest(targetURL, files)
+	if err != nil {
+		printStatus(nil, "", []error{err})
 		return
 	}
+	req.End(printStatus)
 }
 
-func Put(targetURL string, body string) {
-	fmt.Println("==> PUT", targetURL)
+// SendMultipart posts the files in a multipart form and returns the response
+// with its body, like Send.
+func SendMultipart(targetURL string, files ...FormFile) (gorequest.Response, []byte, error) {
+	fmt.Fprintln(os.Stderr, "==> POST (Multipart)", targetURL)
 
+	req, err := multipartRequest(targetURL, files)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, body, errs := req.EndBytes()
+	for _, e := range errs {
+		if e != nil {
+			return nil, nil, e
+		}
+	}
+
+	if resp.StatusCode/100 != 2 {
+		return resp, body, &StatusError{
+			Method:     "POST",
+			URL:        targetURL,
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       strings.TrimSpace(string(body)),
+		}
+	}
+	return resp, body, nil
+}
+
+// multipartRequest returns the request posting the files to targetURL, which
+// is routed and carries the CSRF token like the other requests changing
+// anything.
+func multipartRequest(targetURL string, files []FormFile) (*gorequest.SuperAgent, error) {
 	c, _ := CookieLoad()
-	request.Put(targetURL).
-		Set("Cookie", "harbor-lang=zh-cn; beegosessionID="+c).
-		Send(body).
-		End(printStatus)
+	method, targetURL, _, _, err := routeAPI(request, c, "POST", targetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req := request.CustomMethod(method, targetURL).
+		Set("Cookie", sessionCookie(targetURL, c))
+	if req, err = withCSRFToken(req, method, targetURL, c); err != nil {
+		return nil, err
+	}
+	req = req.Type("multipart")
+	for _, f := range files {
+		req = sendFormFile(req, f)
+	}
+	return req, nil
+}
+
+func sendFormFile(req *gorequest.SuperAgent, f FormFile) *gorequest.SuperAgent {
+	if f.Data != nil {
+		return req.SendFile(f.Data, filepath.Base(f.Path), f.Field)
+	}
+	return req.SendFile(f.Path, "", f.Field)
+}
+
+func Put(targetURL string, body string) {
+	fmt.Println("==> PUT", targetURL)
+	sendRouted("PUT", targetURL, body)
 }
 
 func Head(targetURL string) {
 	fmt.Println("==> HEAD", targetURL)
-	request.Head(targetURL).End(printStatus)
+
+	c, _ := CookieLoad()
+	_, routedURL, _, _, err := routeAPI(request, c, "HEAD", targetURL, nil)
+	if err != nil {
+		printStatus(nil, "", []error{err})
+		return
+	}
+
+	request.Head(routedURL).
+		Set("Cookie", sessionCookie(routedURL, c)).
+		End(printStatus)
 }
 
 // printStatus is a regular simple output callback function.
@@ -0,0 +1,330 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moooofly/harborctl/utils/registry"
+	"github.com/parnurzeal/gorequest"
+	"github.com/spf13/viper"
+)
+
+// The generations of the Harbor API.
+const (
+	// APIv1 is the API of Harbor 1.x under '/api', which the commands are
+	// written against.
+	APIv1 = "v1"
+	// APIv2 is the API of Harbor 2.x under '/api/v2.0', to which the requests
+	// of APIv1 are routed, see apiRoutes.
+	APIv2 = "v2.0"
+)
+
+// apiVersionTTL is how long the API version detected is cached.
+const apiVersionTTL = time.Hour
+
+// apiVersions are the API versions by the base URL of Harbor, guarded by
+// apiVersionsMu, which is held while detecting so that it is detected once.
+var (
+	apiVersions   = make(map[string]string)
+	apiVersionsMu sync.Mutex
+)
+
+// APIVersion returns the API version of Harbor at base, e.g.
+// 'https://harbor.example.com'. It is 'api_version' in the configuration if
+// set, otherwise detected by whether '/api/v2.0/systeminfo' exists.
+func APIVersion(base string) string {
+	apiVersionsMu.Lock()
+	defer apiVersionsMu.Unlock()
+
+	if v, ok := apiVersions[base]; ok {
+		return v
+	}
+
+	v := viper.GetString("api_version")
+	if v != APIv1 && v != APIv2 {
+		v = detectAPIVersion(base)
+	}
+	apiVersions[base] = v
+	return v
+}
+
+// SetAPIVersion sets the API version of Harbor at base, instead of detecting.
+func SetAPIVersion(base, version string) error {
+	if version != APIv1 && version != APIv2 {
+		return fmt.Errorf("invalid api_version %q, must be one of [%s|%s]", version, APIv1, APIv2)
+	}
+	apiVersionsMu.Lock()
+	apiVersions[base] = version
+	apiVersionsMu.Unlock()
+	return nil
+}
+
+func detectAPIVersion(base string) string {
+	key := "api_version " + base
+
+	var v string
+	if cacheLoad(key, apiVersionTTL, &v) {
+		return v
+	}
+
+	fmt.Fprintln(os.Stderr, "==> GET (detect API version)", base+"/api/v2.0/systeminfo")
+	resp, _, errs := NewAgent().Timeout(10 * time.Second).Get(base + "/api/v2.0/systeminfo").End()
+	for _, e := range errs {
+		if e != nil {
+			// NOTE: not cached, and the request failing later tells why.
+			return APIv1
+		}
+	}
+
+	v = APIv1
+	if resp.StatusCode == http.StatusOK {
+		v = APIv2
+	}
+	cacheStore(key, v)
+	return v
+}
+
+// splitURL splits targetURL into the base URL, the path and the query.
+func splitURL(targetURL string) (string, string, url.Values) {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return "", targetURL, nil
+	}
+	return u.Scheme + "://" + u.Host, u.Path, u.Query()
+}
+
+// sessionCookie returns the Cookie header of the session to Harbor of
+// targetURL, whose session cookie is 'sid' since Harbor 2.x.
+func sessionCookie(targetURL, session string) string {
+	base, _, _ := splitURL(targetURL)
+	if APIVersion(base) == APIv2 {
+		return "harbor-lang=zh-cn; sid=" + session
+	}
+	return "harbor-lang=zh-cn; beegosessionID=" + session
+}
+
+// SessionCookieName returns the name of the session cookie of Harbor at base.
+func SessionCookieName(base string) string {
+	if APIVersion(base) == APIv2 {
+		return "sid"
+	}
+	return "beegosessionID"
+}
+
+// csrfToken is the CSRF token of Harbor 2.x, required by the requests
+// changing anything with a session.
+type csrfToken struct {
+	header string
+	cookie string
+}
+
+// csrfTokens are the CSRF tokens by the base URL of Harbor, guarded by
+// csrfTokensMu, which is held while fetching so that it is fetched once.
+var (
+	csrfTokens   = make(map[string]*csrfToken)
+	csrfTokensMu sync.Mutex
+)
+
+// getCSRFToken gets the CSRF token of Harbor at base, by the response of any
+// GET request.
+//
+// NOTE: sent by a new agent, as a SuperAgent holds the request being built.
+func getCSRFToken(base, session string) (*csrfToken, error) {
+	csrfTokensMu.Lock()
+	defer csrfTokensMu.Unlock()
+
+	if t, ok := csrfTokens[base]; ok {
+		return t, nil
+	}
+
+	targetURL := base + "/api/v2.0/systeminfo"
+	fmt.Fprintln(os.Stderr, "==> GET (CSRF token)", targetURL)
+
+	req := NewAgent().Get(targetURL)
+	if session != "" {
+		req = req.Set("Cookie", "sid="+session)
+	}
+	resp, _, errs := req.End()
+	for _, e := range errs {
+		if e != nil {
+			return nil, e
+		}
+	}
+
+	t := &csrfToken{header: resp.Header.Get("X-Harbor-Csrf-Token")}
+	for _, c := range (*http.Response)(resp).Cookies() {
+		if c.Name != "sid" {
+			t.cookie += "; " + c.Name + "=" + c.Value
+		}
+	}
+	if t.header == "" {
+		return nil, fmt.Errorf("no CSRF token in the response of %s", targetURL)
+	}
+	csrfTokens[base] = t
+	return t, nil
+}
+
+// withCSRFToken sets the CSRF token to the request changing anything with a
+// session to Harbor 2.x.
+func withCSRFToken(req *gorequest.SuperAgent, method, targetURL, session string) (*gorequest.SuperAgent, error) {
+	base, _, _ := splitURL(targetURL)
+	if session == "" || method == "GET" || method == "HEAD" || APIVersion(base) != APIv2 {
+		return req, nil
+	}
+
+	t, err := getCSRFToken(base, session)
+	if err != nil {
+		return nil, err
+	}
+	return req.Set("X-Harbor-CSRF-Token", t.header).
+		Set("Cookie", sessionCookie(targetURL, session)+t.cookie), nil
+}
+
+// LoginRequest returns the request of posting the login form to Harbor at
+// base, which is '/c/login' with the CSRF token since Harbor 2.x, with the
+// URL posted to.
+func LoginRequest(agent *gorequest.SuperAgent, base string) (*gorequest.SuperAgent, string, error) {
+	if APIVersion(base) != APIv2 {
+		targetURL := base + "/login"
+		return agent.Post(targetURL).
+			Set("Content-Type", "application/x-www-form-urlencoded;param=value").
+			Set("Cookie", "harbor-lang=zh-cn"), targetURL, nil
+	}
+
+	t, err := getCSRFToken(base, "")
+	if err != nil {
+		return nil, "", err
+	}
+	targetURL := base + "/c/login"
+	return agent.Post(targetURL).
+		Set("Content-Type", "application/x-www-form-urlencoded;param=value").
+		Set("X-Harbor-CSRF-Token", t.header).
+		Set("Cookie", "harbor-lang=zh-cn"+t.cookie), targetURL, nil
+}
+
+// apiClient sends the requests of APIv2 for routing a request of APIv1.
+type apiClient struct {
+	agent   *gorequest.SuperAgent
+	session string
+	base    string
+}
+
+func (c *apiClient) get(uri string, st interface{}) error {
+	return getStruct(c.agent, c.session, c.base+uri, st)
+}
+
+// getRegistry gets uri of the registry API, e.g. a manifest, with the session,
+// accepting the manifests of any media type.
+func (c *apiClient) getRegistry(uri string) ([]byte, error) {
+	targetURL := c.base + uri
+	fmt.Fprintln(os.Stderr, "==> GET (registry)", targetURL)
+
+	resp, body, errs := c.agent.Get(targetURL).
+		Set("Cookie", sessionCookie(targetURL, c.session)).
+		Set("Accept", registry.ManifestAccept()).
+		EndBytes()
+	for _, e := range errs {
+		if e != nil {
+			return nil, e
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Method: "GET", URL: targetURL, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return body, nil
+}
+
+// getPages gets all pages of uri by page size 100, decoding each page by
+// decode, which returns the number of items in the page.
+func (c *apiClient) getPages(uri string, decode func(body json.RawMessage) (int, error)) error {
+	return GetPages(uri, 100, func(pageURI string) (int, error) {
+		var body json.RawMessage
+		if err := c.get(pageURI, &body); err != nil {
+			return 0, err
+		}
+		return decode(body)
+	})
+}
+
+// apiRoute routes the requests of APIv1 matching pattern to APIv2, which do
+// not map to APIv2 by the prefix only.
+type apiRoute struct {
+	method string
+	// pattern matches the path after '/api'.
+	pattern *regexp.Regexp
+	// get gets the response of a GET request by APIv2, converted to APIv1.
+	get func(c *apiClient, m []string, q url.Values) (interface{}, error)
+	// send returns the request of APIv2 for the other methods, with the body
+	// converted from APIv1.
+	send func(c *apiClient, m []string, body interface{}) (method, uri string, newBody interface{}, err error)
+}
+
+// errUnsupported is returned for the requests of APIv1 with no counterparts
+// in APIv2.
+func errUnsupported(method, path string) error {
+	return fmt.Errorf("%s %s is not supported by Harbor 2.x", method, path)
+}
+
+// routeAPI routes a request of APIv1 to APIv2 if Harbor of targetURL is of
+// APIv2. For a GET request routed by apiRoutes, get is returned to get the
+// response converted. Otherwise the request is returned, which is unchanged
+// for APIv1, the chart repository and the requests of APIv2 already, and an
+// error is returned for a request matching neither apiRoutes nor
+// apiPrefixRoutes.
+func routeAPI(agent *gorequest.SuperAgent, session, method, targetURL string, body interface{}) (newMethod, newURL string, newBody interface{}, get func() (interface{}, error), err error) {
+	base, path, q := splitURL(targetURL)
+	if !strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/api/v2.0/") ||
+		strings.HasPrefix(path, "/api/chartrepo/") || APIVersion(base) != APIv2 {
+		return method, targetURL, body, nil, nil
+	}
+
+	c := &apiClient{agent, session, base}
+	sub := strings.TrimPrefix(path, "/api")
+	for _, r := range apiRoutes {
+		m := r.pattern.FindStringSubmatch(sub)
+		if m == nil || r.method != method {
+			continue
+		}
+
+		if method == "GET" {
+			if r.get == nil {
+				return "", "", nil, nil, errUnsupported(method, path)
+			}
+			return method, targetURL, nil, func() (interface{}, error) { return r.get(c, m, q) }, nil
+		}
+
+		if r.send == nil {
+			return "", "", nil, nil, errUnsupported(method, path)
+		}
+		newMethod, uri, newBody, err := r.send(c, m, body)
+		if err != nil {
+			return "", "", nil, nil, err
+		}
+		return newMethod, base + uri, newBody, nil, nil
+	}
+
+	for _, p := range apiPrefixRoutes {
+		if p.MatchString(sub) {
+			u, _ := url.Parse(targetURL)
+			u.Path = "/api/v2.0" + sub
+			return method, u.String(), body, nil, nil
+		}
+	}
+	return "", "", nil, nil, errUnsupported(method, path)
+}
+
+// convertJSON converts v to st by encoding to JSON and decoding.
+func convertJSON(v, st interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, st)
+}
@@ -0,0 +1,1067 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moooofly/harborctl/utils/registry"
+)
+
+// apiRoutes are the routes of APIv1 to APIv2 which do not map by the prefix
+// only, i.e. '/api/{path}' to '/api/v2.0/{path}'. The first route matching
+// the method and the path applies, so the specific ones go first.
+//
+// NOTE: a route with neither get nor send is not supported by APIv2, e.g.
+// the labels of repositories, which are removed in Harbor 2.x.
+var apiRoutes []apiRoute
+
+// apiPrefixRoutes match the paths after '/api' of APIv1 which map to APIv2 by
+// the prefix only. The paths matching neither apiRoutes nor them are not
+// supported by APIv2.
+var apiPrefixRoutes = []*regexp.Regexp{
+	regexp.MustCompile(`^/projects$`),
+	regexp.MustCompile(`^/projects/\d+$`),
+	regexp.MustCompile(`^/projects/\d+/members(/\d+)?$`),
+	regexp.MustCompile(`^/projects/\d+/metadatas(/[^/]+)?$`),
+	regexp.MustCompile(`^/projects/\d+/robots(/\d+)?$`),
+	regexp.MustCompile(`^/projects/\d+/webhook/(policies(/\d+|/test)?|lasttrigger|jobs|events)$`),
+	regexp.MustCompile(`^/users$`),
+	regexp.MustCompile(`^/users/(\d+|current)$`),
+	regexp.MustCompile(`^/users/\d+/(password|sysadmin)$`),
+	regexp.MustCompile(`^/usergroups(/\d+)?$`),
+	regexp.MustCompile(`^/labels(/\d+)?$`),
+	regexp.MustCompile(`^/search$`),
+	regexp.MustCompile(`^/statistics$`),
+	regexp.MustCompile(`^/systeminfo(/volumes|/getcert)?$`),
+}
+
+// NOTE: initialized in init for the routes refer to apiRoutes by getStruct.
+func init() {
+	apiRoutes = []apiRoute{
+		// repositories
+		getRoute(`^/repositories$`, getRepositories),
+		getRoute(`^/repositories/top$`, nil),
+		sendRoute("POST", `^/repositories/scanAll$`, scanAll),
+		getRoute(`^/repositories/(.+)/tags$`, getTags),
+		sendRoute("POST", `^/repositories/(.+)/tags$`, nil),
+		getRoute(`^/repositories/(.+)/tags/([^/]+)/manifest$`, getManifest),
+		getRoute(`^/repositories/(.+)/tags/([^/]+)/labels$`, getTagLabels),
+		sendRoute("POST", `^/repositories/(.+)/tags/([^/]+)/labels$`, artifactRewrite("POST", "/labels")),
+		sendRoute("DELETE", `^/repositories/(.+)/tags/([^/]+)/labels/(\d+)$`, artifactRewrite("DELETE", "/labels/{3}")),
+		sendRoute("POST", `^/repositories/(.+)/tags/([^/]+)/scan$`, artifactRewrite("POST", "/scan")),
+		getRoute(`^/repositories/(.+)/tags/([^/]+)/vulnerability/details$`, getVulnerabilities),
+		getRoute(`^/repositories/(.+)/tags/([^/]+)$`, getTag),
+		sendRoute("DELETE", `^/repositories/(.+)/tags/([^/]+)$`, artifactRewrite("DELETE", "")),
+		getRoute(`^/repositories/(.+)/signatures$`, getSignatures),
+		getRoute(`^/repositories/(.+)/labels$`, nil),
+		sendRoute("POST", `^/repositories/(.+)/labels$`, nil),
+		sendRoute("DELETE", `^/repositories/(.+)/labels/(\d+)$`, nil),
+		sendRoute("PUT", `^/repositories/(.+)$`, repositoryRewrite("PUT")),
+		sendRoute("DELETE", `^/repositories/(.+)$`, repositoryRewrite("DELETE")),
+
+		// labels
+		getRoute(`^/labels/(\d+)/resources$`, nil),
+
+		// logs
+		getRoute(`^/logs$`, getLogs),
+		getRoute(`^/projects/(\d+)/logs$`, getLogs),
+
+		// replication targets, which are registries in APIv2
+		getRoute(`^/targets$`, getTargets),
+		sendRoute("POST", `^/targets$`, createTarget),
+		sendRoute("POST", `^/targets/ping$`, pingTarget),
+		getRoute(`^/targets/(\d+)/policies$`, getTargetPolicies),
+		getRoute(`^/targets/(\d+)$`, getTarget),
+		sendRoute("PUT", `^/targets/(\d+)$`, updateTarget),
+		sendRoute("DELETE", `^/targets/(\d+)$`, uriRewrite("DELETE", "/registries/{1}")),
+
+		// replication policies
+		getRoute(`^/policies/replication$`, getPolicies),
+		sendRoute("POST", `^/policies/replication$`, createPolicy),
+		getRoute(`^/policies/replication/(\d+)$`, getPolicy),
+		sendRoute("PUT", `^/policies/replication/(\d+)$`, updatePolicy),
+		sendRoute("DELETE", `^/policies/replication/(\d+)$`, uriRewrite("DELETE", "/replication/policies/{1}")),
+
+		// replication jobs, which are the tasks of executions in APIv2
+		sendRoute("POST", `^/replications$`, uriRewrite("POST", "/replication/executions")),
+		getRoute(`^/jobs/replication$`, getReplicationJobs),
+		sendRoute("PUT", `^/jobs/replication$`, nil),
+		sendRoute("DELETE", `^/jobs/replication/(\d+)$`, nil),
+		getRoute(`^/jobs/replication/(\d+)/log$`, nil),
+		getRoute(`^/jobs/scan/(\d+)/log$`, nil),
+	}
+}
+
+type getFunc func(c *apiClient, m []string, q url.Values) (interface{}, error)
+
+type sendFunc func(c *apiClient, m []string, body interface{}) (string, string, interface{}, error)
+
+func getRoute(pattern string, get getFunc) apiRoute {
+	return apiRoute{method: "GET", pattern: regexp.MustCompile(pattern), get: get}
+}
+
+func sendRoute(method, pattern string, send sendFunc) apiRoute {
+	return apiRoute{method: method, pattern: regexp.MustCompile(pattern), send: send}
+}
+
+// expand replaces '{n}' in s with the submatch n of m.
+func expand(s string, m []string) string {
+	for i := 1; i < len(m); i++ {
+		s = strings.Replace(s, "{"+strconv.Itoa(i)+"}", m[i], -1)
+	}
+	return s
+}
+
+// uriRewrite returns the route to uri of APIv2 with the body unchanged.
+func uriRewrite(method, uri string) sendFunc {
+	return func(c *apiClient, m []string, body interface{}) (string, string, interface{}, error) {
+		return method, "/api/v2.0" + expand(uri, m), body, nil
+	}
+}
+
+// repositoryURI returns the URI of repo, e.g. 'library/nginx', in APIv2,
+// where the name in the project is escaped twice for the slashes.
+func repositoryURI(repo string) (string, error) {
+	i := strings.Index(repo, "/")
+	if i <= 0 || i == len(repo)-1 {
+		return "", fmt.Errorf("invalid repository %q, must be PROJECT/NAME", repo)
+	}
+	return "/api/v2.0/projects/" + repo[:i] + "/repositories/" + url.PathEscape(url.PathEscape(repo[i+1:])), nil
+}
+
+// artifactURI returns the URI of the artifact of tag in repo in APIv2.
+func artifactURI(repo, tag string) (string, error) {
+	uri, err := repositoryURI(repo)
+	if err != nil {
+		return "", err
+	}
+	return uri + "/artifacts/" + url.PathEscape(tag), nil
+}
+
+func repositoryRewrite(method string) sendFunc {
+	return func(c *apiClient, m []string, body interface{}) (string, string, interface{}, error) {
+		uri, err := repositoryURI(m[1])
+		return method, uri, body, err
+	}
+}
+
+// artifactRewrite returns the route of a tag, whose path is m[1] and m[2],
+// to sub of the artifact.
+func artifactRewrite(method, sub string) sendFunc {
+	return func(c *apiClient, m []string, body interface{}) (string, string, interface{}, error) {
+		uri, err := artifactURI(m[1], m[2])
+		return method, uri + expand(sub, m), body, err
+	}
+}
+
+// projectNames are the names of projects by the base URL and the ID, guarded
+// by projectNamesMu.
+var (
+	projectNames   = make(map[string]string)
+	projectNamesMu sync.Mutex
+)
+
+func (c *apiClient) projectName(id string) (string, error) {
+	key := c.base + " " + id
+	projectNamesMu.Lock()
+	name, ok := projectNames[key]
+	projectNamesMu.Unlock()
+	if ok {
+		return name, nil
+	}
+
+	var p struct {
+		Name string `json:"name"`
+	}
+	if err := c.get("/api/v2.0/projects/"+id, &p); err != nil {
+		return "", err
+	}
+	projectNamesMu.Lock()
+	projectNames[key] = p.Name
+	projectNamesMu.Unlock()
+	return p.Name, nil
+}
+
+func (c *apiClient) projectID(name string) (int64, error) {
+	var projects []struct {
+		ProjectID int64  `json:"project_id"`
+		Name      string `json:"name"`
+	}
+	if err := c.get("/api/v2.0/projects?name="+url.QueryEscape(name), &projects); err != nil {
+		return 0, err
+	}
+	for _, p := range projects {
+		if p.Name == name {
+			projectNamesMu.Lock()
+			projectNames[c.base+" "+strconv.FormatInt(p.ProjectID, 10)] = name
+			projectNamesMu.Unlock()
+			return p.ProjectID, nil
+		}
+	}
+	return 0, fmt.Errorf("project %q not found", name)
+}
+
+// pageQuery returns the paging parameters of q.
+func pageQuery(q url.Values) url.Values {
+	v := url.Values{}
+	for _, k := range []string{"page", "page_size"} {
+		if s := q.Get(k); s != "" {
+			v.Set(k, s)
+		}
+	}
+	return v
+}
+
+// withQuery appends the query v to uri if not empty.
+func withQuery(uri string, v url.Values) string {
+	if len(v) == 0 {
+		return uri
+	}
+	return uri + "?" + v.Encode()
+}
+
+// paginate returns the page of items by the paging parameters of q, for the
+// items filtered or merged by the client.
+func paginate(items []map[string]interface{}, q url.Values) []map[string]interface{} {
+	page, _ := strconv.Atoi(q.Get("page"))
+	size, _ := strconv.Atoi(q.Get("page_size"))
+	if page <= 0 || size <= 0 {
+		return items
+	}
+
+	begin := (page - 1) * size
+	if begin >= len(items) {
+		return []map[string]interface{}{}
+	}
+	end := begin + size
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[begin:end]
+}
+
+type repositoryV2 struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	ProjectID     int64  `json:"project_id"`
+	Description   string `json:"description"`
+	ArtifactCount int64  `json:"artifact_count"`
+	PullCount     int64  `json:"pull_count"`
+	CreationTime  string `json:"creation_time"`
+	UpdateTime    string `json:"update_time"`
+}
+
+func getRepositories(c *apiClient, m []string, q url.Values) (interface{}, error) {
+	projectID := q.Get("project_id")
+	if projectID == "" {
+		return nil, errors.New("project_id is required")
+	}
+	name, err := c.projectName(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	v := pageQuery(q)
+	if s := q.Get("q"); s != "" {
+		v.Set("q", "name=~"+s)
+	}
+
+	var repos []repositoryV2
+	if err := c.get(withQuery("/api/v2.0/projects/"+url.PathEscape(name)+"/repositories", v), &repos); err != nil {
+		return nil, err
+	}
+
+	// NOTE: the number of artifacts stands for tags_count, which are equal
+	// unless an artifact has no tag or more than one.
+	res := make([]map[string]interface{}, 0, len(repos))
+	for _, r := range repos {
+		res = append(res, map[string]interface{}{
+			"id":            r.ID,
+			"name":          r.Name,
+			"project_id":    r.ProjectID,
+			"description":   r.Description,
+			"pull_count":    r.PullCount,
+			"star_count":    0,
+			"tags_count":    r.ArtifactCount,
+			"labels":        []interface{}{},
+			"creation_time": r.CreationTime,
+			"update_time":   r.UpdateTime,
+		})
+	}
+	return res, nil
+}
+
+func scanAll(c *apiClient, m []string, body interface{}) (string, string, interface{}, error) {
+	return "POST", "/api/v2.0/system/scanAll/schedule", map[string]interface{}{
+		"schedule": map[string]string{"type": "Manual"},
+	}, nil
+}
+
+type artifactV2 struct {
+	Digest     string `json:"digest"`
+	Size       int64  `json:"size"`
+	ExtraAttrs struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+		Author       string `json:"author"`
+		Created      string `json:"created"`
+	} `json:"extra_attrs"`
+	Tags []struct {
+		Name     string `json:"name"`
+		PushTime string `json:"push_time"`
+		Signed   bool   `json:"signed"`
+	} `json:"tags"`
+	Labels []json.RawMessage `json:"labels"`
+}
+
+// tags returns the tags of a in APIv1, one for each tag.
+func (a *artifactV2) tags() []map[string]interface{} {
+	labels := a.Labels
+	if labels == nil {
+		labels = []json.RawMessage{}
+	}
+
+	var tags []map[string]interface{}
+	for _, t := range a.Tags {
+		tags = append(tags, map[string]interface{}{
+			"digest":         a.Digest,
+			"name":           t.Name,
+			"size":           a.Size,
+			"architecture":   a.ExtraAttrs.Architecture,
+			"os":             a.ExtraAttrs.OS,
+			"docker_version": "",
+			"author":         a.ExtraAttrs.Author,
+			"created":        a.ExtraAttrs.Created,
+			"push_time":      t.PushTime,
+			"signature":      nil,
+			"labels":         labels,
+		})
+	}
+	return tags
+}
+
+const artifactQuery = "?with_tag=true&with_label=true"
+
+// artifacts gets all artifacts of repo.
+func (c *apiClient) artifacts(repo string) ([]artifactV2, error) {
+	uri, err := repositoryURI(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var artifacts []artifactV2
+	err = c.getPages(uri+"/artifacts"+artifactQuery, func(body json.RawMessage) (int, error) {
+		var page []artifactV2
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		artifacts = append(artifacts, page...)
+		return len(page), nil
+	})
+	return artifacts, err
+}
+
+func getTags(c *apiClient, m []string, q url.Values) (interface{}, error) {
+	artifacts, err := c.artifacts(m[1])
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []map[string]interface{}{}
+	for i := range artifacts {
+		tags = append(tags, artifacts[i].tags()...)
+	}
+	return tags, nil
+}
+
+func getTag(c *apiClient, m []string, q url.Values) (interface{}, error) {
+	uri, err := artifactURI(m[1], m[2])
+	if err != nil {
+		return nil, err
+	}
+
+	var a artifactV2
+	if err := c.get(uri+artifactQuery, &a); err != nil {
+		return nil, err
+	}
+	for _, t := range a.tags() {
+		if t["name"] == m[2] {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("tag %s not found in %s", m[2], m[1])
+}
+
+func getTagLabels(c *apiClient, m []string, q url.Values) (interface{}, error) {
+	t, err := getTag(c, m, q)
+	if err != nil {
+		return nil, err
+	}
+	return t.(map[string]interface{})["labels"], nil
+}
+
+// getSignatures returns the tags signed by Notary without hashes, as Harbor
+// 2.x does not expose the digests signed, and the digests of the artifacts
+// must not be taken as them.
+func getSignatures(c *apiClient, m []string, q url.Values) (interface{}, error) {
+	artifacts, err := c.artifacts(m[1])
+	if err != nil {
+		return nil, err
+	}
+
+	signatures := []map[string]interface{}{}
+	for _, a := range artifacts {
+		for _, t := range a.Tags {
+			if t.Signed {
+				signatures = append(signatures, map[string]interface{}{"tag": t.Name})
+			}
+		}
+	}
+	return signatures, nil
+}
+
+// getVulnerabilities returns the vulnerabilities of the first report, which
+// are of the scanner in APIv2.
+func getVulnerabilities(c *apiClient, m []string, q url.Values) (interface{}, error) {
+	uri, err := artifactURI(m[1], m[2])
+	if err != nil {
+		return nil, err
+	}
+
+	var reports map[string]struct {
+		Vulnerabilities []json.RawMessage `json:"vulnerabilities"`
+	}
+	if err := c.get(uri+"/additions/vulnerabilities", &reports); err != nil {
+		return nil, err
+	}
+	for _, r := range reports {
+		return r.Vulnerabilities, nil
+	}
+	return []json.RawMessage{}, nil
+}
+
+// getManifest gets the manifest of a tag and its config by the registry API,
+// as APIv2 does not expose them. The manifests of version 'v1', i.e. schema
+// 1, are not supported, which the registry of Harbor 2.x does not convert
+// to.
+func getManifest(c *apiClient, m []string, q url.Values) (interface{}, error) {
+	if v := q.Get("version"); v != "" && v != "v2" {
+		return nil, fmt.Errorf("manifest version %s is not supported by Harbor 2.x", v)
+	}
+
+	manifest, err := c.getRegistry("/v2/" + m[1] + "/manifests/" + url.PathEscape(m[2]))
+	if err != nil {
+		return nil, err
+	}
+	resp := map[string]interface{}{"manifest": json.RawMessage(manifest)}
+
+	var mf registry.Manifest
+	if err := json.Unmarshal(manifest, &mf); err != nil {
+		return nil, err
+	}
+	// NOTE: no config for a manifest list or an index, whose manifests are
+	// fetched by their digests as tags.
+	if mf.Config.Digest != "" {
+		config, err := c.getRegistry("/v2/" + m[1] + "/blobs/" + mf.Config.Digest)
+		if err != nil {
+			return nil, err
+		}
+		resp["config"] = string(config)
+	}
+	return resp, nil
+}
+
+// logTimeLayout is the layout of the time range of audit logs in APIv2.
+const logTimeLayout = "2006-01-02 15:04:05"
+
+func getLogs(c *apiClient, m []string, q url.Values) (interface{}, error) {
+	uri := "/api/v2.0/audit-logs"
+	if len(m) > 1 {
+		name, err := c.projectName(m[1])
+		if err != nil {
+			return nil, err
+		}
+		uri = "/api/v2.0/projects/" + url.PathEscape(name) + "/logs"
+	}
+
+	var terms []string
+	if s := q.Get("username"); s != "" {
+		terms = append(terms, "username=~"+s)
+	}
+	if s := q.Get("operation"); s != "" {
+		terms = append(terms, "operation="+s)
+	}
+	if s := q.Get("repository"); s != "" {
+		if t := q.Get("tag"); t != "" {
+			s += ":" + t
+		}
+		terms = append(terms, "resource=~"+s)
+	}
+	if begin, end := q.Get("begin_timestamp"), q.Get("end_timestamp"); begin != "" || end != "" {
+		terms = append(terms, "op_time=["+logTime(begin, 0)+"~"+logTime(end, time.Now().Unix())+"]")
+	}
+
+	v := pageQuery(q)
+	if terms != nil {
+		v.Set("q", strings.Join(terms, ","))
+	}
+
+	var logs []struct {
+		ID        int64  `json:"id"`
+		Username  string `json:"username"`
+		Resource  string `json:"resource"`
+		Operation string `json:"operation"`
+		OpTime    string `json:"op_time"`
+	}
+	if err := c.get(withQuery(uri, v), &logs); err != nil {
+		return nil, err
+	}
+
+	res := []map[string]interface{}{}
+	for _, l := range logs {
+		repo, tag := l.Resource, ""
+		if i := strings.LastIndex(l.Resource, ":"); i > strings.LastIndex(l.Resource, "/") {
+			repo, tag = l.Resource[:i], l.Resource[i+1:]
+		}
+		res = append(res, map[string]interface{}{
+			"log_id":    l.ID,
+			"username":  l.Username,
+			"repo_name": repo,
+			"repo_tag":  tag,
+			"operation": l.Operation,
+			"op_time":   l.OpTime,
+		})
+	}
+	return res, nil
+}
+
+// logTime formats the unix timestamp s, or def if empty.
+func logTime(s string, def int64) string {
+	t, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		t = def
+	}
+	return time.Unix(t, 0).UTC().Format(logTimeLayout)
+}
+
+type registryV2 struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	Type       string `json:"type"`
+	Insecure   bool   `json:"insecure"`
+	Credential struct {
+		AccessKey string `json:"access_key"`
+	} `json:"credential"`
+	CreationTime string `json:"creation_time"`
+	UpdateTime   string `json:"update_time"`
+}
+
+// target returns r as a replication target of APIv1.
+func (r *registryV2) target() map[string]interface{} {
+	return map[string]interface{}{
+		"id":            r.ID,
+		"name":          r.Name,
+		"endpoint":      r.URL,
+		"username":      r.Credential.AccessKey,
+		"password":      "",
+		"insecure":      r.Insecure,
+		"type":          0,
+		"creation_time": r.CreationTime,
+		"update_time":   r.UpdateTime,
+	}
+}
+
+func getTargets(c *apiClient, m []string, q url.Values) (interface{}, error) {
+	v := pageQuery(q)
+	if s := q.Get("name"); s != "" {
+		v.Set("q", "name=~"+s)
+	}
+
+	var registries []registryV2
+	if err := c.get(withQuery("/api/v2.0/registries", v), &registries); err != nil {
+		return nil, err
+	}
+
+	targets := []map[string]interface{}{}
+	for i := range registries {
+		targets = append(targets, registries[i].target())
+	}
+	return targets, nil
+}
+
+func getTarget(c *apiClient, m []string, q url.Values) (interface{}, error) {
+	var r registryV2
+	if err := c.get("/api/v2.0/registries/"+m[1], &r); err != nil {
+		return nil, err
+	}
+	return r.target(), nil
+}
+
+// targetV1 is the body of creating, updating or pinging a target in APIv1,
+// whose fields not given are nil.
+type targetV1 struct {
+	ID       *int64  `json:"id"`
+	Name     *string `json:"name"`
+	Endpoint *string `json:"endpoint"`
+	Username *string `json:"username"`
+	Password *string `json:"password"`
+	Insecure *bool   `json:"insecure"`
+}
+
+func createTarget(c *apiClient, m []string, body interface{}) (string, string, interface{}, error) {
+	var t targetV1
+	if err := convertJSON(body, &t); err != nil {
+		return "", "", nil, err
+	}
+
+	r := map[string]interface{}{"type": "harbor", "name": t.Name, "url": t.Endpoint, "insecure": t.Insecure}
+	if t.Username != nil {
+		r["credential"] = map[string]interface{}{
+			"type":          "basic",
+			"access_key":    t.Username,
+			"access_secret": t.Password,
+		}
+	}
+	return "POST", "/api/v2.0/registries", r, nil
+}
+
+// registryUpdate returns the fields given in t of updating or pinging a
+// registry in APIv2.
+func registryUpdate(t *targetV1) map[string]interface{} {
+	r := make(map[string]interface{})
+	if t.Name != nil {
+		r["name"] = *t.Name
+	}
+	if t.Endpoint != nil {
+		r["url"] = *t.Endpoint
+	}
+	if t.Username != nil {
+		r["credential_type"] = "basic"
+		r["access_key"] = *t.Username
+	}
+	if t.Password != nil {
+		r["access_secret"] = *t.Password
+	}
+	if t.Insecure != nil {
+		r["insecure"] = *t.Insecure
+	}
+	return r
+}
+
+func updateTarget(c *apiClient, m []string, body interface{}) (string, string, interface{}, error) {
+	var t targetV1
+	if err := convertJSON(body, &t); err != nil {
+		return "", "", nil, err
+	}
+	return "PUT", "/api/v2.0/registries/" + m[1], registryUpdate(&t), nil
+}
+
+func pingTarget(c *apiClient, m []string, body interface{}) (string, string, interface{}, error) {
+	var t targetV1
+	if err := convertJSON(body, &t); err != nil {
+		return "", "", nil, err
+	}
+
+	r := registryUpdate(&t)
+	r["type"] = "harbor"
+	if t.ID != nil {
+		r["id"] = *t.ID
+	}
+	return "POST", "/api/v2.0/registries/ping", r, nil
+}
+
+func getTargetPolicies(c *apiClient, m []string, q url.Values) (interface{}, error) {
+	id, _ := strconv.ParseInt(m[1], 10, 64)
+
+	policies, err := c.policies(url.Values{})
+	if err != nil {
+		return nil, err
+	}
+
+	res := []map[string]interface{}{}
+	for _, p := range policies {
+		if p.DestRegistry != nil && p.DestRegistry.ID == id {
+			v1, err := p.policy(c)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, v1)
+		}
+	}
+	return res, nil
+}
+
+type policyV2 struct {
+	ID           int64       `json:"id"`
+	Name         string      `json:"name"`
+	Description  string      `json:"description"`
+	DestRegistry *registryV2 `json:"dest_registry"`
+	Filters      []struct {
+		Type  string      `json:"type"`
+		Value interface{} `json:"value"`
+	} `json:"filters"`
+	Trigger struct {
+		Type            string `json:"type"`
+		TriggerSettings struct {
+			Cron string `json:"cron"`
+		} `json:"trigger_settings"`
+	} `json:"trigger"`
+	Deletion     bool   `json:"deletion"`
+	CreationTime string `json:"creation_time"`
+	UpdateTime   string `json:"update_time"`
+}
+
+// The trigger kinds of APIv1 by the trigger types of APIv2.
+var triggerKinds = map[string]string{
+	"manual":      "Manual",
+	"event_based": "Immediate",
+	"scheduled":   "Scheduled",
+}
+
+// policy returns p as a replication policy of APIv1, whose project is the
+// first part of the name filter, e.g. 'library/**'.
+func (p *policyV2) policy(c *apiClient) (map[string]interface{}, error) {
+	var projects, targets []map[string]interface{}
+	filters := []map[string]interface{}{}
+	for _, f := range p.Filters {
+		s, _ := f.Value.(string)
+		switch f.Type {
+		case "name":
+			project, repo := s, "**"
+			if i := strings.Index(s, "/"); i >= 0 {
+				project, repo = s[:i], s[i+1:]
+			}
+			id, err := c.projectID(project)
+			if err != nil {
+				return nil, fmt.Errorf("policy %s: %v", p.Name, err)
+			}
+			projects = append(projects, map[string]interface{}{"project_id": id, "name": project})
+			if repo != "**" {
+				filters = append(filters, map[string]interface{}{"kind": "repository", "value": repo})
+			}
+		case "tag":
+			filters = append(filters, map[string]interface{}{"kind": "tag", "value": s})
+		}
+	}
+	if p.DestRegistry != nil {
+		targets = append(targets, p.DestRegistry.target())
+	}
+
+	trigger := map[string]interface{}{"kind": triggerKinds[p.Trigger.Type]}
+	if p.Trigger.Type == "scheduled" {
+		param, err := scheduleParam(p.Trigger.TriggerSettings.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %v", p.Name, err)
+		}
+		trigger["schedule_param"] = param
+	}
+
+	return map[string]interface{}{
+		"id":                 p.ID,
+		"name":               p.Name,
+		"description":        p.Description,
+		"projects":           projects,
+		"targets":            targets,
+		"trigger":            trigger,
+		"filters":            filters,
+		"replicate_deletion": p.Deletion,
+		"creation_time":      p.CreationTime,
+		"update_time":        p.UpdateTime,
+	}, nil
+}
+
+// scheduleParam converts the cron of APIv2, e.g. '0 30 2 * * 1', to the
+// schedule of APIv1, whose weekday is from 1 for Monday to 7 for Sunday and
+// offtime is the seconds of the day in UTC.
+func scheduleParam(cron string) (map[string]interface{}, error) {
+	f := strings.Fields(cron)
+	if len(f) != 6 || f[3] != "*" || f[4] != "*" {
+		return nil, fmt.Errorf("schedule %q is not daily or weekly", cron)
+	}
+	sec, err1 := strconv.ParseInt(f[0], 10, 64)
+	min, err2 := strconv.ParseInt(f[1], 10, 64)
+	hour, err3 := strconv.ParseInt(f[2], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil, fmt.Errorf("schedule %q is not daily or weekly", cron)
+	}
+
+	param := map[string]interface{}{"type": "Daily", "offtime": hour*3600 + min*60 + sec}
+	if f[5] != "*" {
+		day, err := strconv.ParseInt(f[5], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q is not daily or weekly", cron)
+		}
+		if day == 0 {
+			day = 7
+		}
+		param["type"] = "Weekly"
+		param["weekday"] = day
+	}
+	return param, nil
+}
+
+// policies gets all replication policies of APIv2 by the query v.
+func (c *apiClient) policies(v url.Values) ([]policyV2, error) {
+	var policies []policyV2
+	err := c.getPages(withQuery("/api/v2.0/replication/policies", v), func(body json.RawMessage) (int, error) {
+		var page []policyV2
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		policies = append(policies, page...)
+		return len(page), nil
+	})
+	return policies, err
+}
+
+func getPolicies(c *apiClient, m []string, q url.Values) (interface{}, error) {
+	v := url.Values{}
+	if s := q.Get("name"); s != "" {
+		v.Set("q", "name=~"+s)
+	}
+	policies, err := c.policies(v)
+	if err != nil {
+		return nil, err
+	}
+
+	projectID := q.Get("project_id")
+	res := []map[string]interface{}{}
+	for _, p := range policies {
+		v1, err := p.policy(c)
+		if err != nil {
+			return nil, err
+		}
+		if projectID != "" && !hasProject(v1, projectID) {
+			continue
+		}
+		res = append(res, v1)
+	}
+	return paginate(res, q), nil
+}
+
+func hasProject(policy map[string]interface{}, projectID string) bool {
+	projects, _ := policy["projects"].([]map[string]interface{})
+	for _, p := range projects {
+		if fmt.Sprint(p["project_id"]) == projectID {
+			return true
+		}
+	}
+	return false
+}
+
+func getPolicy(c *apiClient, m []string, q url.Values) (interface{}, error) {
+	var p policyV2
+	if err := c.get("/api/v2.0/replication/policies/"+m[1], &p); err != nil {
+		return nil, err
+	}
+	return p.policy(c)
+}
+
+// policyV1 is the body of creating or updating a policy in APIv1.
+type policyV1 struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Projects    []struct {
+		ProjectID int64  `json:"project_id"`
+		Name      string `json:"name"`
+	} `json:"projects"`
+	Targets []struct {
+		ID int64 `json:"id"`
+	} `json:"targets"`
+	Trigger struct {
+		Kind          string `json:"kind"`
+		ScheduleParam *struct {
+			Type    string `json:"type"`
+			Weekday int64  `json:"weekday"`
+			Offtime int64  `json:"offtime"`
+		} `json:"schedule_param"`
+	} `json:"trigger"`
+	Filters []struct {
+		Kind  string      `json:"kind"`
+		Value interface{} `json:"value"`
+	} `json:"filters"`
+	ReplicateDeletion bool `json:"replicate_deletion"`
+}
+
+// policyBody converts the policy of APIv1 in body to APIv2.
+func (c *apiClient) policyBody(body interface{}) (map[string]interface{}, error) {
+	var p policyV1
+	if err := convertJSON(body, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Projects) != 1 || len(p.Targets) != 1 {
+		return nil, errors.New("a policy of Harbor 2.x replicates one project to one target")
+	}
+
+	project := p.Projects[0].Name
+	if project == "" {
+		name, err := c.projectName(strconv.FormatInt(p.Projects[0].ProjectID, 10))
+		if err != nil {
+			return nil, err
+		}
+		project = name
+	}
+
+	repo := "**"
+	var filters []map[string]interface{}
+	for _, f := range p.Filters {
+		switch f.Kind {
+		case "repository":
+			repo = fmt.Sprint(f.Value)
+		case "tag":
+			filters = append(filters, map[string]interface{}{"type": "tag", "value": fmt.Sprint(f.Value)})
+		case "label":
+			var l struct {
+				Name string `json:"name"`
+			}
+			if err := c.get("/api/v2.0/labels/"+fmt.Sprint(f.Value), &l); err != nil {
+				return nil, err
+			}
+			filters = append(filters, map[string]interface{}{"type": "label", "value": []string{l.Name}})
+		default:
+			return nil, fmt.Errorf("unknown filter kind %q", f.Kind)
+		}
+	}
+	filters = append(filters, map[string]interface{}{"type": "name", "value": project + "/" + repo})
+
+	trigger := map[string]interface{}{}
+	switch p.Trigger.Kind {
+	case "Manual":
+		trigger["type"] = "manual"
+	case "Immediate":
+		trigger["type"] = "event_based"
+	case "Scheduled":
+		s := p.Trigger.ScheduleParam
+		if s == nil {
+			return nil, errors.New("schedule_param is required by a scheduled trigger")
+		}
+		cron := fmt.Sprintf("%d %d %d * * *", s.Offtime%60, s.Offtime/60%60, s.Offtime/3600%24)
+		if s.Type == "Weekly" {
+			cron = cron[:len(cron)-1] + strconv.FormatInt(s.Weekday%7, 10)
+		}
+		trigger["type"] = "scheduled"
+		trigger["trigger_settings"] = map[string]string{"cron": cron}
+	default:
+		return nil, fmt.Errorf("unknown trigger kind %q", p.Trigger.Kind)
+	}
+
+	return map[string]interface{}{
+		"name":          p.Name,
+		"description":   p.Description,
+		"dest_registry": map[string]int64{"id": p.Targets[0].ID},
+		"filters":       filters,
+		"trigger":       trigger,
+		"deletion":      p.ReplicateDeletion,
+		"override":      true,
+		"enabled":       true,
+	}, nil
+}
+
+func createPolicy(c *apiClient, m []string, body interface{}) (string, string, interface{}, error) {
+	p, err := c.policyBody(body)
+	return "POST", "/api/v2.0/replication/policies", p, err
+}
+
+func updatePolicy(c *apiClient, m []string, body interface{}) (string, string, interface{}, error) {
+	p, err := c.policyBody(body)
+	return "PUT", "/api/v2.0/replication/policies/" + m[1], p, err
+}
+
+// The job statuses of APIv1 by the task statuses of APIv2.
+var jobStatuses = map[string]string{
+	"Pending":    "pending",
+	"InProgress": "running",
+	"Succeed":    "finished",
+	"Failed":     "error",
+	"Stopped":    "stopped",
+}
+
+// getReplicationJobs returns the tasks of the executions of a policy as the
+// jobs of APIv1, from the newest.
+func getReplicationJobs(c *apiClient, m []string, q url.Values) (interface{}, error) {
+	policyID, err := strconv.ParseInt(q.Get("policy_id"), 10, 64)
+	if err != nil {
+		return nil, errors.New("policy_id is required")
+	}
+
+	var executions []struct {
+		ID int64 `json:"id"`
+	}
+	err = c.getPages("/api/v2.0/replication/executions?policy_id="+strconv.FormatInt(policyID, 10), func(body json.RawMessage) (int, error) {
+		var page []struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		executions = append(executions, page...)
+		return len(page), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []map[string]interface{}{}
+	for _, e := range executions {
+		uri := "/api/v2.0/replication/executions/" + strconv.FormatInt(e.ID, 10) + "/tasks"
+		err := c.getPages(uri, func(body json.RawMessage) (int, error) {
+			var tasks []struct {
+				ID          int64  `json:"id"`
+				SrcResource string `json:"src_resource"`
+				Operation   string `json:"operation"`
+				Status      string `json:"status"`
+				StartTime   string `json:"start_time"`
+				EndTime     string `json:"end_time"`
+			}
+			if err := json.Unmarshal(body, &tasks); err != nil {
+				return 0, err
+			}
+
+			for _, t := range tasks {
+				// e.g. 'library/nginx:[1.14 1.15]'
+				repo, tags := t.SrcResource, ""
+				if i := strings.Index(repo, ":["); i >= 0 {
+					repo, tags = repo[:i], strings.TrimSuffix(repo[i+2:], "]")
+				}
+				status := jobStatuses[t.Status]
+				if s := q.Get("status"); s != "" && s != status {
+					continue
+				}
+				if s := q.Get("repository"); s != "" && !strings.Contains(repo, s) {
+					continue
+				}
+
+				operation := "transfer"
+				if t.Operation == "deletion" {
+					operation = "delete"
+				}
+				jobs = append(jobs, map[string]interface{}{
+					"id":            t.ID,
+					"status":        status,
+					"repository":    repo,
+					"policy_id":     policyID,
+					"operation":     operation,
+					"tags":          tags,
+					"creation_time": t.StartTime,
+					"update_time":   t.EndTime,
+				})
+			}
+			return len(tasks), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i]["id"].(int64) > jobs[j]["id"].(int64) })
+	return paginate(jobs, q), nil
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouteAPI(t *testing.T) {
+	base := "https://harbor2.example.com"
+	if err := SetAPIVersion(base, APIv2); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method, uri string
+		want        string
+	}{
+		{"GET", "/api/projects?name=library", "/api/v2.0/projects?name=library"},
+		{"HEAD", "/api/projects?project_name=library", "/api/v2.0/projects?project_name=library"},
+		{"PUT", "/api/projects/1/metadatas/public", "/api/v2.0/projects/1/metadatas/public"},
+		{"DELETE", "/api/projects/1/members/3", "/api/v2.0/projects/1/members/3"},
+		{"POST", "/api/projects/1/webhook/policies/test", "/api/v2.0/projects/1/webhook/policies/test"},
+		{"GET", "/api/users/current", "/api/v2.0/users/current"},
+		{"PUT", "/api/users/2/sysadmin", "/api/v2.0/users/2/sysadmin"},
+		{"GET", "/api/systeminfo/volumes", "/api/v2.0/systeminfo/volumes"},
+		{"DELETE", "/api/repositories/library/nginx", "/api/v2.0/projects/library/repositories/nginx"},
+		{"DELETE", "/api/targets/3", "/api/v2.0/registries/3"},
+		// unchanged
+		{"POST", "/api/chartrepo/library/charts", "/api/chartrepo/library/charts"},
+		{"GET", "/api/v2.0/projects", "/api/v2.0/projects"},
+		{"GET", "/v2/library/nginx/manifests/latest", "/v2/library/nginx/manifests/latest"},
+	}
+	for _, tt := range tests {
+		_, got, _, _, err := routeAPI(NewAgent(), "", tt.method, base+tt.uri, nil)
+		if err != nil {
+			t.Errorf("routeAPI(%s %s): %v", tt.method, tt.uri, err)
+			continue
+		}
+		if got != base+tt.want {
+			t.Errorf("routeAPI(%s %s) = %s, want %s", tt.method, tt.uri, got, base+tt.want)
+		}
+	}
+
+	for _, tt := range []struct{ method, uri string }{
+		{"POST", "/api/internal/syncregistry"},
+		{"GET", "/api/projects/1/unknown"},
+		{"GET", "/api/repositories/library/nginx/labels"},
+		{"GET", "/api/labels/1/resources"},
+		{"GET", "/api/unknown"},
+	} {
+		_, _, _, _, err := routeAPI(NewAgent(), "", tt.method, base+tt.uri, nil)
+		if err == nil || !strings.Contains(err.Error(), "not supported by Harbor 2.x") {
+			t.Errorf("routeAPI(%s %s): got error %v, want not supported", tt.method, tt.uri, err)
+		}
+	}
+}
+
+func TestRouteAPIManifest(t *testing.T) {
+	manifest := `{"schemaVersion":2,"mediaType":"application/vnd.docker.distribution.manifest.v2+json",` +
+		`"config":{"digest":"sha256:c0"},"layers":[{"digest":"sha256:l0","size":10}]}`
+	config := `{"architecture":"amd64"}`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("sid"); err != nil || c.Value != "S" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/library/nginx/manifests/1.15":
+			if !strings.Contains(r.Header.Get("Accept"), "application/vnd.docker.distribution.manifest.v2+json") {
+				w.WriteHeader(http.StatusNotAcceptable)
+				return
+			}
+			w.Write([]byte(manifest))
+		case "/v2/library/nginx/blobs/sha256:c0":
+			w.Write([]byte(config))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	if err := SetAPIVersion(ts.URL, APIv2); err != nil {
+		t.Fatal(err)
+	}
+
+	var resp struct {
+		Manifest json.RawMessage `json:"manifest"`
+		Config   string          `json:"config"`
+	}
+	uri := ts.URL + "/api/repositories/library/nginx/tags/1.15/manifest"
+	if err := getStruct(NewAgent(), "S", uri+"?version=v2", &resp); err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Manifest) != manifest || resp.Config != config {
+		t.Errorf("manifest = %s, config = %q", resp.Manifest, resp.Config)
+	}
+
+	if err := getStruct(NewAgent(), "S", uri+"?version=v1", &resp); err == nil {
+		t.Error("the manifest of version v1 succeeded")
+	}
+	if err := getStruct(NewAgent(), "S", ts.URL+"/api/repositories/library/nginx/tags/1.16/manifest", &resp); !IsStatus(err, http.StatusNotFound) {
+		t.Errorf("the manifest not found: got error %v", err)
+	}
+}
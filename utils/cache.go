@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// cacheEntry is a response body cached with the time fetched.
+type cacheEntry struct {
+	Time time.Time       `json:"time"`
+	Body json.RawMessage `json:"body"`
+}
+
+func readCache() map[string]cacheEntry {
+	cache := make(map[string]cacheEntry)
+	if b, err := ioutil.ReadFile(cachefile); err == nil {
+		json.Unmarshal(b, &cache)
+	}
+	return cache
+}
+
+// cacheLoad decodes the value of key cached within ttl into st, and reports
+// whether it is found.
+func cacheLoad(key string, ttl time.Duration, st interface{}) bool {
+	e, ok := readCache()[key]
+	return ok && time.Since(e.Time) < ttl && json.Unmarshal(e.Body, st) == nil
+}
+
+// cacheMaxAge is the age of the values dropped from the cache.
+const cacheMaxAge = 24 * time.Hour
+
+// cacheStore caches st as the value of key, and drops the values older than
+// cacheMaxAge. Failing to cache is ignored.
+func cacheStore(key string, st interface{}) {
+	body, err := json.Marshal(st)
+	if err != nil {
+		return
+	}
+
+	cache := readCache()
+	for k, e := range cache {
+		if time.Since(e.Time) >= cacheMaxAge {
+			delete(cache, k)
+		}
+	}
+	cache[key] = cacheEntry{time.Now(), body}
+	if b, err := json.Marshal(cache); err == nil {
+		ioutil.WriteFile(cachefile, b, 0600)
+	}
+}
+
+// GetStructCached is like GetStruct but reuses the response of targetURL
+// fetched within ttl, which is cached in .cache.json.
+//
+// NOTE: it is for the commands which must answer quickly and may answer
+// stale, e.g. the shell completion, and failing to cache is ignored.
+func GetStructCached(targetURL string, ttl time.Duration, st interface{}) error {
+	if cacheLoad(targetURL, ttl, st) {
+		return nil
+	}
+
+	if err := GetStruct(targetURL, st); err != nil {
+		return err
+	}
+	cacheStore(targetURL, st)
+	return nil
+}
+
+// CacheClean removes .cache.json entirely.
+//
+// This function is called in stage of logout, as the responses cached are
+// of the user.
+func CacheClean() {
+	os.Remove(cachefile)
+}
@@ -0,0 +1,333 @@
+// Package chart reads, packages and indexes helm charts without helm, which
+// is enough for managing the charts in the chart repositories of Harbor.
+package chart
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Metadata is the content of Chart.yaml.
+type Metadata struct {
+	APIVersion   string       `yaml:"apiVersion" json:"apiVersion"`
+	Name         string       `yaml:"name" json:"name"`
+	Version      string       `yaml:"version" json:"version"`
+	AppVersion   string       `yaml:"appVersion,omitempty" json:"appVersion,omitempty"`
+	KubeVersion  string       `yaml:"kubeVersion,omitempty" json:"kubeVersion,omitempty"`
+	Description  string       `yaml:"description,omitempty" json:"description,omitempty"`
+	Type         string       `yaml:"type,omitempty" json:"type,omitempty"`
+	Keywords     []string     `yaml:"keywords,omitempty" json:"keywords,omitempty"`
+	Home         string       `yaml:"home,omitempty" json:"home,omitempty"`
+	Sources      []string     `yaml:"sources,omitempty" json:"sources,omitempty"`
+	Icon         string       `yaml:"icon,omitempty" json:"icon,omitempty"`
+	Deprecated   bool         `yaml:"deprecated,omitempty" json:"deprecated,omitempty"`
+	Maintainers  []Maintainer `yaml:"maintainers,omitempty" json:"maintainers,omitempty"`
+	Dependencies []Dependency `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
+}
+
+// Maintainer is a maintainer of a chart.
+type Maintainer struct {
+	Name  string `yaml:"name,omitempty" json:"name,omitempty"`
+	Email string `yaml:"email,omitempty" json:"email,omitempty"`
+	URL   string `yaml:"url,omitempty" json:"url,omitempty"`
+}
+
+// Dependency is a dependency of a chart, in Chart.yaml of apiVersion v2 or in
+// requirements.yaml of v1.
+type Dependency struct {
+	Name       string   `yaml:"name" json:"name"`
+	Version    string   `yaml:"version" json:"version"`
+	Repository string   `yaml:"repository,omitempty" json:"repository,omitempty"`
+	Condition  string   `yaml:"condition,omitempty" json:"condition,omitempty"`
+	Tags       []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Alias      string   `yaml:"alias,omitempty" json:"alias,omitempty"`
+}
+
+// Validate checks the required fields of the metadata.
+func (m *Metadata) Validate() error {
+	if m.Name == "" {
+		return errors.New("chart: name is required in Chart.yaml")
+	}
+	if strings.ContainsAny(m.Name, "/\\ ") {
+		return fmt.Errorf("chart: invalid name %q in Chart.yaml", m.Name)
+	}
+	if m.Version == "" {
+		return errors.New("chart: version is required in Chart.yaml")
+	}
+	if _, err := ParseVersion(m.Version); err != nil {
+		return fmt.Errorf("chart: version in Chart.yaml: %v", err)
+	}
+	return nil
+}
+
+// ArchiveName returns the file name of the chart archive like 'mychart-0.1.0.tgz'.
+func (m *Metadata) ArchiveName() string {
+	return m.Name + "-" + m.Version + ".tgz"
+}
+
+// ParseMetadata parses the content of Chart.yaml.
+func ParseMetadata(b []byte) (*Metadata, error) {
+	m := &Metadata{}
+	if err := yaml.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("chart: Chart.yaml: %v", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LoadDir reads the metadata of the chart in dir.
+func LoadDir(dir string) (*Metadata, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, "Chart.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	return ParseMetadata(b)
+}
+
+// Package writes the chart in dir as a gzipped tar archive to w, with the
+// files under a directory of the chart name, and the files matching
+// .helmignore skipped.
+func Package(dir string, w io.Writer) (*Metadata, error) {
+	m, err := LoadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ignore, err := loadIgnore(filepath.Join(dir, ".helmignore"))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if ignore.match(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	now := time.Now()
+	for _, rel := range files {
+		b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		hdr := &tar.Header{
+			Name:     path.Join(m.Name, rel),
+			Mode:     0644,
+			Size:     int64(len(b)),
+			ModTime:  now,
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(b); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Digest returns the sha256 digest in hex of the archive, as in the index.
+func Digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// ProvenanceBody returns the content of the provenance file of the archive
+// before signing, which is Chart.yaml followed by the digest of the archive.
+func ProvenanceBody(m *Metadata, archive []byte) ([]byte, error) {
+	meta, err := yaml.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	files, err := yaml.Marshal(map[string]map[string]string{
+		"files": {m.ArchiveName(): "sha256:" + Digest(archive)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.Write(meta)
+	b.WriteString("\n...\n")
+	b.Write(files)
+	return b.Bytes(), nil
+}
+
+// Archive is a chart archive loaded in memory.
+type Archive struct {
+	Metadata *Metadata
+	// Files are the contents by the slash separated paths in the chart,
+	// e.g. 'values.yaml' and 'templates/deployment.yaml'.
+	Files map[string][]byte
+}
+
+// LoadArchive reads the chart archive b.
+func LoadArchive(b []byte) (*Archive, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("chart: %v", err)
+	}
+	defer gr.Close()
+
+	a := &Archive{Files: make(map[string][]byte)}
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("chart: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		// NOTE: the files are under a directory of the chart name.
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		i := strings.Index(name, "/")
+		if i < 0 {
+			continue
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("chart: %v", err)
+		}
+		a.Files[name[i+1:]] = data
+	}
+
+	meta, ok := a.Files["Chart.yaml"]
+	if !ok {
+		return nil, errors.New("chart: no Chart.yaml in archive")
+	}
+	if a.Metadata, err = ParseMetadata(meta); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Dependencies returns the dependencies of the chart, in Chart.yaml of
+// apiVersion v2 or in requirements.yaml of v1.
+func (a *Archive) Dependencies() ([]Dependency, error) {
+	if len(a.Metadata.Dependencies) > 0 {
+		return a.Metadata.Dependencies, nil
+	}
+
+	b, ok := a.Files["requirements.yaml"]
+	if !ok {
+		return nil, nil
+	}
+	var r struct {
+		Dependencies []Dependency `yaml:"dependencies"`
+	}
+	if err := yaml.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("chart: requirements.yaml: %v", err)
+	}
+	return r.Dependencies, nil
+}
+
+// Vendored reports whether the dependency is packaged in charts/ of the chart.
+func (a *Archive) Vendored(d *Dependency) bool {
+	if _, ok := a.Files["charts/"+d.Name+"/Chart.yaml"]; ok {
+		return true
+	}
+	_, ok := a.Files["charts/"+d.Name+"-"+d.Version+".tgz"]
+	return ok
+}
+
+// FileNames returns the sorted names of the files under dir, e.g. 'templates/'.
+func (a *Archive) FileNames(dir string) []string {
+	var names []string
+	for name := range a.Files {
+		if strings.HasPrefix(name, dir) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProvenanceDigests returns the digests of the archives by their names in the
+// signed provenance file, without verifying the signature.
+func ProvenanceDigests(prov []byte) (map[string]string, error) {
+	const begin, sig = "-----BEGIN PGP SIGNED MESSAGE-----", "-----BEGIN PGP SIGNATURE-----"
+
+	s := strings.Replace(string(prov), "\r\n", "\n", -1)
+	i, j := strings.Index(s, begin), strings.Index(s, sig)
+	if i < 0 || j < i {
+		return nil, errors.New("chart: provenance file is not a clear signed message")
+	}
+
+	// skip the armor headers, which end with an empty line.
+	body := s[i+len(begin) : j]
+	if k := strings.Index(body, "\n\n"); k >= 0 {
+		body = body[k+2:]
+	}
+
+	var lines []string
+	for _, l := range strings.Split(body, "\n") {
+		// NOTE: the dash-escaped lines of the message.
+		lines = append(lines, strings.TrimPrefix(l, "- "))
+	}
+	body = strings.Join(lines, "\n")
+
+	k := strings.LastIndex(body, "\n...\n")
+	if k < 0 {
+		return nil, errors.New("chart: no files in provenance file")
+	}
+	var f struct {
+		Files map[string]string `yaml:"files"`
+	}
+	if err := yaml.Unmarshal([]byte(body[k+5:]), &f); err != nil {
+		return nil, fmt.Errorf("chart: provenance file: %v", err)
+	}
+	return f.Files, nil
+}
@@ -0,0 +1,79 @@
+package chart
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+)
+
+// ignoreRule is a rule of .helmignore.
+type ignoreRule struct {
+	pattern string
+	dirOnly bool
+	negate  bool
+}
+
+type ignoreRules []ignoreRule
+
+// loadIgnore reads the rules of .helmignore. The rules are like .gitignore
+// except that '**' is not supported, which is the same as helm.
+func loadIgnore(file string) (ignoreRules, error) {
+	// NOTE: .helmignore itself is never packaged, as helm does.
+	rules := ignoreRules{{pattern: ".helmignore"}}
+
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return rules, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var r ignoreRule
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		r.pattern = strings.TrimPrefix(line, "/")
+		if _, err := path.Match(r.pattern, ""); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, scanner.Err()
+}
+
+// match reports whether the file of rel, the slash separated path in the
+// chart, is ignored. The last matching rule wins.
+func (rules ignoreRules) match(rel string, isDir bool) bool {
+	var ignored bool
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+
+		var matched bool
+		if strings.Contains(r.pattern, "/") {
+			matched, _ = path.Match(r.pattern, rel)
+		} else {
+			matched, _ = path.Match(r.pattern, path.Base(rel))
+		}
+		if matched {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,83 @@
+package chart
+
+import (
+	"fmt"
+	"sort"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// IndexFile is the index.yaml of a chart repository.
+type IndexFile struct {
+	APIVersion string                     `yaml:"apiVersion"`
+	Generated  string                     `yaml:"generated"`
+	Entries    map[string][]*ChartVersion `yaml:"entries"`
+}
+
+// ChartVersion is a version of a chart in the index.
+type ChartVersion struct {
+	Metadata `yaml:",inline"`
+	Created  string   `yaml:"created"`
+	Digest   string   `yaml:"digest"`
+	URLs     []string `yaml:"urls"`
+	Removed  bool     `yaml:"removed,omitempty"`
+}
+
+// ParseIndex parses index.yaml, with the versions of each chart sorted from
+// the latest.
+func ParseIndex(b []byte) (*IndexFile, error) {
+	i := &IndexFile{}
+	if err := yaml.Unmarshal(b, i); err != nil {
+		return nil, fmt.Errorf("chart: index.yaml: %v", err)
+	}
+
+	for _, versions := range i.Entries {
+		SortVersions(versions)
+	}
+	return i, nil
+}
+
+// SortVersions sorts the versions from the latest. The versions which are not
+// semantic are sorted last by their names.
+func SortVersions(versions []*ChartVersion) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		vi, erri := ParseVersion(versions[i].Version)
+		vj, errj := ParseVersion(versions[j].Version)
+		switch {
+		case erri == nil && errj == nil:
+			return vi.Compare(vj) > 0
+		case erri == nil:
+			return true
+		case errj == nil:
+			return false
+		default:
+			return versions[i].Version > versions[j].Version
+		}
+	})
+}
+
+// Get returns the chart of the version, or the latest stable version (the
+// one without pre-release) if version is empty.
+func (i *IndexFile) Get(name, version string) (*ChartVersion, error) {
+	versions, ok := i.Entries[name]
+	if !ok || len(versions) == 0 {
+		return nil, fmt.Errorf("chart: no chart named %q in index", name)
+	}
+
+	for _, cv := range versions {
+		if version != "" {
+			if cv.Version == version {
+				return cv, nil
+			}
+			continue
+		}
+		if v, err := ParseVersion(cv.Version); err == nil && v.Prerelease == "" {
+			return cv, nil
+		}
+	}
+
+	if version != "" {
+		return nil, fmt.Errorf("chart: no version %s of chart %s in index", version, name)
+	}
+	return nil, fmt.Errorf("chart: no stable version of chart %s in index, specify one", name)
+}
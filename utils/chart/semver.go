@@ -0,0 +1,251 @@
+package chart
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var versionRegexp = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?(?:\+([0-9A-Za-z.-]+))?$`)
+
+// Version is a semantic version of a chart, see https://semver.org.
+type Version struct {
+	Major, Minor, Patch int64
+	Prerelease          string
+	Metadata            string
+
+	original string
+}
+
+// ParseVersion parses a version like '1.2.3', '1.2.3-rc.1+build.5' or 'v1.2'.
+// The missing minor and patch are taken as 0.
+func ParseVersion(s string) (*Version, error) {
+	m := versionRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("invalid semantic version %q", s)
+	}
+
+	v := &Version{Prerelease: m[4], Metadata: m[5], original: s}
+	v.Major, _ = strconv.ParseInt(m[1], 10, 64)
+	if m[2] != "" {
+		v.Minor, _ = strconv.ParseInt(m[2], 10, 64)
+	}
+	if m[3] != "" {
+		v.Patch, _ = strconv.ParseInt(m[3], 10, 64)
+	}
+	return v, nil
+}
+
+func (v *Version) String() string {
+	return v.original
+}
+
+// Compare returns -1, 0 or 1 if v is lower than, equal to or higher than o.
+// The build metadata is ignored.
+func (v *Version) Compare(o *Version) int {
+	for _, d := range []int64{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// comparePrerelease compares the pre-release versions, a version without
+// pre-release is higher than one with.
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		an, aerr := strconv.ParseInt(as[i], 10, 64)
+		bn, berr := strconv.ParseInt(bs[i], 10, 64)
+		switch {
+		case aerr == nil && berr == nil:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case aerr == nil:
+			// numeric identifiers are lower than alphanumeric ones.
+			return -1
+		case berr == nil:
+			return 1
+		case as[i] < bs[i]:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// Range is a set of version constraints like '>=1.2.0 <2.0.0 || ^3.1'.
+type Range struct {
+	// any of the sets matches, where all the constraints of a set match.
+	sets [][]constraint
+}
+
+type constraint struct {
+	op string
+	v  *Version
+}
+
+// ParseRange parses a range of constraints, which are separated by spaces or
+// commas for 'and', and by '||' for 'or'. A constraint is one of:
+//
+//   - '1.2.3' or '=1.2.3', '!=1.2.3', '>1.2.3', '>=1.2.3', '<1.2.3', '<=1.2.3'
+//   - '1.2.x', '1.x' or '*' for the versions with the given parts
+//   - '~1.2.3' for '>=1.2.3 <1.3.0', and '~1' for '>=1.0.0 <2.0.0'
+//   - '^1.2.3' for '>=1.2.3 <2.0.0', and '^0.2.3' for '>=0.2.3 <0.3.0'
+//
+// NOTE: unlike npm, the pre-release versions are compared as usual, e.g.
+// '1.2.0-rc.1' matches '<1.2.0'.
+func ParseRange(s string) (*Range, error) {
+	r := &Range{}
+	for _, or := range strings.Split(s, "||") {
+		var set []constraint
+		for _, f := range strings.FieldsFunc(or, func(c rune) bool { return c == ' ' || c == ',' }) {
+			cs, err := parseConstraint(f)
+			if err != nil {
+				return nil, fmt.Errorf("invalid range %q: %v", s, err)
+			}
+			set = append(set, cs...)
+		}
+		if len(set) == 0 {
+			return nil, fmt.Errorf("invalid range %q: empty constraints", s)
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+func parseConstraint(s string) ([]constraint, error) {
+	op := ""
+	for _, o := range []string{">=", "<=", "!=", ">", "<", "=", "~", "^"} {
+		if strings.HasPrefix(s, o) {
+			op, s = o, strings.TrimSpace(s[len(o):])
+			break
+		}
+	}
+
+	// the wildcards, e.g. '1.2.x', '1.x', '1' and '*'.
+	parts := strings.Split(strings.TrimPrefix(s, "v"), ".")
+	n := len(parts)
+	for i, p := range parts {
+		if p == "x" || p == "X" || p == "*" {
+			n = i
+			break
+		}
+	}
+	if n < 3 && (op == "" || op == "=") {
+		if n == 0 {
+			return []constraint{{">=", &Version{Prerelease: "0", original: "0.0.0-0"}}}, nil
+		}
+		lo, err := ParseVersion(strings.Join(parts[:n], "."))
+		if err != nil {
+			return nil, err
+		}
+		return []constraint{{">=", lo}, {"<", bump(lo, n-1)}}, nil
+	}
+
+	v, err := ParseVersion(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "", "=":
+		return []constraint{{"=", v}}, nil
+	case "~":
+		if n < 2 {
+			return []constraint{{">=", v}, {"<", bump(v, 0)}}, nil
+		}
+		return []constraint{{">=", v}, {"<", bump(v, 1)}}, nil
+	case "^":
+		switch {
+		case v.Major > 0 || n < 2:
+			return []constraint{{">=", v}, {"<", bump(v, 0)}}, nil
+		case v.Minor > 0 || n < 3:
+			return []constraint{{">=", v}, {"<", bump(v, 1)}}, nil
+		default:
+			return []constraint{{">=", v}, {"<", bump(v, 2)}}, nil
+		}
+	default:
+		return []constraint{{op, v}}, nil
+	}
+}
+
+// bump returns the lowest version whose part i (0 for major) is higher than v's.
+func bump(v *Version, i int) *Version {
+	b := &Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	switch i {
+	case 0:
+		b.Major, b.Minor, b.Patch = v.Major+1, 0, 0
+	case 1:
+		b.Minor, b.Patch = v.Minor+1, 0
+	default:
+		b.Patch = v.Patch + 1
+	}
+	// NOTE: the lowest pre-release of the version, so that '<' excludes all
+	// the pre-releases of it.
+	b.Prerelease = "0"
+	b.original = fmt.Sprintf("%d.%d.%d-0", b.Major, b.Minor, b.Patch)
+	return b
+}
+
+// Match reports whether v satisfies the range.
+func (r *Range) Match(v *Version) bool {
+	for _, set := range r.sets {
+		matched := true
+		for _, c := range set {
+			if !c.match(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (c constraint) match(v *Version) bool {
+	d := v.Compare(c.v)
+	switch c.op {
+	case "=":
+		return d == 0
+	case "!=":
+		return d != 0
+	case ">":
+		return d > 0
+	case ">=":
+		return d >= 0
+	case "<":
+		return d < 0
+	default:
+		return d <= 0
+	}
+}
@@ -0,0 +1,127 @@
+package chart
+
+import "testing"
+
+func mustVersion(t *testing.T, s string) *Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		t.Fatalf("ParseVersion(%q): %v", s, err)
+	}
+	return v
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in                  string
+		major, minor, patch int64
+		prerelease, meta    string
+	}{
+		{"1.2.3", 1, 2, 3, "", ""},
+		{"v1.2.3", 1, 2, 3, "", ""},
+		{"1.2", 1, 2, 0, "", ""},
+		{"1", 1, 0, 0, "", ""},
+		{"0.1.0-rc.1", 0, 1, 0, "rc.1", ""},
+		{"1.2.3-rc.1+build.5", 1, 2, 3, "rc.1", "build.5"},
+		{"1.2.3+20181001", 1, 2, 3, "", "20181001"},
+	}
+	for _, tt := range tests {
+		v := mustVersion(t, tt.in)
+		if v.Major != tt.major || v.Minor != tt.minor || v.Patch != tt.patch ||
+			v.Prerelease != tt.prerelease || v.Metadata != tt.meta {
+			t.Errorf("ParseVersion(%q) = %+v", tt.in, *v)
+		}
+		if v.String() != tt.in {
+			t.Errorf("ParseVersion(%q).String() = %q", tt.in, v.String())
+		}
+	}
+
+	for _, in := range []string{"", "x", "1.2.3.4", "1.a", "1.2.3-", "1.2.3-rc_1", "-1.2.3"} {
+		if v, err := ParseVersion(in); err == nil {
+			t.Errorf("ParseVersion(%q) = %+v, want error", in, *v)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.2.3+a", "1.2.3+b", 0},
+		{"1.2.3", "1.2.4", -1},
+		{"1.3.0", "1.2.9", 1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.10.0", "1.9.0", 1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-alpha.beta", "1.0.0-beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0-beta.11", 1},
+	}
+	for _, tt := range tests {
+		a, b := mustVersion(t, tt.a), mustVersion(t, tt.b)
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("%s.Compare(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := b.Compare(a); got != -tt.want {
+			t.Errorf("%s.Compare(%s) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		rng      string
+		match    []string
+		mismatch []string
+	}{
+		{"1.2.3", []string{"1.2.3", "v1.2.3"}, []string{"1.2.4", "1.2.3-rc.1"}},
+		{"=1.2.3", []string{"1.2.3"}, []string{"1.2.2"}},
+		{"!=1.2.3", []string{"1.2.2", "1.2.4"}, []string{"1.2.3"}},
+		{">1.2.3", []string{"1.2.4", "2.0.0"}, []string{"1.2.3", "1.0.0"}},
+		{">=1.2.3 <2.0.0", []string{"1.2.3", "1.9.9", "2.0.0-rc.1"}, []string{"1.2.2", "2.0.0"}},
+		{">=1.2.3,<2.0.0", []string{"1.5.0"}, []string{"2.1.0"}},
+		{"<=1.2.3", []string{"1.2.3", "0.1.0"}, []string{"1.2.4"}},
+		{"1.2.x", []string{"1.2.0", "1.2.99"}, []string{"1.3.0", "1.1.9"}},
+		{"1.x", []string{"1.0.0", "1.99.0"}, []string{"2.0.0", "0.9.0"}},
+		{"1", []string{"1.0.0", "1.5.5"}, []string{"2.0.0"}},
+		{"*", []string{"0.0.0", "1.2.3", "0.1.0-rc.1"}, nil},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0", "1.2.2"}},
+		{"~1.2", []string{"1.2.0", "1.2.9"}, []string{"1.3.0"}},
+		{"~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{"^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"2.0.0", "1.2.2"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0", "0.2.2"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"^0.0", []string{"0.0.9"}, []string{"0.1.0"}},
+		{"<1.2.0", []string{"1.1.9", "1.2.0-rc.1"}, []string{"1.2.0"}},
+		{"<1.0.0 || ^3.1", []string{"0.9.0", "3.1.0", "3.9.9"}, []string{"1.0.0", "2.0.0", "4.0.0"}},
+	}
+	for _, tt := range tests {
+		r, err := ParseRange(tt.rng)
+		if err != nil {
+			t.Errorf("ParseRange(%q): %v", tt.rng, err)
+			continue
+		}
+		for _, s := range tt.match {
+			if !r.Match(mustVersion(t, s)) {
+				t.Errorf("ParseRange(%q).Match(%s) = false, want true", tt.rng, s)
+			}
+		}
+		for _, s := range tt.mismatch {
+			if r.Match(mustVersion(t, s)) {
+				t.Errorf("ParseRange(%q).Match(%s) = true, want false", tt.rng, s)
+			}
+		}
+	}
+}
+
+func TestParseRangeInvalid(t *testing.T) {
+	for _, in := range []string{"", " ", "1.2.3 ||", ">=", ">=a.b", "~x.1", "1.2.3.4"} {
+		if _, err := ParseRange(in); err == nil {
+			t.Errorf("ParseRange(%q) succeeded, want error", in)
+		}
+	}
+}
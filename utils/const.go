@@ -29,3 +29,4 @@ var errCookiesNotAvailable = errors.New("target cookies are not available")
 
 var configfile = "conf/config.yaml"
 var secretfile = "conf/.cookie.yaml"
+var cachefile = "conf/.cache.json"
@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/parnurzeal/gorequest"
+	"github.com/spf13/viper"
+)
+
+// Context is a named Harbor instance defined under 'contexts' in the
+// configuration file, for the commands working with more than one instance,
+// e.g.
+//
+//	contexts:
+//	  dev:
+//	    scheme: https
+//	    address: harbor-dev.example.com
+//	    username: admin
+//	    password_file: ~/.harbor/dev.password
+//	    api_version: v2.0
+//
+// The API version of each instance is detected unless 'api_version' is set,
+// see APIVersion.
+//
+// Unlike the instance of 'address', whose session is saved by 'harborctl
+// login', a context logs in with its own credentials when first used. The
+// password is 'password', or read from the environment variable of
+// 'password_env' or the file of 'password_file', and prompted for if none is
+// configured.
+type Context struct {
+	Name     string
+	Scheme   string `mapstructure:"scheme"`
+	Address  string `mapstructure:"address"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// PasswordEnv is the environment variable of the password.
+	PasswordEnv string `mapstructure:"password_env"`
+	// PasswordFile is the file of the password, whose trailing newline is
+	// trimmed.
+	PasswordFile string `mapstructure:"password_file"`
+	// APIVersion is the API version of the instance, detected if empty.
+	APIVersion string `mapstructure:"api_version"`
+
+	agent   *gorequest.SuperAgent
+	session string
+}
+
+// ContextNames returns the names of the contexts configured.
+func ContextNames() []string {
+	var names []string
+	for name := range viper.GetStringMap("contexts") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadContext loads the context of name from the configuration.
+func LoadContext(name string) (*Context, error) {
+	if !viper.IsSet("contexts." + name) {
+		return nil, fmt.Errorf("no context %q in configuration, available: %v", name, ContextNames())
+	}
+
+	c := &Context{Name: name}
+	if err := viper.UnmarshalKey("contexts."+name, c); err != nil {
+		return nil, fmt.Errorf("context %s: %v", name, err)
+	}
+	if c.Scheme == "" {
+		c.Scheme = "https"
+	}
+	if c.Address == "" {
+		return nil, fmt.Errorf("context %s: address is required", name)
+	}
+	if c.Username == "" {
+		return nil, fmt.Errorf("context %s: username is required", name)
+	}
+	if c.APIVersion != "" {
+		if err := SetAPIVersion(c.URL(""), c.APIVersion); err != nil {
+			return nil, fmt.Errorf("context %s: %v", name, err)
+		}
+	}
+
+	c.agent = NewAgent()
+	return c, nil
+}
+
+// CurrentContext returns the context of the instance of 'address', with the
+// session saved by 'harborctl login'.
+func CurrentContext() *Context {
+	c, _ := CookieLoad()
+	return &Context{
+		Name:    "current",
+		Scheme:  viper.GetString("scheme"),
+		Address: viper.GetString("address"),
+		agent:   request,
+		session: c,
+	}
+}
+
+// URL generates the target URL of the context like URLGen.
+func (c *Context) URL(uri string) string {
+	return c.Scheme + "://" + c.Address + uri
+}
+
+// Login logs in the instance if not yet.
+func (c *Context) Login() error {
+	// NOTE: the current context without a saved session sends requests
+	// anonymously, as GetStruct does.
+	if c.session != "" || c.Username == "" {
+		return nil
+	}
+
+	if c.Password == "" {
+		p, err := c.readPassword()
+		if err != nil {
+			return fmt.Errorf("context %s: %v", c.Name, err)
+		}
+		c.Password = p
+	}
+	if c.Password == "" {
+		fmt.Printf("Login %s as %s\n", c.Address, c.Username)
+		p, err := ReadPasswordFromTerm()
+		if err != nil {
+			return err
+		}
+		if p == "" {
+			return errors.New("password required")
+		}
+		c.Password = p
+	}
+
+	req, targetURL, err := LoginRequest(c.agent, c.URL(""))
+	if err != nil {
+		return fmt.Errorf("context %s: %v", c.Name, err)
+	}
+	fmt.Fprintln(os.Stderr, "==> POST", targetURL)
+
+	resp, _, errs := req.
+		Send("principal=" + url.QueryEscape(c.Username) + "&password=" + url.QueryEscape(c.Password)).
+		End()
+	for _, e := range errs {
+		if e != nil {
+			return fmt.Errorf("context %s: %v", c.Name, e)
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("context %s: failed to login as %s: %s", c.Name, c.Username, resp.Status)
+	}
+
+	sid, err := CookieFilter((*http.Response)(resp).Cookies(), SessionCookieName(c.URL("")))
+	if err != nil {
+		return fmt.Errorf("context %s: %v", c.Name, err)
+	}
+	c.session = sid
+	return nil
+}
+
+// readPassword reads the password from 'password_env' or 'password_file',
+// or returns "" if neither is configured.
+func (c *Context) readPassword() (string, error) {
+	if c.PasswordEnv != "" {
+		p := os.Getenv(c.PasswordEnv)
+		if p == "" {
+			return "", fmt.Errorf("environment variable %s of the password is not set", c.PasswordEnv)
+		}
+		return p, nil
+	}
+	if c.PasswordFile == "" {
+		return "", nil
+	}
+
+	file, err := homedir.Expand(c.PasswordFile)
+	if err != nil {
+		return "", err
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	p := strings.TrimRight(string(b), "\r\n")
+	if p == "" {
+		return "", fmt.Errorf("password file %s is empty", file)
+	}
+	return p, nil
+}
+
+// HasEndpoint reports whether endpoint, e.g. of a replication target, is of
+// the instance of the context, which compares the schemes, the hosts ignoring
+// case and the ports, the default one of the scheme if not given.
+func (c *Context) HasEndpoint(endpoint string) bool {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	return normalizeHost(u.Scheme, u.Host) == normalizeHost(c.Scheme, c.Address)
+}
+
+// normalizeHost returns 'scheme://host:port' of host, with the default port
+// of scheme if host has none.
+func normalizeHost(scheme, host string) string {
+	scheme = strings.ToLower(scheme)
+	if scheme == "" {
+		scheme = "https"
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "/"))
+
+	h, port, err := net.SplitHostPort(host)
+	if err != nil {
+		h, port = strings.Trim(host, "[]"), ""
+	}
+	if port == "" {
+		port = "443"
+		if scheme == "http" {
+			port = "80"
+		}
+	}
+	return scheme + "://" + net.JoinHostPort(h, port)
+}
+
+// GetStruct is like GetStruct of the package but gets uri of the context.
+func (c *Context) GetStruct(uri string, st interface{}) error {
+	if err := c.Login(); err != nil {
+		return err
+	}
+	return getStruct(c.agent, c.session, c.URL(uri), st)
+}
+
+// Send is like Send of the package but sends the request to uri of the context.
+func (c *Context) Send(method, uri string, st interface{}) (gorequest.Response, []byte, error) {
+	if err := c.Login(); err != nil {
+		return nil, nil, err
+	}
+	return send(c.agent, c.session, method, c.URL(uri), st)
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestContextHasEndpoint(t *testing.T) {
+	tests := []struct {
+		scheme, address string
+		endpoint        string
+		want            bool
+	}{
+		{"https", "harbor.example.com", "https://harbor.example.com", true},
+		{"https", "harbor.example.com", "https://harbor.example.com/", true},
+		{"https", "harbor.example.com", "https://Harbor.Example.com:443", true},
+		{"https", "harbor.example.com:443", "https://harbor.example.com", true},
+		{"", "harbor.example.com", "https://harbor.example.com", true},
+		{"https", "harbor.example.com", "harbor.example.com", true},
+		{"http", "harbor.example.com", "http://harbor.example.com:80", true},
+		{"https", "harbor.example.com:8443", "https://harbor.example.com:8443", true},
+		{"https", "[::1]", "https://[::1]:443", true},
+		{"https", "harbor.example.com", "http://harbor.example.com", false},
+		{"https", "harbor.example.com", "https://harbor.example.com:8443", false},
+		{"http", "harbor.example.com", "https://harbor.example.com:80", false},
+		{"https", "harbor.example.com", "https://harbor2.example.com", false},
+		{"https", "harbor.example.com", "://", false},
+	}
+	for _, tt := range tests {
+		c := &Context{Scheme: tt.scheme, Address: tt.address}
+		if got := c.HasEndpoint(tt.endpoint); got != tt.want {
+			t.Errorf("%s://%s HasEndpoint(%q) = %v, want %v", tt.scheme, tt.address, tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+func TestContextReadPassword(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "password")
+	if err := ioutil.WriteFile(file, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := ioutil.WriteFile(empty, []byte("\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("HARBORCTL_TEST_PASSWORD", "fromenv")
+	defer os.Unsetenv("HARBORCTL_TEST_PASSWORD")
+
+	tests := []struct {
+		c    Context
+		want string
+		err  bool
+	}{
+		{Context{}, "", false},
+		{Context{PasswordFile: file}, "s3cret", false},
+		{Context{PasswordEnv: "HARBORCTL_TEST_PASSWORD"}, "fromenv", false},
+		{Context{PasswordEnv: "HARBORCTL_TEST_PASSWORD", PasswordFile: file}, "fromenv", false},
+		{Context{PasswordEnv: "HARBORCTL_TEST_UNSET"}, "", true},
+		{Context{PasswordFile: filepath.Join(dir, "missing")}, "", true},
+		{Context{PasswordFile: empty}, "", true},
+	}
+	for _, tt := range tests {
+		got, err := tt.c.readPassword()
+		if got != tt.want || (err != nil) != tt.err {
+			t.Errorf("readPassword of %+v = %q, %v", tt.c, got, err)
+		}
+	}
+}
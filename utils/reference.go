@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	tagRegexp       = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestRegexp    = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
+	componentRegexp = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+)
+
+var errEmptyReference = errors.New("image reference is empty")
+
+// Reference is an image reference of form [host/]project/repo[:tag][@digest].
+type Reference struct {
+	Host       string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// String returns the reference in its canonical form.
+func (r *Reference) String() string {
+	s := r.Repository
+	if r.Host != "" {
+		s = r.Host + "/" + s
+	}
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Project returns the project part of the repository name.
+func (r *Reference) Project() string {
+	return strings.SplitN(r.Repository, "/", 2)[0]
+}
+
+// ParseReference parses an image reference like
+// 'harbor.example.com/library/nginx:1.15@sha256:...'.
+//
+// As with docker, the first path component is treated as a host only when it
+// contains a '.' or a ':', or equals 'localhost'. As Harbor always puts a
+// repository under a project, the repository part must contain a '/'.
+func ParseReference(s string) (*Reference, error) {
+	if s == "" {
+		return nil, errEmptyReference
+	}
+
+	var ref Reference
+	remainder := s
+
+	if i := strings.Index(remainder, "@"); i >= 0 {
+		ref.Digest = remainder[i+1:]
+		remainder = remainder[:i]
+		if !digestRegexp.MatchString(ref.Digest) {
+			return nil, fmt.Errorf("invalid digest %q in reference %q", ref.Digest, s)
+		}
+	}
+
+	// A ':' after the last '/' separates the tag, otherwise it belongs to the host port.
+	if i := strings.LastIndex(remainder, ":"); i > strings.LastIndex(remainder, "/") {
+		ref.Tag = remainder[i+1:]
+		remainder = remainder[:i]
+		if !tagRegexp.MatchString(ref.Tag) {
+			return nil, fmt.Errorf("invalid tag %q in reference %q", ref.Tag, s)
+		}
+	}
+
+	if i := strings.Index(remainder, "/"); i >= 0 {
+		first := remainder[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			ref.Host = first
+			remainder = remainder[i+1:]
+		}
+	}
+
+	ref.Repository = remainder
+	if !strings.Contains(ref.Repository, "/") {
+		return nil, fmt.Errorf("invalid reference %q: repository must be of format '<project>/<repo>'", s)
+	}
+	for _, c := range strings.Split(ref.Repository, "/") {
+		if !componentRegexp.MatchString(c) {
+			return nil, fmt.Errorf("invalid repository name %q in reference %q", ref.Repository, s)
+		}
+	}
+
+	return &ref, nil
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+
+	tests := []struct {
+		in   string
+		want Reference
+	}{
+		{"library/nginx", Reference{Repository: "library/nginx"}},
+		{"library/nginx:1.15", Reference{Repository: "library/nginx", Tag: "1.15"}},
+		{"library/nginx@" + digest, Reference{Repository: "library/nginx", Digest: digest}},
+		{"library/nginx:1.15@" + digest, Reference{Repository: "library/nginx", Tag: "1.15", Digest: digest}},
+		{"library/a/b-c:v1", Reference{Repository: "library/a/b-c", Tag: "v1"}},
+		{"harbor.example.com/library/nginx:1.15", Reference{Host: "harbor.example.com", Repository: "library/nginx", Tag: "1.15"}},
+		{"harbor:5000/library/nginx", Reference{Host: "harbor:5000", Repository: "library/nginx"}},
+		{"harbor:5000/library/nginx:latest", Reference{Host: "harbor:5000", Repository: "library/nginx", Tag: "latest"}},
+		{"localhost/library/nginx", Reference{Host: "localhost", Repository: "library/nginx"}},
+		// the first component is a project, as it has no '.' or ':'.
+		{"harbor/library/nginx", Reference{Repository: "harbor/library/nginx"}},
+	}
+	for _, tt := range tests {
+		ref, err := ParseReference(tt.in)
+		if err != nil {
+			t.Errorf("ParseReference(%q): %v", tt.in, err)
+			continue
+		}
+		if *ref != tt.want {
+			t.Errorf("ParseReference(%q) = %+v, want %+v", tt.in, *ref, tt.want)
+		}
+		if s := ref.String(); s != tt.in {
+			t.Errorf("ParseReference(%q).String() = %q", tt.in, s)
+		}
+	}
+}
+
+func TestParseReferenceInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"nginx",
+		"nginx:1.15",
+		"harbor.example.com/nginx",
+		"library/Nginx",
+		"library/nginx:",
+		"library/nginx:-bad",
+		"library/nginx:" + strings.Repeat("a", 129),
+		"library/nginx@sha256:short",
+		"library/nginx@" + strings.Repeat("ab", 32),
+		"library//nginx",
+		"library/nginx_/x",
+	}
+	for _, in := range tests {
+		if ref, err := ParseReference(in); err == nil {
+			t.Errorf("ParseReference(%q) = %+v, want error", in, *ref)
+		}
+	}
+}
+
+func TestReferenceProject(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"library/nginx", "library"},
+		{"harbor.example.com/team/a/b:v1", "team"},
+	}
+	for _, tt := range tests {
+		ref, err := ParseReference(tt.in)
+		if err != nil {
+			t.Fatalf("ParseReference(%q): %v", tt.in, err)
+		}
+		if p := ref.Project(); p != tt.want {
+			t.Errorf("ParseReference(%q).Project() = %q, want %q", tt.in, p, tt.want)
+		}
+	}
+}
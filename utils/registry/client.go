@@ -0,0 +1,390 @@
+// Package registry is a minimal client of the Docker Registry HTTP API V2
+// (namely the OCI distribution API) served by Harbor, which is enough for
+// pulling and pushing images without a Docker daemon.
+package registry
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client talks to a registry on behalf of a user. Anonymous access is used
+// when username is empty.
+type Client struct {
+	baseURL  string
+	username string
+	password string
+
+	client    *http.Client
+	challenge *challenge
+	tokens    map[string]string
+}
+
+// challenge is the parsed 'WWW-Authenticate' header from "GET /v2/".
+type challenge struct {
+	scheme string
+	params map[string]string
+}
+
+// NewClient returns a client of the registry at baseURL (e.g. 'https://harbor.example.com').
+//
+// NOTE: as the management API requests do, the server's certificate chain and
+// host name are not verified.
+func NewClient(baseURL, username, password string) *Client {
+	return &Client{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		username: username,
+		password: password,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+		tokens: make(map[string]string),
+	}
+}
+
+// Error is an error returned by the registry.
+type Error struct {
+	Status string
+	Errors []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (e *Error) Error() string {
+	if len(e.Errors) == 0 {
+		return e.Status
+	}
+	msgs := make([]string, 0, len(e.Errors))
+	for _, r := range e.Errors {
+		msgs = append(msgs, r.Code+": "+r.Message)
+	}
+	return e.Status + " (" + strings.Join(msgs, "; ") + ")"
+}
+
+func newError(resp *http.Response) error {
+	e := &Error{Status: resp.Status}
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	json.Unmarshal(body, e)
+	return e
+}
+
+// ping gets the authentication challenge of the registry.
+func (c *Client) ping() error {
+	resp, err := c.client.Get(c.baseURL + "/v2/")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	c.challenge = &challenge{params: make(map[string]string)}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized:
+		c.challenge.scheme, c.challenge.params = parseChallenge(resp.Header.Get("WWW-Authenticate"))
+		return nil
+	default:
+		return newError(resp)
+	}
+}
+
+// parseChallenge parses a header like 'Bearer realm="https://x/service/token",service="harbor-registry"'.
+func parseChallenge(header string) (string, map[string]string) {
+	params := make(map[string]string)
+
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	scheme := strings.ToLower(parts[0])
+	if len(parts) == 1 {
+		return scheme, params
+	}
+
+	rest := parts[1]
+	for rest != "" {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimSpace(rest[eq+1:])
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.Index(rest, ","); comma >= 0 {
+			value, rest = rest[:comma], rest[comma:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[key] = value
+
+		rest = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+	}
+
+	return scheme, params
+}
+
+// token gets a bearer token for scope (e.g. 'repository:library/nginx:pull') from
+// the token service of Harbor.
+func (c *Client) token(scope string) (string, error) {
+	if t, ok := c.tokens[scope]; ok {
+		return t, nil
+	}
+
+	realm := c.challenge.params["realm"]
+	if realm == "" {
+		return "", errors.New("registry: no realm in authentication challenge")
+	}
+
+	q := url.Values{}
+	if s := c.challenge.params["service"]; s != "" {
+		q.Set("service", s)
+	}
+	q.Set("scope", scope)
+
+	req, err := http.NewRequest("GET", realm+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", newError(resp)
+	}
+
+	var t struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", err
+	}
+	if t.Token == "" {
+		t.Token = t.AccessToken
+	}
+
+	c.tokens[scope] = t.Token
+	return t.Token, nil
+}
+
+// do sends req with the credentials required by scope.
+func (c *Client) do(req *http.Request, scope string) (*http.Response, error) {
+	if c.challenge == nil {
+		if err := c.ping(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch c.challenge.scheme {
+	case "bearer":
+		t, err := c.token(scope)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+t)
+	case "basic":
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	return c.client.Do(req)
+}
+
+func pullScope(repo string) string {
+	return "repository:" + repo + ":pull"
+}
+
+func pushScope(repo string) string {
+	return "repository:" + repo + ":pull,push"
+}
+
+// GetManifest fetches the manifest of repo by tag or digest, and returns its
+// content, media type and digest.
+func (c *Client) GetManifest(repo, reference string) ([]byte, string, string, error) {
+	req, err := http.NewRequest("GET", c.baseURL+"/v2/"+repo+"/manifests/"+reference, nil)
+	if err != nil {
+		return nil, "", "", err
+	}
+	req.Header.Set("Accept", ManifestAccept())
+
+	resp, err := c.do(req, pullScope(repo))
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", "", newError(resp)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	mediaType := resp.Header.Get("Content-Type")
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = strings.TrimSpace(mediaType[:i])
+	}
+	if mediaType == "" || mediaType == "application/json" || mediaType == "text/plain" {
+		mediaType = DetectMediaType(body)
+	}
+
+	d := Digest(body)
+	if IsDigest(reference) && reference != d {
+		return nil, "", "", fmt.Errorf("registry: manifest digest mismatch, expect %s, got %s", reference, d)
+	}
+
+	return body, mediaType, d, nil
+}
+
+// PutManifest uploads a manifest to repo under the reference (tag or digest).
+func (c *Client) PutManifest(repo, reference, mediaType string, manifest []byte) error {
+	req, err := http.NewRequest("PUT", c.baseURL+"/v2/"+repo+"/manifests/"+reference, bytes.NewReader(manifest))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mediaType)
+
+	resp, err := c.do(req, pushScope(repo))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return newError(resp)
+	}
+	return nil
+}
+
+// GetBlob opens the blob of repo by digest. The caller must close it.
+func (c *Client) GetBlob(repo, digest string) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", c.baseURL+"/v2/"+repo+"/blobs/"+digest, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req, pullScope(repo))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, newError(resp)
+	}
+	return resp.Body, nil
+}
+
+// BlobExists checks whether the blob exists in repo.
+func (c *Client) BlobExists(repo, digest string) (bool, error) {
+	req, err := http.NewRequest("HEAD", c.baseURL+"/v2/"+repo+"/blobs/"+digest, nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.do(req, pushScope(repo))
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, newError(resp)
+	}
+}
+
+// BlobSize returns the size of the blob of repo by digest.
+func (c *Client) BlobSize(repo, digest string) (int64, error) {
+	req, err := http.NewRequest("HEAD", c.baseURL+"/v2/"+repo+"/blobs/"+digest, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := c.do(req, pullScope(repo))
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, newError(resp)
+	}
+	return resp.ContentLength, nil
+}
+
+// PutBlob uploads size bytes from r as a blob of repo, by a monolithic upload.
+func (c *Client) PutBlob(repo, digest string, size int64, r io.Reader) error {
+	req, err := http.NewRequest("POST", c.baseURL+"/v2/"+repo+"/blobs/uploads/", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req, pushScope(repo))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return newError(resp)
+	}
+
+	location, err := resp.Location()
+	if err != nil {
+		return err
+	}
+	q := location.Query()
+	q.Set("digest", digest)
+	location.RawQuery = q.Encode()
+
+	req, err = http.NewRequest("PUT", location.String(), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Length", strconv.FormatInt(size, 10))
+
+	resp, err = c.do(req, pushScope(repo))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return newError(resp)
+	}
+	return nil
+}
@@ -0,0 +1,489 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testRegistry is an in-memory registry behind a token service like Harbor's.
+type testRegistry struct {
+	*httptest.Server
+	username, password string
+
+	mu sync.Mutex
+	// blobs and manifests are keyed by 'repo@digest', and manifests by
+	// 'repo@tag' too.
+	blobs     map[string][]byte
+	manifests map[string]testManifest
+	// scopes are the scopes of the tokens issued.
+	scopes []string
+	// corrupt and truncate are the blobs served with wrong content, and cut
+	// off in the middle respectively.
+	corrupt  map[string]bool
+	truncate map[string]bool
+}
+
+type testManifest struct {
+	mediaType string
+	body      []byte
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	r := &testRegistry{
+		username:  "admin",
+		password:  "Harbor12345",
+		blobs:     make(map[string][]byte),
+		manifests: make(map[string]testManifest),
+		corrupt:   make(map[string]bool),
+		truncate:  make(map[string]bool),
+	}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serve))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *testRegistry) serve(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if req.URL.Path == "/service/token" {
+		r.serveToken(w, req)
+		return
+	}
+	if !strings.HasPrefix(req.URL.Path, "/v2/") {
+		http.NotFound(w, req)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	auth := req.Header.Get("Authorization")
+	if path == "" {
+		if !strings.HasPrefix(auth, "Bearer ") {
+			r.challenge(w)
+		}
+		return
+	}
+
+	var repo, kind, rest string
+	for _, k := range []string{"/manifests/", "/blobs/uploads/", "/blobs/"} {
+		if i := strings.LastIndex(path, k); i >= 0 {
+			repo, kind, rest = path[:i], strings.Trim(k, "/"), path[i+len(k):]
+			break
+		}
+	}
+	if repo == "" {
+		http.NotFound(w, req)
+		return
+	}
+
+	action := "pull"
+	if req.Method == "PUT" || req.Method == "POST" {
+		action = "push"
+	}
+	scope := strings.TrimPrefix(auth, "Bearer tok:")
+	if !strings.HasPrefix(scope, "repository:"+repo+":") || !strings.Contains(scope, action) {
+		r.challenge(w)
+		return
+	}
+
+	switch {
+	case kind == "manifests" && req.Method == "GET":
+		m, ok := r.manifests[repo+"@"+rest]
+		if !ok {
+			writeTestError(w, http.StatusNotFound, "MANIFEST_UNKNOWN")
+			return
+		}
+		w.Header().Set("Content-Type", m.mediaType)
+		w.Write(m.body)
+
+	case kind == "manifests" && req.Method == "PUT":
+		body, _ := ioutil.ReadAll(req.Body)
+		m := testManifest{req.Header.Get("Content-Type"), body}
+		r.manifests[repo+"@"+rest] = m
+		r.manifests[repo+"@"+Digest(body)] = m
+		w.WriteHeader(http.StatusCreated)
+
+	case kind == "blobs" && (req.Method == "GET" || req.Method == "HEAD"):
+		b, ok := r.blobs[repo+"@"+rest]
+		if !ok {
+			writeTestError(w, http.StatusNotFound, "BLOB_UNKNOWN")
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(b)))
+		if req.Method == "HEAD" {
+			return
+		}
+		switch {
+		case r.corrupt[rest]:
+			b = append([]byte{b[0] ^ 0xff}, b[1:]...)
+		case r.truncate[rest]:
+			// the server drops the connection as it writes less than
+			// Content-Length.
+			b = b[:len(b)/2]
+		}
+		w.Write(b)
+
+	case kind == "blobs/uploads" && req.Method == "POST":
+		w.Header().Set("Location", "/v2/"+repo+"/blobs/uploads/session-1")
+		w.WriteHeader(http.StatusAccepted)
+
+	case kind == "blobs/uploads" && req.Method == "PUT":
+		body, _ := ioutil.ReadAll(req.Body)
+		digest := req.URL.Query().Get("digest")
+		if Digest(body) != digest {
+			writeTestError(w, http.StatusBadRequest, "DIGEST_INVALID")
+			return
+		}
+		r.blobs[repo+"@"+digest] = body
+		w.WriteHeader(http.StatusCreated)
+
+	default:
+		writeTestError(w, http.StatusMethodNotAllowed, "UNSUPPORTED")
+	}
+}
+
+func (r *testRegistry) challenge(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="`+r.URL+`/service/token",service="harbor-registry"`)
+	writeTestError(w, http.StatusUnauthorized, "UNAUTHORIZED")
+}
+
+func (r *testRegistry) serveToken(w http.ResponseWriter, req *http.Request) {
+	username, password, ok := req.BasicAuth()
+	if !ok || username != r.username || password != r.password || req.URL.Query().Get("service") != "harbor-registry" {
+		writeTestError(w, http.StatusUnauthorized, "UNAUTHORIZED")
+		return
+	}
+	scope := req.URL.Query().Get("scope")
+	r.scopes = append(r.scopes, scope)
+	json.NewEncoder(w).Encode(map[string]string{"token": "tok:" + scope})
+}
+
+func writeTestError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"errors":[{"code":%q,"message":"test"}]}`, code)
+}
+
+// addImage stores an image of a config and a layer in the registry, and
+// returns the manifest.
+func (r *testRegistry) addImage(repo, tag string) ([]byte, *Manifest) {
+	config := []byte(`{"architecture":"amd64","os":"linux","rootfs":{"type":"layers","diff_ids":[]}}`)
+	layer := bytes.Repeat([]byte("layer of "+repo+":"+tag+"\n"), 1000)
+	m := &Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeDockerManifest,
+		Config:        Descriptor{MediaType: MediaTypeDockerConfig, Digest: Digest(config), Size: int64(len(config))},
+		Layers:        []Descriptor{{MediaType: MediaTypeDockerLayer, Digest: Digest(layer), Size: int64(len(layer))}},
+	}
+	body, _ := json.Marshal(m)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.blobs[repo+"@"+m.Config.Digest] = config
+	r.blobs[repo+"@"+m.Layers[0].Digest] = layer
+	tm := testManifest{MediaTypeDockerManifest, body}
+	r.manifests[repo+"@"+tag] = tm
+	r.manifests[repo+"@"+Digest(body)] = tm
+	return body, m
+}
+
+func TestClientTokenAuth(t *testing.T) {
+	r := newTestRegistry(t)
+	body, _ := r.addImage("library/app", "1.0")
+
+	c := NewClient(r.URL, r.username, r.password)
+	for i := 0; i < 2; i++ {
+		got, mediaType, digest, err := c.GetManifest("library/app", "1.0")
+		if err != nil {
+			t.Fatalf("GetManifest: %v", err)
+		}
+		if !bytes.Equal(got, body) || mediaType != MediaTypeDockerManifest || digest != Digest(body) {
+			t.Errorf("GetManifest = %s, %s, %s", got, mediaType, digest)
+		}
+	}
+	if _, err := c.BlobExists("library/app", Digest(body)); err != nil {
+		t.Fatalf("BlobExists: %v", err)
+	}
+
+	// a token is requested once for each scope.
+	want := []string{"repository:library/app:pull", "repository:library/app:pull,push"}
+	if fmt.Sprint(r.scopes) != fmt.Sprint(want) {
+		t.Errorf("scopes of tokens = %v, want %v", r.scopes, want)
+	}
+}
+
+func TestClientTokenAuthRejected(t *testing.T) {
+	r := newTestRegistry(t)
+	r.addImage("library/app", "1.0")
+
+	for _, c := range []*Client{
+		NewClient(r.URL, r.username, "wrong"),
+		NewClient(r.URL, "", ""),
+	} {
+		_, _, _, err := c.GetManifest("library/app", "1.0")
+		if e, ok := err.(*Error); !ok || !strings.HasPrefix(e.Status, "401") || e.Errors[0].Code != "UNAUTHORIZED" {
+			t.Errorf("GetManifest as %q: got error %v, want 401 UNAUTHORIZED", c.username, err)
+		}
+	}
+	if len(r.scopes) != 0 {
+		t.Errorf("tokens issued for %v, want none", r.scopes)
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	tests := []struct {
+		header string
+		scheme string
+		params map[string]string
+	}{
+		{`Basic realm="harbor"`, "basic", map[string]string{"realm": "harbor"}},
+		{`Bearer realm="https://h/service/token",service="harbor-registry"`, "bearer",
+			map[string]string{"realm": "https://h/service/token", "service": "harbor-registry"}},
+		{`Bearer realm="https://h/a,b", service=harbor-registry, scope="x"`, "bearer",
+			map[string]string{"realm": "https://h/a,b", "service": "harbor-registry", "scope": "x"}},
+		{`Bearer`, "bearer", map[string]string{}},
+	}
+	for _, tt := range tests {
+		scheme, params := parseChallenge(tt.header)
+		if scheme != tt.scheme || fmt.Sprint(params) != fmt.Sprint(tt.params) {
+			t.Errorf("parseChallenge(%q) = %q, %v, want %q, %v", tt.header, scheme, params, tt.scheme, tt.params)
+		}
+	}
+}
+
+func TestGetManifestVerifiesDigest(t *testing.T) {
+	r := newTestRegistry(t)
+	body, _ := r.addImage("library/app", "1.0")
+	other, _ := r.addImage("library/app", "2.0")
+
+	// the registry serves the manifest of 2.0 for the digest of 1.0.
+	r.manifests["library/app@"+Digest(body)] = testManifest{MediaTypeDockerManifest, other}
+
+	c := NewClient(r.URL, r.username, r.password)
+	_, _, _, err := c.GetManifest("library/app", Digest(body))
+	if err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Errorf("GetManifest: got error %v, want digest mismatch", err)
+	}
+}
+
+// pullToLayout pulls the image of repo by reference into the layout, as
+// 'image pull' does.
+func pullToLayout(c *Client, l Layout, repo, reference string) (Descriptor, error) {
+	body, mediaType, digest, err := c.GetManifest(repo, reference)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return Descriptor{}, err
+	}
+
+	for _, b := range append([]Descriptor{m.Config}, m.Layers...) {
+		if l.HasBlob(b.Digest) {
+			continue
+		}
+		rc, err := c.GetBlob(repo, b.Digest)
+		if err != nil {
+			return Descriptor{}, err
+		}
+		err = l.WriteBlob(b.Digest, rc)
+		rc.Close()
+		if err != nil {
+			return Descriptor{}, err
+		}
+	}
+
+	desc := Descriptor{MediaType: mediaType, Digest: digest, Size: int64(len(body))}
+	if err := l.WriteBlob(digest, bytes.NewReader(body)); err != nil {
+		return Descriptor{}, err
+	}
+	return desc, l.AddManifest(desc, reference)
+}
+
+// pushFromLayout pushes the image named refName in the layout to repo, as
+// 'image push' does. It returns the number of blobs uploaded.
+func pushFromLayout(c *Client, l Layout, refName, repo, tag string) (int, error) {
+	desc, err := l.Find(refName)
+	if err != nil {
+		return 0, err
+	}
+	body, err := l.ReadBlob(desc.Digest)
+	if err != nil {
+		return 0, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return 0, err
+	}
+
+	uploaded := 0
+	for _, b := range append([]Descriptor{m.Config}, m.Layers...) {
+		exists, err := c.BlobExists(repo, b.Digest)
+		if err != nil {
+			return uploaded, err
+		}
+		if exists {
+			continue
+		}
+		f, err := l.OpenBlob(b.Digest)
+		if err != nil {
+			return uploaded, err
+		}
+		err = c.PutBlob(repo, b.Digest, b.Size, f)
+		f.Close()
+		if err != nil {
+			return uploaded, err
+		}
+		uploaded++
+	}
+	return uploaded, c.PutManifest(repo, tag, desc.MediaType, body)
+}
+
+func TestPushPullRoundTrip(t *testing.T) {
+	r := newTestRegistry(t)
+	body, m := r.addImage("library/app", "1.0")
+	c := NewClient(r.URL, r.username, r.password)
+
+	src := Layout{Path: filepath.Join(t.TempDir(), "src")}
+	desc, err := pullToLayout(c, src, "library/app", "1.0")
+	if err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	if desc.Digest != Digest(body) {
+		t.Errorf("pulled digest %s, want %s", desc.Digest, Digest(body))
+	}
+
+	n, err := pushFromLayout(c, src, "1.0", "team/copy", "v1")
+	if err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("pushed %d blobs, want 2", n)
+	}
+	// the blobs exist now, so only the manifest is pushed again.
+	if n, err = pushFromLayout(c, src, "1.0", "team/copy", "v2"); err != nil || n != 0 {
+		t.Errorf("push again: %d blobs pushed, error %v, want 0 and no error", n, err)
+	}
+
+	dst := Layout{Path: filepath.Join(t.TempDir(), "dst")}
+	desc, err = pullToLayout(c, dst, "team/copy", "v1")
+	if err != nil {
+		t.Fatalf("pull the pushed image: %v", err)
+	}
+	if desc.Digest != Digest(body) {
+		t.Errorf("round trip digest %s, want %s", desc.Digest, Digest(body))
+	}
+
+	for _, b := range append([]Descriptor{m.Config, desc}, m.Layers...) {
+		want, err := src.ReadBlob(b.Digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := dst.ReadBlob(b.Digest)
+		if err != nil {
+			t.Errorf("blob %s is not pulled: %v", b.Digest, err)
+		} else if !bytes.Equal(got, want) {
+			t.Errorf("blob %s differs after the round trip", b.Digest)
+		}
+	}
+
+	found, err := dst.Find("v1")
+	if err != nil || found.Digest != Digest(body) {
+		t.Errorf("Find(v1) = %+v, %v", found, err)
+	}
+}
+
+func TestPutBlobRejectedDigest(t *testing.T) {
+	r := newTestRegistry(t)
+	c := NewClient(r.URL, r.username, r.password)
+
+	content := []byte("content")
+	err := c.PutBlob("library/app", Digest([]byte("other")), int64(len(content)), bytes.NewReader(content))
+	if e, ok := err.(*Error); !ok || e.Errors[0].Code != "DIGEST_INVALID" {
+		t.Errorf("PutBlob: got error %v, want DIGEST_INVALID", err)
+	}
+}
+
+func TestPullVerifiesBlobDigest(t *testing.T) {
+	r := newTestRegistry(t)
+	_, m := r.addImage("library/app", "1.0")
+	r.corrupt[m.Layers[0].Digest] = true
+
+	l := Layout{Path: t.TempDir()}
+	_, err := pullToLayout(NewClient(r.URL, r.username, r.password), l, "library/app", "1.0")
+	if err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Fatalf("pull: got error %v, want digest mismatch", err)
+	}
+	if l.HasBlob(m.Layers[0].Digest) {
+		t.Error("the corrupt blob is written")
+	}
+	assertNoTempBlobs(t, l)
+}
+
+func TestPullInterrupted(t *testing.T) {
+	r := newTestRegistry(t)
+	_, m := r.addImage("library/app", "1.0")
+	r.truncate[m.Layers[0].Digest] = true
+
+	l := Layout{Path: t.TempDir()}
+	c := NewClient(r.URL, r.username, r.password)
+	if _, err := pullToLayout(c, l, "library/app", "1.0"); err == nil {
+		t.Fatal("pull of a truncated blob succeeded")
+	}
+	if l.HasBlob(m.Layers[0].Digest) {
+		t.Error("the truncated blob is written")
+	}
+	if _, err := l.Index(); !os.IsNotExist(err) {
+		t.Errorf("index.json is written: %v", err)
+	}
+	assertNoTempBlobs(t, l)
+
+	// a retry after the registry recovers completes the pull.
+	delete(r.truncate, m.Layers[0].Digest)
+	if _, err := pullToLayout(c, l, "library/app", "1.0"); err != nil {
+		t.Fatalf("pull again: %v", err)
+	}
+	if !l.HasBlob(m.Layers[0].Digest) {
+		t.Error("the blob is not written")
+	}
+}
+
+// assertNoTempBlobs checks no temporary file is left in the blobs of l.
+func assertNoTempBlobs(t *testing.T, l Layout) {
+	t.Helper()
+	filepath.Walk(l.Path, func(path string, fi os.FileInfo, err error) error {
+		if err == nil && strings.HasPrefix(fi.Name(), ".tmp-") {
+			t.Errorf("temporary file %s is left", path)
+		}
+		return nil
+	})
+}
+
+// errReader returns err after n bytes of r.
+type errReader struct {
+	r   io.Reader
+	n   int
+	err error
+}
+
+func (e *errReader) Read(p []byte) (int, error) {
+	if e.n <= 0 {
+		return 0, e.err
+	}
+	if len(p) > e.n {
+		p = p[:e.n]
+	}
+	n, err := e.r.Read(p)
+	e.n -= n
+	return n, err
+}
@@ -0,0 +1,194 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Config is the image configuration of docker or OCI.
+type Config struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+	Created      string `json:"created,omitempty"`
+	Author       string `json:"author,omitempty"`
+	Config       struct {
+		User         string              `json:"User,omitempty"`
+		Env          []string            `json:"Env,omitempty"`
+		Entrypoint   []string            `json:"Entrypoint,omitempty"`
+		Cmd          []string            `json:"Cmd,omitempty"`
+		WorkingDir   string              `json:"WorkingDir,omitempty"`
+		Labels       map[string]string   `json:"Labels,omitempty"`
+		ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+		Volumes      map[string]struct{} `json:"Volumes,omitempty"`
+	} `json:"config"`
+	RootFS struct {
+		Type    string   `json:"type"`
+		DiffIDs []string `json:"diff_ids"`
+	} `json:"rootfs"`
+	History []History `json:"history,omitempty"`
+}
+
+// History is a step which built the image.
+type History struct {
+	Created    string `json:"created,omitempty"`
+	CreatedBy  string `json:"created_by,omitempty"`
+	Author     string `json:"author,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+	EmptyLayer bool   `json:"empty_layer,omitempty"`
+}
+
+// Schema1Manifest is a docker schema1 manifest.
+type Schema1Manifest struct {
+	Name         string `json:"name"`
+	Tag          string `json:"tag"`
+	Architecture string `json:"architecture"`
+	FSLayers     []struct {
+		BlobSum string `json:"blobSum"`
+	} `json:"fsLayers"`
+	History []struct {
+		V1Compatibility string `json:"v1Compatibility"`
+	} `json:"history"`
+}
+
+// Image is an image of a single platform with its manifest decoded.
+type Image struct {
+	MediaType string
+	Digest    string
+	Config    Config
+	// Layers are ordered from the base layer.
+	Layers []Descriptor
+}
+
+// Platform returns the platform of the image by its configuration.
+func (i *Image) Platform() *Platform {
+	return &Platform{OS: i.Config.OS, Architecture: i.Config.Architecture, Variant: i.Config.Variant}
+}
+
+// Size returns the total compressed size of the layers.
+func (i *Image) Size() int64 {
+	var n int64
+	for _, l := range i.Layers {
+		n += l.Size
+	}
+	return n
+}
+
+// LayerHistory returns the history steps which created the layers, in the
+// same order as Layers. A step is empty if history is unavailable.
+func (i *Image) LayerHistory() []History {
+	hs := make([]History, 0, len(i.Layers))
+	for _, h := range i.Config.History {
+		if !h.EmptyLayer {
+			hs = append(hs, h)
+		}
+	}
+	for len(hs) < len(i.Layers) {
+		hs = append(hs, History{})
+	}
+	return hs[:len(i.Layers)]
+}
+
+// LoadImage decodes a single-platform manifest of repo fetched by
+// GetManifest, and fetches its configuration.
+func (c *Client) LoadImage(repo string, manifest []byte, mediaType, digest string) (*Image, error) {
+	if IsIndex(mediaType) {
+		return nil, errors.New("registry: a manifest list is not an image")
+	}
+	if IsSchema1(mediaType) {
+		return c.loadSchema1(repo, manifest, mediaType, digest)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return nil, err
+	}
+
+	img := &Image{MediaType: mediaType, Digest: digest, Layers: m.Layers}
+
+	rc, err := c.GetBlob(repo, m.Config.Digest)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	if d := Digest(b); d != m.Config.Digest {
+		return nil, fmt.Errorf("registry: config digest mismatch, expect %s, got %s", m.Config.Digest, d)
+	}
+	if err := json.Unmarshal(b, &img.Config); err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
+
+// loadSchema1 converts a schema1 manifest. As schema1 does not record the
+// blob sizes, they are fetched from the registry.
+func (c *Client) loadSchema1(repo string, manifest []byte, mediaType, digest string) (*Image, error) {
+	var m Schema1Manifest
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return nil, err
+	}
+	if len(m.FSLayers) == 0 || len(m.FSLayers) != len(m.History) {
+		return nil, errors.New("registry: schema1 manifest has mismatched fsLayers and history")
+	}
+
+	img := &Image{MediaType: mediaType, Digest: digest}
+
+	// The configuration of the image is the v1Compatibility of the top layer.
+	if err := json.Unmarshal([]byte(m.History[0].V1Compatibility), &img.Config); err != nil {
+		return nil, err
+	}
+	if img.Config.Architecture == "" {
+		img.Config.Architecture = m.Architecture
+	}
+	img.Config.History = nil
+
+	sizes := make(map[string]int64)
+	// NOTE: both fsLayers and history of schema1 are ordered from the top layer.
+	for i := len(m.History) - 1; i >= 0; i-- {
+		var v1 struct {
+			Created         string `json:"created"`
+			Author          string `json:"author"`
+			Comment         string `json:"comment"`
+			Throwaway       bool   `json:"throwaway"`
+			ContainerConfig struct {
+				Cmd []string `json:"Cmd"`
+			} `json:"container_config"`
+		}
+		if err := json.Unmarshal([]byte(m.History[i].V1Compatibility), &v1); err != nil {
+			return nil, err
+		}
+
+		img.Config.History = append(img.Config.History, History{
+			Created:    v1.Created,
+			CreatedBy:  strings.Join(v1.ContainerConfig.Cmd, " "),
+			Author:     v1.Author,
+			Comment:    v1.Comment,
+			EmptyLayer: v1.Throwaway,
+		})
+		if v1.Throwaway {
+			continue
+		}
+
+		d := m.FSLayers[i].BlobSum
+		size, ok := sizes[d]
+		if !ok {
+			var err error
+			if size, err = c.BlobSize(repo, d); err != nil {
+				return nil, err
+			}
+			sizes[d] = size
+		}
+		img.Layers = append(img.Layers, Descriptor{MediaType: MediaTypeDockerLayer, Digest: d, Size: size})
+	}
+
+	return img, nil
+}
@@ -0,0 +1,158 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AnnotationRefName is the annotation which names a manifest in an OCI image layout.
+const AnnotationRefName = "org.opencontainers.image.ref.name"
+
+// Layout is a directory of the OCI image layout, see
+// https://github.com/opencontainers/image-spec/blob/master/image-layout.md
+type Layout struct {
+	Path string
+}
+
+// BlobPath returns the path of the blob with digest.
+func (l Layout) BlobPath(digest string) string {
+	algo := "sha256"
+	if i := strings.Index(digest, ":"); i >= 0 {
+		algo = digest[:i]
+	}
+	return filepath.Join(l.Path, "blobs", algo, Encoded(digest))
+}
+
+// HasBlob reports whether the blob with digest exists.
+func (l Layout) HasBlob(digest string) bool {
+	_, err := os.Stat(l.BlobPath(digest))
+	return err == nil
+}
+
+// OpenBlob opens the blob with digest. The caller must close it.
+func (l Layout) OpenBlob(digest string) (*os.File, error) {
+	return os.Open(l.BlobPath(digest))
+}
+
+// ReadBlob reads the whole blob with digest.
+func (l Layout) ReadBlob(digest string) ([]byte, error) {
+	return ioutil.ReadFile(l.BlobPath(digest))
+}
+
+// WriteBlob writes the content from r as the blob with digest. The content is
+// verified against the digest before the blob becomes visible.
+func (l Layout) WriteBlob(digest string, r io.Reader) error {
+	p := l.BlobPath(digest)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(p), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if got := "sha256:" + hex.EncodeToString(h.Sum(nil)); got != digest {
+		return fmt.Errorf("blob digest mismatch, expect %s, got %s", digest, got)
+	}
+
+	return os.Rename(tmp.Name(), p)
+}
+
+// Index reads the index.json of the layout.
+func (l Layout) Index() (*Index, error) {
+	b, err := ioutil.ReadFile(filepath.Join(l.Path, "index.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var idx Index
+	if err := json.Unmarshal(b, &idx); err != nil {
+		return nil, err
+	}
+	return &idx, nil
+}
+
+// AddManifest records the manifest descriptor in index.json under refName,
+// replacing the one with the same name if any.
+func (l Layout) AddManifest(desc Descriptor, refName string) error {
+	if err := os.MkdirAll(l.Path, 0755); err != nil {
+		return err
+	}
+
+	err := ioutil.WriteFile(filepath.Join(l.Path, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644)
+	if err != nil {
+		return err
+	}
+
+	idx, err := l.Index()
+	if os.IsNotExist(err) {
+		idx, err = &Index{SchemaVersion: 2}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if refName != "" {
+		if desc.Annotations == nil {
+			desc.Annotations = make(map[string]string)
+		}
+		desc.Annotations[AnnotationRefName] = refName
+	}
+
+	manifests := idx.Manifests[:0]
+	for _, m := range idx.Manifests {
+		if refName != "" && m.Annotations[AnnotationRefName] == refName {
+			continue
+		}
+		manifests = append(manifests, m)
+	}
+	idx.Manifests = append(manifests, desc)
+
+	b, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(l.Path, "index.json"), b, 0644)
+}
+
+// Find returns the descriptor of the manifest named refName. An empty refName
+// is allowed only when the layout holds a single manifest.
+func (l Layout) Find(refName string) (Descriptor, error) {
+	idx, err := l.Index()
+	if err != nil {
+		return Descriptor{}, err
+	}
+
+	if refName == "" {
+		if len(idx.Manifests) != 1 {
+			return Descriptor{}, fmt.Errorf("%s holds %d manifests, a reference name is required", l.Path, len(idx.Manifests))
+		}
+		return idx.Manifests[0], nil
+	}
+
+	for _, m := range idx.Manifests {
+		if m.Annotations[AnnotationRefName] == refName {
+			return m, nil
+		}
+	}
+	return Descriptor{}, errors.New("no manifest named " + refName + " in " + l.Path)
+}
@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLayoutWriteBlob(t *testing.T) {
+	content := []byte("hello, layout")
+	digest := Digest(content)
+
+	tests := []struct {
+		name    string
+		content []byte
+		err     string
+	}{
+		{"verified", content, ""},
+		{"digest mismatch", []byte("hello, other"), "digest mismatch"},
+		{"empty", nil, "digest mismatch"},
+	}
+	for _, tt := range tests {
+		l := Layout{Path: t.TempDir()}
+		err := l.WriteBlob(digest, bytes.NewReader(tt.content))
+
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: WriteBlob: %v", tt.name, err)
+			}
+			got, err := l.ReadBlob(digest)
+			if err != nil || !bytes.Equal(got, content) {
+				t.Errorf("%s: ReadBlob = %q, %v", tt.name, got, err)
+			}
+			if p := l.BlobPath(digest); p != filepath.Join(l.Path, "blobs", "sha256", Encoded(digest)) {
+				t.Errorf("%s: BlobPath = %s", tt.name, p)
+			}
+		} else {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: WriteBlob: got error %v, want %q", tt.name, err, tt.err)
+			}
+			if l.HasBlob(digest) {
+				t.Errorf("%s: the blob is written", tt.name)
+			}
+		}
+		assertNoTempBlobs(t, l)
+	}
+}
+
+func TestLayoutWriteBlobInterrupted(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	digest := Digest(content)
+	l := Layout{Path: t.TempDir()}
+
+	errBroken := errors.New("connection reset")
+	err := l.WriteBlob(digest, &errReader{r: bytes.NewReader(content), n: len(content) / 2, err: errBroken})
+	if err != errBroken {
+		t.Fatalf("WriteBlob: got error %v, want %v", err, errBroken)
+	}
+	if l.HasBlob(digest) {
+		t.Error("the partial blob is visible")
+	}
+	assertNoTempBlobs(t, l)
+
+	if err := l.WriteBlob(digest, bytes.NewReader(content)); err != nil {
+		t.Fatalf("WriteBlob again: %v", err)
+	}
+	if got, _ := l.ReadBlob(digest); !bytes.Equal(got, content) {
+		t.Error("the blob written again differs")
+	}
+}
+
+// TestLayoutWriteBlobKeepsExisting checks a failed write does not damage the
+// blob already in the layout, as the content is renamed over it only when
+// verified.
+func TestLayoutWriteBlobKeepsExisting(t *testing.T) {
+	content := []byte("existing blob")
+	digest := Digest(content)
+	l := Layout{Path: t.TempDir()}
+
+	if err := l.WriteBlob(digest, bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.WriteBlob(digest, bytes.NewReader([]byte("garbage"))); err == nil {
+		t.Fatal("WriteBlob of wrong content succeeded")
+	}
+	if got, err := ioutil.ReadFile(l.BlobPath(digest)); err != nil || !bytes.Equal(got, content) {
+		t.Errorf("existing blob = %q, %v, want %q", got, err, content)
+	}
+}
+
+func TestLayoutManifests(t *testing.T) {
+	l := Layout{Path: filepath.Join(t.TempDir(), "layout")}
+	a := Descriptor{MediaType: MediaTypeOCIManifest, Digest: Digest([]byte("a")), Size: 1}
+	b := Descriptor{MediaType: MediaTypeOCIManifest, Digest: Digest([]byte("b")), Size: 1}
+
+	if err := l.AddManifest(a, "1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := l.Find(""); err != nil || d.Digest != a.Digest {
+		t.Errorf("Find(\"\") of a single manifest = %+v, %v", d, err)
+	}
+	if err := l.AddManifest(b, "2.0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Find(""); err == nil {
+		t.Error("Find(\"\") of two manifests succeeded")
+	}
+
+	// a manifest of the same name replaces the old one.
+	if err := l.AddManifest(b, "1.0"); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := l.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Manifests) != 2 {
+		t.Errorf("index has %d manifests, want 2", len(idx.Manifests))
+	}
+	for _, ref := range []string{"1.0", "2.0"} {
+		if d, err := l.Find(ref); err != nil || d.Digest != b.Digest {
+			t.Errorf("Find(%q) = %+v, %v, want %s", ref, d, err, b.Digest)
+		}
+	}
+	if _, err := l.Find("3.0"); err == nil {
+		t.Error("Find(3.0) succeeded")
+	}
+
+	if b, err := ioutil.ReadFile(filepath.Join(l.Path, "oci-layout")); err != nil || !strings.Contains(string(b), "1.0.0") {
+		t.Errorf("oci-layout = %q, %v", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(l.Path, "index.json")); err != nil {
+		t.Error(err)
+	}
+}
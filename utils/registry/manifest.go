@@ -0,0 +1,163 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Media types of manifests and blobs.
+const (
+	MediaTypeDockerManifest      = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList  = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeDockerSchema1       = "application/vnd.docker.distribution.manifest.v1+json"
+	MediaTypeDockerSchema1Signed = "application/vnd.docker.distribution.manifest.v1+prettyjws"
+	MediaTypeDockerConfig        = "application/vnd.docker.container.image.v1+json"
+	MediaTypeDockerLayer         = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+
+	MediaTypeOCIManifest = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex    = "application/vnd.oci.image.index.v1+json"
+	MediaTypeOCIConfig   = "application/vnd.oci.image.config.v1+json"
+	MediaTypeOCILayer    = "application/vnd.oci.image.layer.v1.tar+gzip"
+)
+
+// manifestMediaTypes are the media types accepted when fetching manifests.
+var manifestMediaTypes = []string{
+	MediaTypeDockerManifest,
+	MediaTypeDockerManifestList,
+	MediaTypeOCIManifest,
+	MediaTypeOCIIndex,
+	MediaTypeDockerSchema1Signed,
+	MediaTypeDockerSchema1,
+}
+
+// ManifestAccept returns the Accept header of fetching manifests of any media
+// type.
+func ManifestAccept() string {
+	return strings.Join(manifestMediaTypes, ", ")
+}
+
+// Platform describes the platform which an image runs on.
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+func (p *Platform) String() string {
+	if p == nil {
+		return ""
+	}
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// Descriptor describes the content (a blob or a manifest) by media type, digest and size.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	URLs        []string          `json:"urls,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *Platform         `json:"platform,omitempty"`
+}
+
+// Manifest is an image manifest of docker schema2 or OCI.
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+}
+
+// Index is a docker manifest list or an OCI image index.
+type Index struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Manifests     []Descriptor `json:"manifests"`
+}
+
+// IsIndex reports whether mediaType is of a manifest list or an image index.
+func IsIndex(mediaType string) bool {
+	return mediaType == MediaTypeDockerManifestList || mediaType == MediaTypeOCIIndex
+}
+
+// IsSchema1 reports whether mediaType is of a docker schema1 manifest.
+func IsSchema1(mediaType string) bool {
+	return mediaType == MediaTypeDockerSchema1 || mediaType == MediaTypeDockerSchema1Signed
+}
+
+// DetectMediaType guesses the media type of a manifest without one declared by the server.
+func DetectMediaType(manifest []byte) string {
+	var m struct {
+		SchemaVersion int             `json:"schemaVersion"`
+		MediaType     string          `json:"mediaType"`
+		Manifests     json.RawMessage `json:"manifests"`
+		Signatures    json.RawMessage `json:"signatures"`
+	}
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return ""
+	}
+
+	switch {
+	case m.MediaType != "":
+		return m.MediaType
+	case m.SchemaVersion == 1 && m.Signatures != nil:
+		return MediaTypeDockerSchema1Signed
+	case m.SchemaVersion == 1:
+		return MediaTypeDockerSchema1
+	case m.Manifests != nil:
+		return MediaTypeOCIIndex
+	default:
+		return MediaTypeOCIManifest
+	}
+}
+
+// ParsePlatform parses a platform of format 'os/arch[/variant]'.
+func ParsePlatform(s string) (*Platform, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid platform %q, must be of format 'os/arch[/variant]'", s)
+	}
+
+	p := &Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p, nil
+}
+
+// Match reports whether the descriptor of an index matches the platform. The
+// variant is ignored when p does not specify one.
+func (p *Platform) Match(d Descriptor) bool {
+	if d.Platform == nil {
+		return false
+	}
+	return d.Platform.OS == p.OS &&
+		d.Platform.Architecture == p.Architecture &&
+		(p.Variant == "" || d.Platform.Variant == p.Variant)
+}
+
+// Digest returns the sha256 digest of b.
+func Digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// IsDigest reports whether s looks like a digest rather than a tag.
+func IsDigest(s string) bool {
+	return strings.Contains(s, ":")
+}
+
+// Encoded returns the hex part of a digest like 'sha256:<hex>'.
+func Encoded(digest string) string {
+	if i := strings.Index(digest, ":"); i >= 0 {
+		return digest[i+1:]
+	}
+	return digest
+}
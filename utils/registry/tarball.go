@@ -0,0 +1,222 @@
+package registry
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+)
+
+// TarballManifest is an entry of manifest.json in a tarball made by 'docker save'.
+type TarballManifest struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+}
+
+// TarballWriter writes images in the format of 'docker save', which can be
+// loaded by 'docker load'.
+type TarballWriter struct {
+	tw        *tar.Writer
+	manifests []TarballManifest
+	written   map[string]bool
+}
+
+// NewTarballWriter returns a TarballWriter writing to w.
+func NewTarballWriter(w io.Writer) *TarballWriter {
+	return &TarballWriter{
+		tw:      tar.NewWriter(w),
+		written: make(map[string]bool),
+	}
+}
+
+// WriteFile writes size bytes from r as the file name. A file which has been
+// written already is skipped.
+func (t *TarballWriter) WriteFile(name string, size int64, r io.Reader) error {
+	if t.written[name] {
+		_, err := io.Copy(ioutil.Discard, r)
+		return err
+	}
+
+	if dir := path.Dir(name); dir != "." && !t.written[dir] {
+		err := t.tw.WriteHeader(&tar.Header{
+			Name:     dir + "/",
+			Mode:     0755,
+			Typeflag: tar.TypeDir,
+			ModTime:  time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		t.written[dir] = true
+	}
+
+	err := t.tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		Typeflag: tar.TypeReg,
+		ModTime:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.CopyN(t.tw, r, size); err != nil {
+		return err
+	}
+
+	t.written[name] = true
+	return nil
+}
+
+// AddManifest adds an image to manifest.json.
+func (t *TarballWriter) AddManifest(m TarballManifest) {
+	t.manifests = append(t.manifests, m)
+}
+
+// Close writes manifest.json and finishes the tarball.
+func (t *TarballWriter) Close() error {
+	b, err := json.Marshal(t.manifests)
+	if err != nil {
+		return err
+	}
+
+	if err := t.WriteFile("manifest.json", int64(len(b)), bytes.NewReader(b)); err != nil {
+		return err
+	}
+	return t.tw.Close()
+}
+
+// Tarball is a tarball made by 'docker save'.
+type Tarball struct {
+	Path string
+}
+
+type tarballEntry struct {
+	io.Reader
+	io.Closer
+}
+
+// Open opens the file name in the tarball, and returns it with its size. The
+// caller must close it.
+func (t Tarball) Open(name string) (io.ReadCloser, int64, error) {
+	f, err := os.Open(t.Path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+
+		if path.Clean(hdr.Name) == path.Clean(name) {
+			return tarballEntry{tr, f}, hdr.Size, nil
+		}
+	}
+
+	f.Close()
+	return nil, 0, fmt.Errorf("no %s in %s", name, t.Path)
+}
+
+// Manifests reads manifest.json of the tarball.
+func (t Tarball) Manifests() ([]TarballManifest, error) {
+	rc, _, err := t.Open("manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var ms []TarballManifest
+	if err := json.NewDecoder(rc).Decode(&ms); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+// Find returns the image tagged as repoTag. An empty repoTag is allowed only
+// when the tarball holds a single image.
+func (t Tarball) Find(repoTag string) (TarballManifest, error) {
+	ms, err := t.Manifests()
+	if err != nil {
+		return TarballManifest{}, err
+	}
+
+	if repoTag == "" {
+		if len(ms) != 1 {
+			return TarballManifest{}, fmt.Errorf("%s holds %d images, a repo tag is required", t.Path, len(ms))
+		}
+		return ms[0], nil
+	}
+
+	for _, m := range ms {
+		for _, rt := range m.RepoTags {
+			if rt == repoTag {
+				return m, nil
+			}
+		}
+	}
+	return TarballManifest{}, fmt.Errorf("no image tagged as %s in %s", repoTag, t.Path)
+}
+
+// CopyLayer writes the layer name of the tarball to w as a gzip compressed
+// blob, and returns its descriptor. A layer which is compressed already is
+// copied as is.
+func (t Tarball) CopyLayer(name string, w io.Writer) (Descriptor, error) {
+	rc, _, err := t.Open(name)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	cw := &countWriter{w: io.MultiWriter(w, h)}
+
+	br := bufio.NewReader(rc)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		if _, err := io.Copy(cw, br); err != nil {
+			return Descriptor{}, err
+		}
+	} else {
+		zw := gzip.NewWriter(cw)
+		if _, err := io.Copy(zw, br); err != nil {
+			return Descriptor{}, err
+		}
+		if err := zw.Close(); err != nil {
+			return Descriptor{}, err
+		}
+	}
+
+	return Descriptor{
+		MediaType: MediaTypeDockerLayer,
+		Digest:    "sha256:" + hex.EncodeToString(h.Sum(nil)),
+		Size:      cw.n,
+	}, nil
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
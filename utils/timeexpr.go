@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var relativeTimeRegexp = regexp.MustCompile(`^(\d+)([smhdwy])$`)
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"20060102",
+}
+
+// ParseTimeExpr parses a human-friendly time expression relative to now. The
+// supported expressions are:
+//
+//   - 'now', 'today' and 'yesterday'
+//   - a duration ago like '90s', '30m', '12h', '30d', '2w' or '1y'
+//   - a date or time like '2018-10-01', '20181001', '2018-10-01 08:00' or
+//     RFC3339, in local time if no zone is given
+//   - a unix timestamp in seconds like '1538352000'
+func ParseTimeExpr(s string, now time.Time) (time.Time, error) {
+	switch s {
+	case "now":
+		return now, nil
+	case "today":
+		y, m, d := now.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), nil
+	case "yesterday":
+		y, m, d := now.Date()
+		return time.Date(y, m, d-1, 0, 0, 0, 0, now.Location()), nil
+	}
+
+	if m := relativeTimeRegexp.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "s":
+			return now.Add(-time.Duration(n) * time.Second), nil
+		case "m":
+			return now.Add(-time.Duration(n) * time.Minute), nil
+		case "h":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "d":
+			return now.AddDate(0, 0, -n), nil
+		case "w":
+			return now.AddDate(0, 0, -7*n), nil
+		case "y":
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	// NOTE: 8 digits are taken as 'yyyymmdd' above, so a timestamp must be longer.
+	if len(s) > 8 {
+		if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(sec, 0), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q, must be like '30d', '12h', 'today', '2018-10-01' or a unix timestamp", s)
+}
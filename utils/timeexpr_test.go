@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeExpr(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	now := time.Date(2018, 10, 15, 13, 30, 45, 0, loc)
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"now", now},
+		{"today", time.Date(2018, 10, 15, 0, 0, 0, 0, loc)},
+		{"yesterday", time.Date(2018, 10, 14, 0, 0, 0, 0, loc)},
+		{"90s", now.Add(-90 * time.Second)},
+		{"30m", now.Add(-30 * time.Minute)},
+		{"12h", now.Add(-12 * time.Hour)},
+		{"30d", time.Date(2018, 9, 15, 13, 30, 45, 0, loc)},
+		{"2w", time.Date(2018, 10, 1, 13, 30, 45, 0, loc)},
+		{"1y", time.Date(2017, 10, 15, 13, 30, 45, 0, loc)},
+		{"2018-10-01", time.Date(2018, 10, 1, 0, 0, 0, 0, loc)},
+		{"20181001", time.Date(2018, 10, 1, 0, 0, 0, 0, loc)},
+		{"2018-10-01 08:00", time.Date(2018, 10, 1, 8, 0, 0, 0, loc)},
+		{"2018-10-01 08:00:30", time.Date(2018, 10, 1, 8, 0, 30, 0, loc)},
+		{"2018-10-01T08:00:30", time.Date(2018, 10, 1, 8, 0, 30, 0, loc)},
+		{"2018-10-01T08:00:30Z", time.Date(2018, 10, 1, 8, 0, 30, 0, time.UTC)},
+		{"2018-10-01T08:00:30+02:00", time.Date(2018, 10, 1, 6, 0, 30, 0, time.UTC)},
+		{"1538352000", time.Unix(1538352000, 0)},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeExpr(tt.in, now)
+		if err != nil {
+			t.Errorf("ParseTimeExpr(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTimeExpr(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseTimeExprInvalid(t *testing.T) {
+	now := time.Now()
+	tests := []string{
+		"",
+		"tomorrow",
+		"30",
+		"-30d",
+		"30x",
+		"d30",
+		"1.5h",
+		"2018-13-01",
+		"2018/10/01",
+		"12345678x",
+	}
+	for _, in := range tests {
+		if got, err := ParseTimeExpr(in, now); err == nil {
+			t.Errorf("ParseTimeExpr(%q) = %v, want error", in, got)
+		}
+	}
+}